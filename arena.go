@@ -0,0 +1,128 @@
+package gbson
+
+// Arena allocates the documents and lists materialized for a request out of large chunks, which are
+// released in one step by Reset and reused, so the temporary documents of requests don't churn the heap.
+// Outputs are appended right into the free space of the current chunk, and copied into a new chunk only
+// if they outgrow it. Values allocated from an arena must not be used after Reset.
+// An Arena is not safe for concurrent use.
+type Arena struct {
+	chunkSize int
+	chunks    [][]byte // chunks of bytes in use, the current one is the last
+	spare     [][]byte // chunks released by Reset
+	results   []Result // the current chunk of results
+}
+
+// DefaultArenaChunkSize is the chunk size of arenas if not set.
+const DefaultArenaChunkSize = 64 << 10
+
+// arenaResultChunkSize is the min number of results in a chunk.
+const arenaResultChunkSize = 256
+
+// NewArena returns an arena allocating chunks of chunkSize bytes, DefaultArenaChunkSize if not positive.
+// Larger outputs get chunks of their own sizes.
+func NewArena(chunkSize int) *Arena {
+	if chunkSize <= 0 {
+		chunkSize = DefaultArenaChunkSize
+	}
+	return &Arena{chunkSize: chunkSize}
+}
+
+// Marshal is Marshal with the output allocated from the arena.
+func (a *Arena) Marshal(v interface{}) ([]byte, error) {
+	return a.append(func(dst []byte) ([]byte, error) {
+		return appendMarshal(dst, v)
+	})
+}
+
+// FromJSON is FromJSON with the output allocated from the arena.
+func (a *Arena) FromJSON(data []byte) ([]byte, error) {
+	return a.append(func(dst []byte) ([]byte, error) {
+		return appendFromJSON(dst, data)
+	})
+}
+
+// FromMap is FromMap with the output allocated from the arena.
+func (a *Arena) FromMap(m map[string]interface{}) ([]byte, error) {
+	return a.append(func(dst []byte) ([]byte, error) {
+		dst, _, err := (&mapEncoder{}).appendMap(dst, m)
+		return dst, err
+	})
+}
+
+// Copy copies the bytes into the arena, e.g. a document read into a buffer which is reused.
+func (a *Arena) Copy(b []byte) []byte {
+	out, _ := a.append(func(dst []byte) ([]byte, error) {
+		return append(dst, b...), nil
+	})
+	return out
+}
+
+// Array is Result.Array with the slice allocated from the arena.
+func (a *Arena) Array(r Result) []Result {
+	free := a.results[len(a.results):cap(a.results)]
+	out := r.AppendArray(free[:0])
+	if len(out) == 0 {
+		return out
+	}
+	if cap(out) != cap(free) {
+		// outgrown, copied into a new chunk
+		size := arenaResultChunkSize
+		if len(out) > size {
+			size = len(out)
+		}
+		a.results = make([]Result, 0, size)
+		out = append(a.results, out...)
+	}
+	a.results = a.results[:len(a.results)+len(out)]
+	return out[:len(out):len(out)]
+}
+
+// append appends the output of fn into the free space of the current chunk, and takes the used part.
+// The output is capped, so appending to it never overwrites the following outputs.
+func (a *Arena) append(fn func(dst []byte) ([]byte, error)) ([]byte, error) {
+	if len(a.chunks) == 0 {
+		a.newChunk(0)
+	}
+	cur := a.chunks[len(a.chunks)-1]
+	out, err := fn(cur[len(cur):len(cur)])
+	if err != nil || len(out) == 0 {
+		return nil, err
+	}
+	if cap(out) != cap(cur)-len(cur) {
+		// outgrown, copied into a new chunk
+		cur = a.newChunk(len(out))
+		out = append(cur, out...)
+	}
+	a.chunks[len(a.chunks)-1] = cur[:len(cur)+len(out)]
+	return out[:len(out):len(out)], nil
+}
+
+// newChunk makes a chunk of at least n bytes the current one, reusing a spare one if large enough.
+func (a *Arena) newChunk(n int) []byte {
+	size := a.chunkSize
+	if n > size {
+		size = n
+	}
+	var chunk []byte
+	for i, spare := range a.spare {
+		if cap(spare) >= size {
+			chunk = spare
+			a.spare = append(a.spare[:i], a.spare[i+1:]...)
+			break
+		}
+	}
+	if chunk == nil {
+		chunk = make([]byte, 0, size)
+	}
+	a.chunks = append(a.chunks, chunk)
+	return chunk
+}
+
+// Reset releases all the values allocated from the arena at once, keeping the chunks for reuse.
+func (a *Arena) Reset() {
+	for _, chunk := range a.chunks {
+		a.spare = append(a.spare, chunk[:0])
+	}
+	a.chunks = a.chunks[:0]
+	a.results = a.results[:0]
+}
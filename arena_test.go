@@ -0,0 +1,45 @@
+package gbson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestArena(t *testing.T) {
+	a := NewArena(64)
+	v := bson.D{{Key: "a", Value: int32(1)}, {Key: "b", Value: bson.A{"x", "y"}}}
+	expected := mustMarshal(t, v)
+	doc, err := a.Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, expected, doc)
+	js, err := a.FromJSON([]byte(`{"a": {"$numberInt": "1"}, "b": ["x", "y"]}`))
+	require.NoError(t, err)
+	require.Equal(t, expected, js)
+	m, err := a.FromMap(map[string]interface{}{"b": bson.A{"x", "y"}, "a": int32(1)})
+	require.NoError(t, err)
+	require.Equal(t, expected, m)
+	_, err = a.FromJSON([]byte(`[]`))
+	require.ErrorIs(t, err, ErrNotObject)
+
+	// outputs are capped, appending to one doesn't overwrite the next
+	_ = append(doc, 0xff)
+	require.Equal(t, expected, js)
+	large := a.Copy(make([]byte, 100))
+	require.Len(t, large, 100)
+	require.Equal(t, expected, m)
+
+	items := a.Array(Get(doc, "b"))
+	require.Equal(t, Get(doc, "b").Array(), items)
+	require.Equal(t, items, a.Array(Get(doc, "b")))
+	require.Empty(t, a.Array(Get(doc, "a")))
+
+	a.Reset()
+	allocs := testing.AllocsPerRun(100, func() {
+		a.Reset()
+		a.Copy(expected)
+		a.Array(Get(expected, "b"))
+	})
+	require.Zero(t, allocs)
+}
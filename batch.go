@@ -0,0 +1,129 @@
+package gbson
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// GetBatch gets the first value by the given path from each of the documents, for scan style workloads
+// extracting the same field from many buffered documents. The results are in the order of the documents,
+// written into a single slice without allocations per document. The errors of malformed documents are
+// reported by Err of their results.
+func GetBatch(docs [][]byte, path ...string) []Result {
+	results := make([]Result, len(docs))
+	getBatch(results, docs, path)
+	return results
+}
+
+// GetBatchParallel is GetBatch across goroutines, which take the documents in chunks of batchChunkSize.
+// workers is the number of goroutines, GOMAXPROCS if not positive.
+func GetBatchParallel(workers int, docs [][]byte, path ...string) []Result {
+	results := make([]Result, len(docs))
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if chunks := (len(docs) + batchChunkSize - 1) / batchChunkSize; workers > chunks {
+		workers = chunks
+	}
+	var next int64 = -1
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				start := int(atomic.AddInt64(&next, 1)) * batchChunkSize
+				if start >= len(docs) {
+					return
+				}
+				end := start + batchChunkSize
+				if end > len(docs) {
+					end = len(docs)
+				}
+				getBatch(results[start:end], docs[start:end], path)
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// batchChunkSize is the number of documents GetBatchParallel takes at a time, large enough to keep
+// the synchronization cheap compared with the lookups.
+const batchChunkSize = 256
+
+// getBatch gets the values of the documents into the results, which are of the same length.
+func getBatch(results []Result, docs [][]byte, path []string) {
+	p := batchPath{keys: path, limits: defaultGetLimits}
+	if len(path) > p.limits.maxDepth {
+		err := errors.Wrapf(ErrMaxDepth, "depth %d", len(path))
+		for i := range results {
+			results[i] = Result{Type: BSONTypeUndefined, err: err}
+		}
+		return
+	}
+	for i, doc := range docs {
+		results[i] = p.get(doc)
+	}
+}
+
+// batchPath is a path compiled once for the documents of a batch: the limits are taken and the depth of
+// the path is checked before the documents, which are walked by the keys without the callbacks of Get.
+type batchPath struct {
+	keys   []string
+	limits getLimits
+}
+
+// get gets the first value by the path from the document, the same as Get.
+func (p *batchPath) get(doc []byte) Result {
+	if len(p.keys) == 0 {
+		return resultFromBytes(doc)
+	}
+	result, _, err := p.walk(resultFromBytes(doc), 0, 0)
+	if err != nil && !result.Exist() && !errors.Is(err, ErrNotObject) {
+		return Result{Type: BSONTypeUndefined, err: locateError(doc, err)}
+	}
+	return result
+}
+
+// walk gets the first value by the keys from the depth in the container, in the depth first order of
+// GetIter. visited is the number of the elements visited before, which is returned updated.
+func (p *batchPath) walk(r Result, depth, visited int) (Result, int, error) {
+	missing := Result{Type: BSONTypeUndefined}
+	if !r.IsContainer() {
+		return missing, visited, ErrNotObject
+	}
+	elements, ok := containerElements(r.Raw)
+	if !ok {
+		return missing, visited, ErrInvalidLength
+	}
+	key, last := p.keys[depth], depth == len(p.keys)-1
+	for len(elements) > 0 {
+		tp, name, value, n := consumeElementPolicy(elements, p.limits.unknownTypes)
+		if n < 0 {
+			return missing, visited, ErrInvalidLength
+		}
+		elements = elements[n:]
+		if p.limits.unknownTypes.Mode == UnknownTypeSkip && !isKnownType(tp) {
+			continue
+		}
+		if visited++; p.limits.budget > 0 && visited > p.limits.budget {
+			return missing, visited, errors.Wrapf(ErrBudgetExceeded, "more than %d elements visited", p.limits.budget)
+		}
+		if !bytesEqualToString(name, key) {
+			continue
+		}
+		found := Result{Type: tp, Raw: value}
+		if last {
+			return found, visited, nil
+		}
+		var err error
+		if found, visited, err = p.walk(found, depth+1, visited); err != nil || found.Exist() {
+			return found, visited, err
+		}
+	}
+	return missing, visited, nil
+}
@@ -0,0 +1,70 @@
+package gbson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func getTestBatch(t testing.TB, n int) [][]byte {
+	docs := make([][]byte, n)
+	for i := range docs {
+		docs[i] = mustMarshal(t, bson.D{{Key: "i", Value: int32(i)}, {Key: "doc", Value: bson.D{{Key: "v", Value: int32(-i)}}}})
+	}
+	return docs
+}
+
+func TestGetBatch(t *testing.T) {
+	docs := getTestBatch(t, 1000)
+	docs[10] = docs[10][:len(docs[10])-1]
+	docs[20] = nil
+	for _, results := range [][]Result{
+		GetBatch(docs, "doc", "v"),
+		GetBatchParallel(0, docs, "doc", "v"),
+		GetBatchParallel(3, docs, "doc", "v"),
+	} {
+		require.Len(t, results, len(docs))
+		for i, doc := range docs {
+			require.Equal(t, Get(doc, "doc", "v"), results[i], i)
+		}
+		require.Equal(t, int32(-999), results[999].Int32())
+		require.ErrorIs(t, results[10].Err(), ErrInvalidLength)
+		require.False(t, results[20].Exist())
+	}
+	require.Empty(t, GetBatchParallel(4, nil, "i"))
+
+	doc := getTestJSONDocument(t)
+	dup := mustMarshal(t, bson.D{{Key: "a", Value: 1}, {Key: "a", Value: bson.D{{Key: "b", Value: 2}}}, {Key: "c", Value: bson.D{{Key: "x", Value: 1}}}, {Key: "c", Value: bson.D{{Key: "b", Value: 3}}}})
+	for _, path := range [][]string{{}, {"int32"}, {"doc", "b", "1"}, {"doc", "b", "1", "x"}, {"missing"}, {"a", "b"}, {"c", "b"}} {
+		results := GetBatch([][]byte{doc, dup, doc[:len(doc)-1]}, path...)
+		for i, d := range [][]byte{doc, dup, doc[:len(doc)-1]} {
+			require.Equal(t, Get(d, path...).Raw, results[i].Raw, "%v %d", path, i)
+			require.Equal(t, Get(d, path...).Type, results[i].Type, "%v %d", path, i)
+			if err := Get(d, path...).Err(); err != nil {
+				require.EqualError(t, results[i].Err(), err.Error(), "%v %d", path, i)
+			}
+		}
+	}
+	require.ErrorIs(t, GetBatch(docs[:1], make([]string, DefaultMaxDepth+1)...)[0].Err(), ErrMaxDepth)
+
+	require.Equal(t, 1.0, testing.AllocsPerRun(10, func() {
+		GetBatch(docs[:10], "doc", "v") // only the results are allocated
+	}))
+}
+
+func BenchmarkGetBatch(b *testing.B) {
+	docs := getTestBatch(b, 100000)
+	b.Run("gbson get batch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			GetBatch(docs, "doc", "v")
+		}
+	})
+	b.Run("gbson get batch parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			GetBatchParallel(0, docs, "doc", "v")
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package gbson
+
+import (
+	"math"
+	"math/big"
+)
+
+// decimal128FloatPrec is the precision used by BigFloat for decimal128 values,
+// which is more than enough for the 34 decimal digits of the coefficient.
+const decimal128FloatPrec = 128
+
+// BigInt converts int32, int64, double and decimal128 values into a big.Int without precision loss.
+// It returns nil if the value is not a number or not an integral number, e.g. 1.5, NaN or infinities.
+func (r Result) BigInt() *big.Int {
+	switch r.Type {
+	case BSONTypeInt32, BSONTypeInt64:
+		return big.NewInt(r.Int64())
+	case BSONTypeDouble:
+		f := r.Float64()
+		if math.IsInf(f, 0) || math.IsNaN(f) || f != math.Trunc(f) {
+			return nil
+		}
+		i, _ := new(big.Float).SetFloat64(f).Int(nil)
+		return i
+	case BSONTypeDecimal128:
+		rat, ok := r.Decimal128().BigRat()
+		if !ok || !rat.IsInt() {
+			return nil
+		}
+		return new(big.Int).Set(rat.Num())
+	}
+	return nil
+}
+
+// BigFloat converts int32, int64, double and decimal128 values into a big.Float.
+// Integers and doubles are converted exactly, decimal128 values are rounded to 128 bits of precision
+// since decimal fractions are not always representable in binary, use BigRat for exact results.
+// It returns nil if the value is not a number or is NaN.
+func (r Result) BigFloat() *big.Float {
+	switch r.Type {
+	case BSONTypeInt32, BSONTypeInt64:
+		return new(big.Float).SetInt64(r.Int64())
+	case BSONTypeDouble:
+		f := r.Float64()
+		if math.IsNaN(f) {
+			return nil
+		}
+		return new(big.Float).SetFloat64(f)
+	case BSONTypeDecimal128:
+		f, _ := r.Decimal128().BigFloat(decimal128FloatPrec)
+		return f
+	}
+	return nil
+}
+
+// BigRat converts int32, int64, double and decimal128 values into a big.Rat exactly.
+// It returns nil if the value is not a number, NaN or an infinity.
+func (r Result) BigRat() *big.Rat {
+	switch r.Type {
+	case BSONTypeInt32, BSONTypeInt64:
+		return new(big.Rat).SetInt64(r.Int64())
+	case BSONTypeDouble:
+		f := r.Float64()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil
+		}
+		return new(big.Rat).SetFloat64(f)
+	case BSONTypeDecimal128:
+		rat, _ := r.Decimal128().BigRat()
+		return rat
+	}
+	return nil
+}
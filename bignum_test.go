@@ -0,0 +1,45 @@
+package gbson
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBigNumbers(t *testing.T) {
+	amount, err := primitive.ParseDecimal128("12345678901234567890.12")
+	require.NoError(t, err)
+	integral, err := primitive.ParseDecimal128("1.2E+30")
+	require.NoError(t, err)
+	doc := mustMarshal(t, bson.D{
+		{Key: "long", Value: int64(math.MaxInt64)},
+		{Key: "double", Value: 1e20},
+		{Key: "fraction", Value: 0.5},
+		{Key: "nan", Value: math.NaN()},
+		{Key: "amount", Value: amount},
+		{Key: "integral", Value: integral},
+		{Key: "string", Value: "1"},
+	})
+
+	require.Equal(t, big.NewInt(math.MaxInt64), Get(doc, "long").BigInt())
+	expected, _ := new(big.Int).SetString("100000000000000000000", 10)
+	require.Equal(t, expected, Get(doc, "double").BigInt())
+	require.Nil(t, Get(doc, "fraction").BigInt())
+	require.Nil(t, Get(doc, "amount").BigInt())
+	expected, _ = new(big.Int).SetString("1200000000000000000000000000000", 10)
+	require.Equal(t, expected, Get(doc, "integral").BigInt())
+	require.Nil(t, Get(doc, "string").BigInt())
+
+	require.Equal(t, "9223372036854775807", Get(doc, "long").BigFloat().Text('f', 0))
+	require.Equal(t, "12345678901234567890.12", Get(doc, "amount").BigFloat().Text('f', 2))
+	require.Nil(t, Get(doc, "nan").BigFloat())
+
+	rat, _ := new(big.Rat).SetString("1234567890123456789012/100")
+	require.Equal(t, rat, Get(doc, "amount").BigRat())
+	require.Equal(t, big.NewRat(1, 2), Get(doc, "fraction").BigRat())
+	require.Nil(t, Get(doc, "nan").BigRat())
+}
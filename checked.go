@@ -0,0 +1,292 @@
+package gbson
+
+import (
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Accessors returning errors rather than zero values when the value has a mismatched type,
+// or the raw bytes are too short for the type.
+// Values are coerced between numeric types the same as the accessors without errors.
+
+// checkType returns an error if the type of the value is not one of the types, or the error of getting it.
+func (r Result) checkType(types ...Type) error {
+	if r.err != nil {
+		return r.err
+	}
+	if !r.Exist() {
+		return ErrNotExist
+	}
+	for _, tp := range types {
+		if r.Type == tp {
+			return nil
+		}
+	}
+	return errors.Wrapf(ErrTypeMismatch, "type %v", r.Type)
+}
+
+// checkLength returns an error if the raw bytes are shorter than the fixed size of the type.
+func (r Result) checkLength() error {
+	var n int
+	switch r.Type {
+	case BSONTypeBoolean:
+		n = 1
+	case BSONTypeInt32:
+		n = 4
+	case BSONTypeDouble, BSONTypeDateTime, BSONTypeTimestamp, BSONTypeInt64:
+		n = 8
+	case BSONTypeObjectID:
+		n = 12
+	case BSONTypeDecimal128:
+		n = 16
+	}
+	if len(r.Raw) < n {
+		return errors.Wrapf(ErrInvalidLength, "%d bytes for type %v", len(r.Raw), r.Type)
+	}
+	return nil
+}
+
+// StringE is like String, but returns an error if the value is not a string type or malformed.
+func (r Result) StringE() (string, error) {
+	if err := r.checkType(BSONTypeString, BSONTypeSymbol, BSONTypeJavaScript); err != nil {
+		return "", err
+	}
+	value, n := consumeString(r.Raw)
+	if n == 0 || r.Raw[n-1] != 0 {
+		return "", errors.Wrap(ErrInvalidLength, "malformed string")
+	}
+	if r.strict && !isStrictText(value) {
+		return "", errors.Wrap(ErrInvalidValue, "string is not valid UTF-8 or has zero bytes")
+	}
+	return string(value), nil
+}
+
+// BoolE is like Bool, but returns an error if the value is not a boolean or malformed.
+func (r Result) BoolE() (bool, error) {
+	if err := r.checkType(BSONTypeBoolean); err != nil {
+		return false, err
+	}
+	if err := r.checkLength(); err != nil {
+		return false, err
+	}
+	return r.Bool(), nil
+}
+
+// Float64E is like Float64, but returns an error if the value is not a number or malformed.
+func (r Result) Float64E() (float64, error) {
+	if err := r.checkType(BSONTypeDouble, BSONTypeInt32, BSONTypeInt64); err != nil {
+		return 0, err
+	}
+	if err := r.checkLength(); err != nil {
+		return 0, err
+	}
+	return r.Float64(), nil
+}
+
+// Int32E is like Int32, but returns an error if the value is not a number or malformed.
+func (r Result) Int32E() (int32, error) {
+	if err := r.checkType(BSONTypeInt32, BSONTypeInt64, BSONTypeDouble); err != nil {
+		return 0, err
+	}
+	if err := r.checkLength(); err != nil {
+		return 0, err
+	}
+	return r.Int32(), nil
+}
+
+// Int64E is like Int64, but returns an error if the value is not a number or malformed.
+func (r Result) Int64E() (int64, error) {
+	if err := r.checkType(BSONTypeInt64, BSONTypeInt32, BSONTypeDouble); err != nil {
+		return 0, err
+	}
+	if err := r.checkLength(); err != nil {
+		return 0, err
+	}
+	return r.Int64(), nil
+}
+
+// IntE is like Int, but returns an error if the value is not a number or malformed.
+func (r Result) IntE() (int, error) {
+	i, err := r.Int64E()
+	return int(i), err
+}
+
+// UnixMilliE is like UnixMilli, but returns an error if the value is not a DateTime or malformed.
+func (r Result) UnixMilliE() (int64, error) {
+	if err := r.checkType(BSONTypeDateTime); err != nil {
+		return 0, err
+	}
+	if err := r.checkLength(); err != nil {
+		return 0, err
+	}
+	return r.UnixMilli(), nil
+}
+
+// TimeE is like Time, but returns an error if the value is not a DateTime or Timestamp, or malformed.
+// Time never overflows, every DateTime is converted exactly.
+func (r Result) TimeE() (time.Time, error) {
+	if err := r.checkType(BSONTypeDateTime, BSONTypeTimestamp); err != nil {
+		return time.Time{}, err
+	}
+	if err := r.checkLength(); err != nil {
+		return time.Time{}, err
+	}
+	return r.Time(), nil
+}
+
+// ObjectIDE is like ObjectID, but returns an error if the value is not an ObjectID or malformed.
+func (r Result) ObjectIDE() (id [12]byte, err error) {
+	if err = r.checkType(BSONTypeObjectID); err != nil {
+		return
+	}
+	if err = r.checkLength(); err != nil {
+		return
+	}
+	copy(id[:], r.Raw)
+	return
+}
+
+// StringOr returns the text of a string value, or def if the value is missing, null or of another type.
+func (r Result) StringOr(def string) string {
+	if v, err := r.StringE(); err == nil {
+		return v
+	}
+	return def
+}
+
+// BoolOr returns the boolean value, or def if the value is missing, null or of another type.
+func (r Result) BoolOr(def bool) bool {
+	if v, err := r.BoolE(); err == nil {
+		return v
+	}
+	return def
+}
+
+// Float64Or returns the numeric value as float64, or def if the value is missing, null or of another type.
+func (r Result) Float64Or(def float64) float64 {
+	if v, err := r.Float64E(); err == nil {
+		return v
+	}
+	return def
+}
+
+// Int32Or returns the numeric value as int32, or def if the value is missing, null or of another type.
+func (r Result) Int32Or(def int32) int32 {
+	if v, err := r.Int32E(); err == nil {
+		return v
+	}
+	return def
+}
+
+// Int64Or returns the numeric value as int64, or def if the value is missing, null or of another type.
+func (r Result) Int64Or(def int64) int64 {
+	if v, err := r.Int64E(); err == nil {
+		return v
+	}
+	return def
+}
+
+// IntOr returns the numeric value as int, or def if the value is missing, null or of another type.
+func (r Result) IntOr(def int) int {
+	if v, err := r.IntE(); err == nil {
+		return v
+	}
+	return def
+}
+
+// TimeOr returns the time value, or def if the value is missing, null or of another type.
+func (r Result) TimeOr(def time.Time) time.Time {
+	if v, err := r.TimeE(); err == nil {
+		return v
+	}
+	return def
+}
+
+// Int64Checked is like Int64E, but rejects doubles which are not integral or out of the int64 range.
+func (r Result) Int64Checked() (int64, error) {
+	if r.Type != BSONTypeDouble {
+		return r.Int64E()
+	}
+	f, err := r.Float64E()
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, errors.Wrapf(ErrLossyConversion, "%v to int64", f)
+	}
+	return int64(f), nil
+}
+
+// Int32Checked is like Int32E, but rejects values which are not integral or out of the int32 range.
+func (r Result) Int32Checked() (int32, error) {
+	i, err := r.Int64Checked()
+	if err != nil {
+		return 0, err
+	}
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		return 0, errors.Wrapf(ErrLossyConversion, "%d to int32", i)
+	}
+	return int32(i), nil
+}
+
+// IntChecked is like IntE, but rejects values which are not integral or out of the int range.
+func (r Result) IntChecked() (int, error) {
+	i, err := r.Int64Checked()
+	if err != nil {
+		return 0, err
+	}
+	if int64(int(i)) != i {
+		return 0, errors.Wrapf(ErrLossyConversion, "%d to int", i)
+	}
+	return int(i), nil
+}
+
+// Uint64Checked is like Uint64, but rejects negative and non-integral values.
+func (r Result) Uint64Checked() (uint64, error) {
+	if r.Type == BSONTypeDouble {
+		f, err := r.Float64E()
+		if err != nil {
+			return 0, err
+		}
+		if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+			return 0, errors.Wrapf(ErrLossyConversion, "%v to uint64", f)
+		}
+		return uint64(f), nil
+	}
+	i, err := r.Int64E()
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, errors.Wrapf(ErrLossyConversion, "%d to uint64", i)
+	}
+	return uint64(i), nil
+}
+
+// Uint32Checked is like Uint32, but rejects values which are negative, non-integral or out of the uint32 range.
+func (r Result) Uint32Checked() (uint32, error) {
+	u, err := r.Uint64Checked()
+	if err != nil {
+		return 0, err
+	}
+	if u > math.MaxUint32 {
+		return 0, errors.Wrapf(ErrLossyConversion, "%d to uint32", u)
+	}
+	return uint32(u), nil
+}
+
+// Float64Checked is like Float64E, but rejects integers which can't be represented by a float64 exactly.
+func (r Result) Float64Checked() (float64, error) {
+	f, err := r.Float64E()
+	if err != nil || r.Type == BSONTypeDouble {
+		return f, err
+	}
+	// integers up to 2^53 are always exact, larger ones only if they convert back to the same value
+	i := r.Int64()
+	if f >= math.MaxInt64 || int64(f) != i {
+		return 0, errors.Wrapf(ErrLossyConversion, "%d to float64", i)
+	}
+	return f, nil
+}
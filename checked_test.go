@@ -0,0 +1,147 @@
+package gbson
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestErrorAccessors(t *testing.T) {
+	when := time.UnixMilli(1668000000123)
+	doc := mustMarshal(t, bson.D{
+		{Key: "s", Value: "text"},
+		{Key: "b", Value: true},
+		{Key: "i", Value: int32(0)},
+		{Key: "l", Value: int64(42)},
+		{Key: "f", Value: 2.5},
+		{Key: "t", Value: when},
+	})
+
+	s, err := Get(doc, "s").StringE()
+	require.NoError(t, err)
+	require.Equal(t, "text", s)
+	b, err := Get(doc, "b").BoolE()
+	require.NoError(t, err)
+	require.True(t, b)
+	i, err := Get(doc, "i").Int32E()
+	require.NoError(t, err)
+	require.Equal(t, int32(0), i)
+	l, err := Get(doc, "l").Int64E()
+	require.NoError(t, err)
+	require.Equal(t, int64(42), l)
+	n, err := Get(doc, "f").IntE()
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	f, err := Get(doc, "i").Float64E()
+	require.NoError(t, err)
+	require.Equal(t, 0.0, f)
+	tm, err := Get(doc, "t").TimeE()
+	require.NoError(t, err)
+	require.True(t, when.Equal(tm))
+
+	_, err = Get(doc, "s").Int64E()
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	_, err = Get(doc, "i").StringE()
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	_, err = Get(doc, "missing").BoolE()
+	require.ErrorIs(t, err, ErrNotExist)
+	_, err = Get(doc, "s").ObjectIDE()
+	require.ErrorIs(t, err, ErrTypeMismatch)
+
+	_, err = Result{Type: BSONTypeDouble, Raw: []byte{1, 2}}.Float64E()
+	require.ErrorIs(t, err, ErrInvalidLength)
+	_, err = Result{Type: BSONTypeString, Raw: []byte{10, 0, 0, 0, 'a', 0}}.StringE()
+	require.ErrorIs(t, err, ErrInvalidLength)
+	_, err = Result{Type: BSONTypeString, Raw: []byte{2, 0, 0, 0, 'a', 'b'}}.StringE()
+	require.ErrorIs(t, err, ErrInvalidLength)
+}
+
+func TestDefaultedAccessors(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "s", Value: "text"},
+		{Key: "b", Value: false},
+		{Key: "i", Value: int32(3)},
+		{Key: "null", Value: nil},
+	})
+	require.Equal(t, "text", Get(doc, "s").StringOr("def"))
+	require.Equal(t, "def", Get(doc, "missing").StringOr("def"))
+	require.Equal(t, "def", Get(doc, "i").StringOr("def"))
+	require.False(t, Get(doc, "b").BoolOr(true))
+	require.True(t, Get(doc, "null").BoolOr(true))
+	require.Equal(t, int64(3), Get(doc, "i").Int64Or(9))
+	require.Equal(t, int64(9), Get(doc, "null").Int64Or(9))
+	require.Equal(t, int32(9), Get(doc, "s").Int32Or(9))
+	require.Equal(t, 9, Get(doc, "missing").IntOr(9))
+	require.Equal(t, 3.0, Get(doc, "i").Float64Or(1.5))
+	require.Equal(t, 1.5, Get(doc, "b").Float64Or(1.5))
+	def := time.Unix(1, 0)
+	require.Equal(t, def, Get(doc, "i").TimeOr(def))
+}
+
+func TestCheckedAccessors(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "small", Value: int64(7)},
+		{Key: "large", Value: int64(1) << 40},
+		{Key: "negative", Value: int32(-1)},
+		{Key: "integral", Value: 3.0},
+		{Key: "fraction", Value: 3.5},
+		{Key: "huge", Value: 1e19},
+		{Key: "precise", Value: int64(1)<<53 + 1},
+		{Key: "string", Value: "1"},
+	})
+
+	i32, err := Get(doc, "small").Int32Checked()
+	require.NoError(t, err)
+	require.Equal(t, int32(7), i32)
+	_, err = Get(doc, "large").Int32Checked()
+	require.ErrorIs(t, err, ErrLossyConversion)
+	i64, err := Get(doc, "integral").Int64Checked()
+	require.NoError(t, err)
+	require.Equal(t, int64(3), i64)
+	_, err = Get(doc, "fraction").Int64Checked()
+	require.ErrorIs(t, err, ErrLossyConversion)
+	_, err = Get(doc, "huge").Int64Checked()
+	require.ErrorIs(t, err, ErrLossyConversion)
+	n, err := Get(doc, "large").IntChecked()
+	require.NoError(t, err)
+	require.Equal(t, 1<<40, n)
+
+	_, err = Get(doc, "negative").Uint64Checked()
+	require.ErrorIs(t, err, ErrLossyConversion)
+	u64, err := Get(doc, "huge").Uint64Checked()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1e19), u64)
+	_, err = Get(doc, "large").Uint32Checked()
+	require.ErrorIs(t, err, ErrLossyConversion)
+
+	f, err := Get(doc, "large").Float64Checked()
+	require.NoError(t, err)
+	require.Equal(t, float64(1<<40), f)
+	_, err = Get(doc, "precise").Float64Checked()
+	require.ErrorIs(t, err, ErrLossyConversion)
+
+	_, err = Get(doc, "string").Int32Checked()
+	require.ErrorIs(t, err, ErrTypeMismatch)
+}
+
+func TestStrictStrings(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "ok", Value: "中文"},
+		{Key: "bad", Value: "bad\xff"},
+		{Key: "nul", Value: "a\x00b"},
+	})
+	require.Equal(t, "bad\xff", Get(doc, "bad").String())
+	require.Equal(t, "a\x00b", Get(doc, "nul").String())
+
+	strict := GetOptions{StrictStrings: true}
+	require.Equal(t, "中文", GetLimited(doc, strict, "ok").String())
+	for _, key := range []string{"bad", "nul"} {
+		require.Equal(t, "", GetLimited(doc, strict, key).String())
+		require.Nil(t, GetLimited(doc, strict, key).StringBytes())
+		require.Equal(t, "def", GetLimited(doc, strict, key).StringOr("def"))
+		_, err := GetLimited(doc, strict, key).StringE()
+		require.ErrorIs(t, err, ErrInvalidValue)
+	}
+}
@@ -0,0 +1,57 @@
+package gbson
+
+import (
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// ResultCodec is a mongo-driver codec for Result. Decoding keeps the raw bytes of the value,
+// so a struct field of type Result receives a sub-document untouched and its fields are accessed lazily
+// by gbson later. Encoding writes the raw bytes back as is.
+type ResultCodec struct{}
+
+var (
+	_ bsoncodec.ValueEncoder = ResultCodec{}
+	_ bsoncodec.ValueDecoder = ResultCodec{}
+)
+
+// RegisterCodec registers ResultCodec for Result to the registry builder.
+func RegisterCodec(rb *bsoncodec.RegistryBuilder) *bsoncodec.RegistryBuilder {
+	return rb.RegisterTypeEncoder(resultType, ResultCodec{}).RegisterTypeDecoder(resultType, ResultCodec{})
+}
+
+// NewRegistry returns mongo-driver's default registry with ResultCodec registered,
+// which could be set to clients, collections or bson.UnmarshalWithRegistry.
+func NewRegistry() *bsoncodec.Registry {
+	return RegisterCodec(bson.NewRegistryBuilder()).Build()
+}
+
+// EncodeValue implements bsoncodec.ValueEncoder, a missing or zero result is written as null, as undefined
+// is deprecated and rejected by some drivers.
+func (ResultCodec) EncodeValue(_ bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+	if !val.IsValid() || val.Type() != resultType {
+		return bsoncodec.ValueEncoderError{Name: "ResultCodec.EncodeValue", Types: []reflect.Type{resultType}, Received: val}
+	}
+	r := val.Interface().(Result)
+	if r.Type == 0 || !r.Exist() {
+		return vw.WriteNull()
+	}
+	return bsonrw.Copier{}.CopyValueFromBytes(vw, bsontype.Type(r.Type), r.Raw)
+}
+
+// DecodeValue implements bsoncodec.ValueDecoder, the raw bytes of the value are copied into the result.
+func (ResultCodec) DecodeValue(_ bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	if !val.CanSet() || val.Type() != resultType {
+		return bsoncodec.ValueDecoderError{Name: "ResultCodec.DecodeValue", Types: []reflect.Type{resultType}, Received: val}
+	}
+	tp, raw, err := bsonrw.Copier{}.CopyValueToBytes(vr)
+	if err != nil {
+		return err
+	}
+	val.Set(reflect.ValueOf(Result{Type: Type(tp), Raw: raw}))
+	return nil
+}
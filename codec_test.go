@@ -0,0 +1,40 @@
+package gbson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+type testCodecStruct struct {
+	Name    string
+	Payload Result
+	Items   []Result
+}
+
+func TestResultCodec(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "name", Value: "lazy"},
+		{Key: "payload", Value: bson.D{{Key: "a", Value: int32(1)}, {Key: "b", Value: bson.A{"x"}}}},
+		{Key: "items", Value: bson.A{int64(2), "y"}},
+	})
+	registry := NewRegistry()
+	var v testCodecStruct
+	require.NoError(t, bson.UnmarshalWithRegistry(registry, doc, &v))
+	require.Equal(t, "lazy", v.Name)
+	require.Equal(t, BSONTypeObject, v.Payload.Type)
+	require.Equal(t, "x", v.Payload.Get("b", "0").String())
+	require.Len(t, v.Items, 2)
+	require.Equal(t, int64(2), v.Items[0].Int64())
+	require.Equal(t, "y", v.Items[1].String())
+
+	actual, err := bson.MarshalWithRegistry(registry, v)
+	require.NoError(t, err)
+	require.Equal(t, doc, actual)
+
+	actual, err = bson.MarshalWithRegistry(registry, testCodecStruct{})
+	require.NoError(t, err)
+	require.Equal(t, bsontype.Null, bson.Raw(actual).Lookup("payload").Type)
+}
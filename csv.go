@@ -0,0 +1,103 @@
+package gbson
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CSVOptions configures ExportCSV.
+type CSVOptions struct {
+	// Columns are the dotted paths of the columns, like "a.b" or "tags.0".
+	// If empty, the columns are the flattened paths of the first document.
+	Columns []string
+	// NoHeader skips the header row of column names.
+	NoHeader bool
+	// Format formats a value of the column, missing values included. If nil, values are formatted by Str.
+	Format func(column string, value Result) string
+}
+
+// ExportCSV reads a stream of bson documents and writes one CSV row per document.
+// Documents are flattened to dotted paths, the paths of nested documents and arrays are joined by ".",
+// e.g. {"a": {"b": [1]}} has a column "a.b.0". A nil opts exports all columns of the first document.
+func ExportCSV(r io.Reader, w io.Writer, opts *CSVOptions) error {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	format := opts.Format
+	if format == nil {
+		format = func(_ string, value Result) string { return value.Str() }
+	}
+	cw := csv.NewWriter(w)
+	dec := NewDecoder(r)
+	columns := opts.Columns
+	var paths [][]string
+	var doc []byte
+	var row []string
+	for {
+		var err error
+		if doc, err = dec.readDocument(doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if paths == nil {
+			if len(columns) == 0 {
+				if columns, err = flattenPaths(resultFromBytes(doc)); err != nil {
+					return errors.WithMessage(err, "document 0")
+				}
+			}
+			if !opts.NoHeader {
+				if err = cw.Write(columns); err != nil {
+					return err
+				}
+			}
+			paths = make([][]string, len(columns))
+			for i, column := range columns {
+				paths[i] = strings.Split(column, ".")
+			}
+			row = make([]string, len(columns))
+		}
+		for i, path := range paths {
+			row[i] = format(columns[i], Get(doc, path...))
+		}
+		if err = cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// flattenPaths returns the dotted paths of all the leaf values in the document, in the document order.
+// Empty documents and arrays are leaves as well.
+func flattenPaths(r Result) ([]string, error) {
+	var paths []string
+	var walk func(prefix string, r Result, depth int) error
+	walk = func(prefix string, r Result, depth int) error {
+		if err := checkDepth(depth); err != nil {
+			return err
+		}
+		var err error
+		_, iterErr := r.iterFields(func(key []byte, value Result) bool {
+			path := string(key)
+			if prefix != "" {
+				path = prefix + "." + path
+			}
+			if value.IsContainer() && value.Length() > 0 {
+				err = walk(path, value, depth+1)
+				return err == nil
+			}
+			paths = append(paths, path)
+			return true
+		})
+		if iterErr != nil {
+			return iterErr
+		}
+		return err
+	}
+	return paths, walk("", r, 1)
+}
@@ -0,0 +1,52 @@
+package gbson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestExportCSV(t *testing.T) {
+	stream := bytes.Join([][]byte{
+		mustMarshal(t, bson.D{
+			{Key: "name", Value: "a,b"},
+			{Key: "n", Value: int32(1)},
+			{Key: "sub", Value: bson.D{{Key: "x", Value: 1.5}, {Key: "empty", Value: bson.D{}}}},
+			{Key: "tags", Value: bson.A{"t1", "t2"}},
+		}),
+		mustMarshal(t, bson.D{
+			{Key: "n", Value: int64(2)},
+			{Key: "name", Value: "line\nbreak"},
+			{Key: "when", Value: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)},
+			{Key: "tags", Value: bson.A{"t3"}},
+		}),
+	}, nil)
+
+	var out bytes.Buffer
+	require.NoError(t, ExportCSV(bytes.NewReader(stream), &out, nil))
+	require.Equal(t, strings.Join([]string{
+		"name,n,sub.x,sub.empty,tags.0,tags.1",
+		`"a,b",1,1.5,{},t1,t2`,
+		`"line` + "\n" + `break",2,,,t3,`,
+		"",
+	}, "\n"), out.String())
+
+	out.Reset()
+	require.NoError(t, ExportCSV(bytes.NewReader(stream), &out, &CSVOptions{
+		Columns:  []string{"when", "tags", "missing"},
+		NoHeader: true,
+		Format: func(column string, value Result) string {
+			if !value.Exist() {
+				return "-"
+			}
+			return column + "=" + value.Str()
+		},
+	}))
+	require.Equal(t, "-,\"tags=[\"\"t1\"\",\"\"t2\"\"]\",-\nwhen=2022-01-02T03:04:05Z,\"tags=[\"\"t3\"\"]\",-\n", out.String())
+
+	require.Error(t, ExportCSV(bytes.NewReader(stream[:len(stream)-1]), &out, nil))
+}
@@ -0,0 +1,90 @@
+package gbson
+
+// Cursor iterates the elements of a document or an array in a loop rather than callbacks, so tight loops
+// have no closures escaping to the heap. It's a value, which is usually a local variable:
+//
+//	c := r.Cursor()
+//	for c.Next() {
+//		fmt.Println(string(c.Key()), c.Value().Str())
+//	}
+//	if err := c.Err(); err != nil {
+//		...
+//	}
+//
+// Embedded documents and arrays are values, see ElementReader for reading into them.
+type Cursor struct {
+	raw      []byte // the document or array, for locating errors
+	elements []byte // unread elements
+	key      []byte
+	value    Result
+	err      error
+}
+
+// Cursor returns a cursor of the elements of the document or array, Err of which is ErrNotObject
+// for the other values.
+func (r Result) Cursor() Cursor {
+	if !r.IsContainer() {
+		return Cursor{err: ErrNotObject}
+	}
+	elements, ok := containerElements(r.Raw)
+	if !ok {
+		return Cursor{raw: r.Raw, err: ErrInvalidLength}
+	}
+	return Cursor{raw: r.Raw, elements: elements}
+}
+
+// Next advances to the next element, it returns false at the end or on errors.
+func (c *Cursor) Next() bool {
+	for len(c.elements) > 0 && c.err == nil {
+		tp, name, value, n := consumeElement(c.elements)
+		if n < 0 {
+			c.err = ErrInvalidLength
+			break
+		}
+		c.elements = c.elements[n:]
+		c.key, c.value = name, Result{Type: tp, Raw: value}
+		return true
+	}
+	c.key, c.value = nil, Result{Type: BSONTypeUndefined}
+	return false
+}
+
+// Key returns the name of the current element, which refers to the document without copying.
+// Keys of arrays are the indexes.
+func (c *Cursor) Key() []byte {
+	return c.key
+}
+
+// Value returns the value of the current element.
+func (c *Cursor) Value() Result {
+	return c.value
+}
+
+// Err returns the error occurred while iterating, nil if the elements are iterated to the end.
+// Malformed elements are located by a Finding, as Walk does.
+func (c *Cursor) Err() error {
+	c.err = locateError(c.raw, c.err)
+	return c.err
+}
+
+// Field is an element of a document.
+type Field struct {
+	Key   []byte // refers to the document without copying
+	Value Result
+}
+
+// Fields returns the top level elements of the document in order with a single scan, a faster alternative
+// to Get per key or Map when most of the elements are used. Duplicate keys are all returned.
+// The elements before a malformed one are returned, Validate tells whether it's malformed.
+func Fields(doc []byte) []Field {
+	return AppendFields(nil, doc)
+}
+
+// AppendFields appends the top level elements of the document to dst like Fields, reusing the slice in
+// hot loops, e.g. fields = gbson.AppendFields(fields[:0], doc).
+func AppendFields(dst []Field, doc []byte) []Field {
+	for c := resultFromBytes(doc).Cursor(); c.Next(); {
+		dst = append(dst, Field{Key: c.Key(), Value: c.Value()})
+	}
+	return dst
+}
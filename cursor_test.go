@@ -0,0 +1,66 @@
+package gbson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCursor(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	var keys []string
+	var values []Result
+	c := Get(doc).Cursor()
+	for c.Next() {
+		keys = append(keys, string(c.Key()))
+		values = append(values, c.Value())
+	}
+	require.NoError(t, c.Err())
+	require.False(t, c.Next())
+	require.False(t, c.Value().Exist())
+	i := 0
+	Get(doc).IterDocument(func(key string, r Result) bool {
+		require.Equal(t, key, keys[i])
+		require.Equal(t, r, values[i])
+		i++
+		return true
+	})
+	require.Equal(t, len(keys), i)
+
+	c = Get(doc, "doc", "b").Cursor()
+	require.True(t, c.Next())
+	require.Equal(t, "0", string(c.Key()))
+	require.Equal(t, int64(2), c.Value().Int64())
+
+	c = Get(doc, "int32").Cursor()
+	require.False(t, c.Next())
+	require.ErrorIs(t, c.Err(), ErrNotObject)
+
+	bad := mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}, {Key: "s", Value: "x"}})
+	bad[14] = 100 // the length of s
+	c = Get(bad).Cursor()
+	require.True(t, c.Next())
+	require.False(t, c.Next())
+	require.ErrorIs(t, c.Err(), ErrInvalidLength)
+	require.Equal(t, "s", c.Err().(Finding).Path)
+	require.Equal(t, 14, c.Err().(Finding).Offset)
+	c = Get(bad[:len(bad)-1]).Cursor()
+	require.False(t, c.Next())
+	require.ErrorIs(t, c.Err(), ErrInvalidLength)
+}
+
+func TestFields(t *testing.T) {
+	doc := mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}, {Key: "b", Value: "x"}, {Key: "a", Value: true}})
+	fields := Fields(doc)
+	require.Len(t, fields, 3)
+	for i, key := range []string{"a", "b", "a"} {
+		require.Equal(t, key, string(fields[i].Key))
+	}
+	require.Equal(t, Get(doc, "b"), fields[1].Value)
+	require.True(t, fields[2].Value.Bool())
+	require.Equal(t, fields, AppendFields(fields[:0], doc))
+	require.Len(t, AppendFields(fields[:1], doc), 4)
+	require.Len(t, Fields(doc[:len(doc)-1]), 0)
+	require.Empty(t, Fields(mustMarshal(t, bson.D{})))
+}
@@ -0,0 +1,197 @@
+package gbson
+
+import (
+	"encoding/binary"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const decimal128ExponentBias = 6176
+
+// Decimal128 is the IEEE 754-2008 128-bit decimal floating point value, in the BID encoding.
+type Decimal128 struct {
+	High, Low uint64
+}
+
+// Decimal128 returns the decimal value, zero for the other types.
+func (r Result) Decimal128() Decimal128 {
+	if r.Type == BSONTypeDecimal128 && len(r.Raw) >= 16 {
+		return Decimal128{
+			Low:  binary.LittleEndian.Uint64(r.Raw),
+			High: binary.LittleEndian.Uint64(r.Raw[8:]),
+		}
+	}
+	return Decimal128{}
+}
+
+// IsNaN reports whether the value is not a number.
+func (d Decimal128) IsNaN() bool {
+	return d.High>>58&0x1F == 0x1F
+}
+
+// IsInf reports whether the value is an infinity, sign > 0 checks +Inf, sign < 0 checks -Inf and 0 checks either.
+func (d Decimal128) IsInf(sign int) bool {
+	if d.High>>58&0x1F != 0x1E {
+		return false
+	}
+	neg := d.High>>63 == 1
+	return sign == 0 || (sign > 0 && !neg) || (sign < 0 && neg)
+}
+
+// IsCanonical reports whether the value is in the canonical encoding of IEEE 754-2008, the only one
+// encoders produce: coefficients of finite values are at most 34 digits, which excludes the combination
+// fields with the 2 bits prefix, payloads of NaNs are at most 33 digits, and the bits of infinities
+// and NaNs following the combination field prefix are zero, except the signaling bit of NaNs.
+// Non-canonical values are garbage, e.g. decimals of out of range coefficients are zero by the specification.
+func (d Decimal128) IsCanonical() bool {
+	reason := d.abnormality()
+	return reason == "" || reason == "is a signaling NaN"
+}
+
+// abnormality tells why the value is not canonical or is a signaling NaN, "" if neither.
+func (d Decimal128) abnormality() string {
+	switch {
+	case d.IsInf(0):
+		if d.High&(1<<58-1) != 0 || d.Low != 0 {
+			return "infinity has non-zero bits"
+		}
+	case d.IsNaN():
+		if d.High>>46&(1<<11-1) != 0 {
+			return "NaN has non-zero exponent bits"
+		}
+		if greaterUint128(d.High&(1<<46-1), d.Low, maxNaNPayloadHigh, maxNaNPayloadLow) {
+			return "NaN payload exceeds 33 digits"
+		}
+		if d.High>>57&1 == 1 {
+			return "is a signaling NaN"
+		}
+	case d.High>>61&3 == 3:
+		return "coefficient of the combination field exceeds 34 digits"
+	case greaterUint128(d.High&(1<<49-1), d.Low, maxCoefficientHigh, maxCoefficientLow):
+		return "coefficient exceeds 34 digits"
+	}
+	return ""
+}
+
+// The high and low 64 bits of the max coefficient 10^34-1 and the max NaN payload 10^33-1.
+const (
+	maxCoefficientHigh = 0x1ed09bead87c0
+	maxCoefficientLow  = 0x378d8e63ffffffff
+	maxNaNPayloadHigh  = 0x314dc6448d93
+	maxNaNPayloadLow   = 0x38c15b09ffffffff
+)
+
+// greaterUint128 reports whether the 128 bits integer of high and low is greater than the one of high2 and low2.
+func greaterUint128(high, low, high2, low2 uint64) bool {
+	return high > high2 || high == high2 && low > low2
+}
+
+// parts splits a finite value into coefficient * 10^exp,
+// coefficients out of the valid range are treated as zero as the specification says.
+func (d Decimal128) parts() (neg bool, coefficient *big.Int, exp int) {
+	neg = d.High>>63 == 1
+	coefficient = new(big.Int)
+	if d.High>>61&3 == 3 {
+		// 2 bits combination prefix, the implied significand is always out of range
+		exp = int(d.High>>47&(1<<14-1)) - decimal128ExponentBias
+		return
+	}
+	exp = int(d.High>>49&(1<<14-1)) - decimal128ExponentBias
+	coefficient.SetUint64(d.High & (1<<49 - 1))
+	coefficient.Lsh(coefficient, 64)
+	coefficient.Or(coefficient, new(big.Int).SetUint64(d.Low))
+	if coefficient.Cmp(maxDecimal128Coefficient) > 0 {
+		coefficient.SetUint64(0)
+	}
+	return
+}
+
+var maxDecimal128Coefficient, _ = new(big.Int).SetString(strings.Repeat("9", 34), 10)
+
+// String renders the value the same as the bson decimal128 specification, like "1.23", "-0", "1.0E+3", "NaN" or "Infinity".
+func (d Decimal128) String() string {
+	if d.IsNaN() {
+		return "NaN"
+	}
+	if d.IsInf(1) {
+		return "Infinity"
+	}
+	if d.IsInf(-1) {
+		return "-Infinity"
+	}
+	neg, coefficient, exp := d.parts()
+	digits := coefficient.String()
+	var sb strings.Builder
+	if neg {
+		sb.WriteByte('-')
+	}
+	adjusted := exp + len(digits) - 1
+	switch {
+	case exp == 0:
+		sb.WriteString(digits)
+	case exp < 0 && adjusted >= -6:
+		if n := len(digits) + exp; n > 0 {
+			sb.WriteString(digits[:n])
+			sb.WriteByte('.')
+			sb.WriteString(digits[n:])
+		} else {
+			sb.WriteString("0.")
+			sb.WriteString(strings.Repeat("0", -n))
+			sb.WriteString(digits)
+		}
+	default:
+		sb.WriteByte(digits[0])
+		if len(digits) > 1 {
+			sb.WriteByte('.')
+			sb.WriteString(digits[1:])
+		}
+		sb.WriteByte('E')
+		if adjusted > 0 {
+			sb.WriteByte('+')
+		}
+		sb.WriteString(strconv.Itoa(adjusted))
+	}
+	return sb.String()
+}
+
+// BigRat converts the value into a big.Rat exactly, ok is false for NaN and infinities.
+func (d Decimal128) BigRat() (rat *big.Rat, ok bool) {
+	if d.IsNaN() || d.IsInf(0) {
+		return nil, false
+	}
+	neg, coefficient, exp := d.parts()
+	if neg {
+		coefficient.Neg(coefficient)
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exp))), nil)
+	if exp >= 0 {
+		return new(big.Rat).SetInt(coefficient.Mul(coefficient, scale)), true
+	}
+	return new(big.Rat).SetFrac(coefficient, scale), true
+}
+
+// BigFloat converts the value into a big.Float with the given precision in bits, 0 means the precision of
+// big.Float.SetRat. Infinities are converted to infinite big.Float, ok is false for NaN.
+func (d Decimal128) BigFloat(prec uint) (f *big.Float, ok bool) {
+	if d.IsNaN() {
+		return nil, false
+	}
+	f = new(big.Float).SetPrec(prec)
+	if d.IsInf(0) {
+		return f.SetInf(d.IsInf(-1)), true
+	}
+	rat, _ := d.BigRat()
+	f.SetRat(rat)
+	if rat.Sign() == 0 && d.High>>63 == 1 {
+		f.Neg(f)
+	}
+	return f, true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,77 @@
+package gbson
+
+import (
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDecimal128(t *testing.T) {
+	for _, s := range []string{
+		"0", "-0", "12.34", "-12.34", "0.000001234", "1.234E-7", "1E+3", "1.000E+3", "0E-10",
+		"9999999999999999999999999999999999", "1.23E+6144", "1E-6176", "NaN", "Infinity", "-Infinity",
+	} {
+		dec, err := primitive.ParseDecimal128(s)
+		require.NoError(t, err)
+		doc := mustMarshal(t, bson.D{{Key: "d", Value: dec}})
+		d := Get(doc, "d").Decimal128()
+		high, low := dec.GetBytes()
+		require.Equal(t, Decimal128{High: high, Low: low}, d)
+		require.Equal(t, dec.String(), d.String(), s)
+		require.True(t, d.IsCanonical(), s)
+	}
+
+	dec, _ := primitive.ParseDecimal128("-12.34")
+	doc := mustMarshal(t, bson.D{{Key: "d", Value: dec}})
+	d := Get(doc, "d").Decimal128()
+	rat, ok := d.BigRat()
+	require.True(t, ok)
+	require.Equal(t, big.NewRat(-1234, 100), rat)
+	f, ok := d.BigFloat(0)
+	require.True(t, ok)
+	require.Equal(t, "-12.34", f.Text('f', 2))
+
+	inf, _ := primitive.ParseDecimal128("-Infinity")
+	d = Get(mustMarshal(t, bson.D{{Key: "d", Value: inf}}), "d").Decimal128()
+	require.True(t, d.IsInf(-1))
+	f, ok = d.BigFloat(0)
+	require.True(t, ok)
+	require.True(t, f.IsInf())
+	_, ok = d.BigRat()
+	require.False(t, ok)
+
+	require.Equal(t, Decimal128{}, Get(doc, "missing").Decimal128())
+}
+
+func TestDecimal128Canonical(t *testing.T) {
+	for _, d := range []Decimal128{
+		{High: 6176<<49 | maxCoefficientHigh, Low: maxCoefficientLow + 1}, // 10^34
+		{High: 3<<61 | 6176<<47},
+		{High: 0x7800000000000000 | 1},
+		{High: 0x7c00000000000000 | 1<<46},
+		{High: 0x7c00000000000000 | maxNaNPayloadHigh, Low: maxNaNPayloadLow + 1},
+	} {
+		require.False(t, d.IsCanonical(), "%x %x", d.High, d.Low)
+	}
+	for _, d := range []Decimal128{
+		{High: 6176<<49 | maxCoefficientHigh, Low: maxCoefficientLow},
+		{High: 0x7c00000000000000 | maxNaNPayloadHigh, Low: maxNaNPayloadLow},
+		{High: 0x7e00000000000000}, // signaling
+	} {
+		require.True(t, d.IsCanonical(), "%x %x", d.High, d.Low)
+	}
+
+	nan, _ := primitive.ParseDecimal128("NaN")
+	doc := mustMarshal(t, bson.D{{Key: "d", Value: nan}})
+	require.NoError(t, Validator{CanonicalDecimals: true}.Validate(doc))
+	binary.LittleEndian.PutUint64(doc[15:], 0x7e00000000000000)
+	require.NoError(t, Validate(doc))
+	require.Equal(t, Finding{Offset: 7, Path: "d", Reason: "decimal128 is a signaling NaN", Cause: ErrInvalidValue},
+		Validator{CanonicalDecimals: true}.Validate(doc))
+	binary.LittleEndian.PutUint64(doc[15:], 3<<61)
+	require.ErrorIs(t, Validator{CanonicalDecimals: true}.Validate(doc), ErrInvalidValue)
+}
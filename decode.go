@@ -0,0 +1,109 @@
+package gbson
+
+// Value decodes the value into its natural Go representation:
+//
+//	Double                        float64
+//	String, Symbol, JavaScript    string
+//	Object                        map[string]interface{}
+//	Array                         []interface{}
+//	Binary                        []byte, a copy of the payload
+//	ObjectID                      [12]byte
+//	Boolean                       bool
+//	DateTime                      time.Time
+//	Int32                         int32
+//	Int64                         int64
+//	Decimal128                    Decimal128
+//	Null, Undefined               nil
+//
+// Regex, DBPointer, JavaScriptWithScope, Timestamp, MinKey and MaxKey values have no
+// natural representation, they are returned as the Result itself.
+// Containers are decoded recursively, a missing result decodes to nil.
+// Containers nested deeper than DefaultMaxDepth are returned as the Result itself, see ValueE.
+func (r Result) Value() interface{} {
+	return r.value(1)
+}
+
+// ValueE is Value returning ErrMaxDepth for containers nested deeper than DefaultMaxDepth,
+// instead of decoding the deep ones into Results.
+func (r Result) ValueE() (interface{}, error) {
+	if r.IsContainer() {
+		if err := checkNesting(r.Raw, 1); err != nil {
+			return nil, err
+		}
+	}
+	return r.Value(), nil
+}
+
+// value is Value of the value at the depth if it's a container.
+func (r Result) value(depth int) interface{} {
+	switch r.Type {
+	case BSONTypeDouble:
+		return r.Float64()
+	case BSONTypeString, BSONTypeSymbol, BSONTypeJavaScript:
+		return r.String()
+	case BSONTypeObject:
+		if depth > DefaultMaxDepth {
+			return r
+		}
+		return r.toMap(depth)
+	case BSONTypeArray:
+		if depth > DefaultMaxDepth {
+			return r
+		}
+		return r.toSlice(depth)
+	case BSONTypeBinary:
+		_, data := r.BinaryCopy()
+		return data
+	case BSONTypeObjectID:
+		return r.ObjectID()
+	case BSONTypeBoolean:
+		return r.Bool()
+	case BSONTypeDateTime:
+		return r.Time()
+	case BSONTypeInt32:
+		return r.Int32()
+	case BSONTypeInt64:
+		return r.Int64()
+	case BSONTypeDecimal128:
+		return r.Decimal128()
+	case BSONTypeNull, BSONTypeUndefined:
+		return nil
+	}
+	return r
+}
+
+// ToMap decodes a document into a map recursively, values are decoded by Value, so containers nested deeper
+// than DefaultMaxDepth are Results. It returns nil if the value is not a document.
+func (r Result) ToMap() map[string]interface{} {
+	if r.Type != BSONTypeObject {
+		return nil
+	}
+	return r.toMap(1)
+}
+
+func (r Result) toMap(depth int) map[string]interface{} {
+	m := make(map[string]interface{})
+	_, _ = r.iterFields(func(key []byte, r Result) bool {
+		m[string(key)] = r.value(depth + 1)
+		return true
+	})
+	return m
+}
+
+// ToSlice decodes an array into a slice recursively, values are decoded by Value, so containers nested deeper
+// than DefaultMaxDepth are Results. It returns nil if the value is not an array.
+func (r Result) ToSlice() []interface{} {
+	if r.Type != BSONTypeArray {
+		return nil
+	}
+	return r.toSlice(1)
+}
+
+func (r Result) toSlice(depth int) []interface{} {
+	a := make([]interface{}, 0)
+	_, _ = r.iterFields(func(_ []byte, r Result) bool {
+		a = append(a, r.value(depth+1))
+		return true
+	})
+	return a
+}
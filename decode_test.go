@@ -0,0 +1,58 @@
+package gbson
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValue(t *testing.T) {
+	oid := primitive.NewObjectID()
+	when := time.UnixMilli(1668000000123)
+	doc := mustMarshal(t, bson.D{
+		{Key: "_id", Value: oid},
+		{Key: "double", Value: 1.5},
+		{Key: "string", Value: "text"},
+		{Key: "doc", Value: bson.D{{Key: "a", Value: int32(1)}, {Key: "b", Value: bson.A{"x", int64(2), nil}}}},
+		{Key: "binary", Value: []byte{1, 2}},
+		{Key: "bool", Value: true},
+		{Key: "date", Value: when},
+		{Key: "null", Value: nil},
+		{Key: "ts", Value: primitive.Timestamp{T: 1, I: 2}},
+	})
+	v := Get(doc).Value()
+	require.Equal(t, map[string]interface{}{
+		"_id":    [12]byte(oid),
+		"double": 1.5,
+		"string": "text",
+		"doc": map[string]interface{}{
+			"a": int32(1),
+			"b": []interface{}{"x", int64(2), nil},
+		},
+		"binary": []byte{1, 2},
+		"bool":   true,
+		"date":   when,
+		"null":   nil,
+		"ts":     Get(doc, "ts"),
+	}, v)
+	require.Nil(t, Get(doc, "missing").Value())
+}
+
+func TestToMap(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "doc", Value: bson.D{{Key: "a", Value: int32(1)}, {Key: "b", Value: bson.A{"x", bson.D{}}}}},
+		{Key: "empty", Value: bson.A{}},
+	})
+	require.Equal(t, map[string]interface{}{
+		"a": int32(1),
+		"b": []interface{}{"x", map[string]interface{}{}},
+	}, Get(doc, "doc").ToMap())
+	require.Equal(t, []interface{}{"x", map[string]interface{}{}}, Get(doc, "doc", "b").ToSlice())
+	require.Equal(t, []interface{}{}, Get(doc, "empty").ToSlice())
+	require.Nil(t, Get(doc, "empty").ToMap())
+	require.Nil(t, Get(doc, "doc").ToSlice())
+	require.Nil(t, Get(doc, "missing").ToMap())
+}
@@ -0,0 +1,125 @@
+package gbson
+
+// ElementReader is a pull parser reading the elements of a document one by one, an alternative to the
+// callbacks of IterDocument for stateful consumers:
+//
+//	er := gbson.NewElementReader(doc)
+//	for er.Next() {
+//		if er.Value().IsContainer() {
+//			er.Enter() // the following elements are the ones of the container
+//		}
+//		fmt.Println(er.Depth(), er.Key(), er.Value().Str())
+//	}
+//	if err := er.Err(); err != nil {
+//		...
+//	}
+//
+// Embedded documents and arrays are skipped as a whole unless entered. The elements of an entered
+// container are followed by the rest of its parent's, Depth tells which container an element belongs to.
+type ElementReader struct {
+	doc   []byte   // for locating errors
+	stack [][]byte // unread elements of the entered containers, the innermost is the last
+	key   []byte
+	value Result
+	enter bool
+	err   error
+}
+
+// NewElementReader returns a reader of the elements of the document.
+func NewElementReader(doc []byte) *ElementReader {
+	er := &ElementReader{doc: doc}
+	if elements, ok := containerElements(doc); ok {
+		er.stack = append(er.stack, elements)
+	} else {
+		er.err = ErrInvalidLength
+	}
+	return er
+}
+
+// containerElements returns the elements of a document or an array, which are between the length and
+// the terminating zero byte.
+func containerElements(raw []byte) ([]byte, bool) {
+	n := int(consumeInt32(raw))
+	if n < 5 || n > len(raw) || raw[n-1] != 0 {
+		return nil, false
+	}
+	return raw[4 : n-1], true
+}
+
+// Next advances to the next element, it returns false at the end of the document or on errors.
+func (er *ElementReader) Next() bool {
+	if er.err != nil {
+		return false
+	}
+	if er.enter {
+		er.enter = false
+		elements, ok := containerElements(er.value.Raw)
+		if !ok {
+			er.err = ErrInvalidLength
+			return false
+		}
+		if er.err = checkDepth(len(er.stack) + 1); er.err != nil {
+			return false
+		}
+		er.stack = append(er.stack, elements)
+	}
+	for len(er.stack) > 0 {
+		top := len(er.stack) - 1
+		if len(er.stack[top]) == 0 {
+			er.stack = er.stack[:top]
+			continue
+		}
+		tp, name, value, n := consumeElement(er.stack[top])
+		if n < 0 {
+			er.err = ErrInvalidLength
+			return false
+		}
+		er.stack[top] = er.stack[top][n:]
+		er.key, er.value = name, Result{Type: tp, Raw: value}
+		return true
+	}
+	er.key, er.value = nil, Result{Type: BSONTypeUndefined}
+	return false
+}
+
+// Key returns the name of the current element, which refers to the document without copying.
+func (er *ElementReader) Key() []byte {
+	return er.key
+}
+
+// Value returns the value of the current element.
+func (er *ElementReader) Value() Result {
+	return er.value
+}
+
+// Depth returns the number of containers the current element is in, 0 for the elements of the document.
+func (er *ElementReader) Depth() int {
+	return len(er.stack) - 1
+}
+
+// Enter makes the following elements the ones of the current element, which is a document or an array.
+// It returns false for the other values.
+func (er *ElementReader) Enter() bool {
+	if !er.value.IsContainer() {
+		return false
+	}
+	er.enter = true
+	return true
+}
+
+// Skip skips the rest of the container of the current element, so the next element is the one following
+// the container. Skipping at depth 0 ends the document.
+func (er *ElementReader) Skip() {
+	er.enter = false
+	if len(er.stack) > 0 {
+		er.stack[len(er.stack)-1] = nil
+	}
+}
+
+// Err returns the error occurred while reading, nil if the document is read to the end.
+// Entering containers nested deeper than DefaultMaxDepth fails with ErrMaxDepth, and malformed elements are
+// located by a Finding, as Walk does.
+func (er *ElementReader) Err() error {
+	er.err = locateError(er.doc, er.err)
+	return er.err
+}
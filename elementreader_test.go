@@ -0,0 +1,64 @@
+package gbson
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestElementReader(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "a", Value: int32(1)},
+		{Key: "b", Value: bson.D{{Key: "c", Value: bson.A{"x", bson.D{}}}, {Key: "d", Value: true}}},
+		{Key: "e", Value: bson.A{}},
+		{Key: "f", Value: "y"},
+	})
+	var lines []string
+	er := NewElementReader(doc)
+	for er.Next() {
+		lines = append(lines, strconv.Itoa(er.Depth())+" "+string(er.Key())+" "+er.Value().TypeString())
+		require.Equal(t, er.Value().IsContainer(), er.Enter())
+	}
+	require.NoError(t, er.Err())
+	require.Equal(t, []string{
+		"0 a int", "0 b object", "1 c array", "2 0 string", "2 1 object", "1 d bool", "0 e array", "0 f string",
+	}, lines)
+
+	// containers are skipped unless entered, Skip leaves the current container
+	lines = nil
+	er = NewElementReader(doc)
+	for er.Next() {
+		lines = append(lines, string(er.Key()))
+		switch string(er.Key()) {
+		case "b":
+			er.Enter()
+		case "c":
+			er.Skip()
+		}
+	}
+	require.NoError(t, er.Err())
+	require.Equal(t, "a b c e f", strings.Join(lines, " "))
+
+	er = NewElementReader(doc[:len(doc)-1])
+	require.False(t, er.Next())
+	require.ErrorIs(t, er.Err(), ErrInvalidLength)
+
+	bad := append([]byte(nil), doc...)
+	bad[len(bad)-6] = 0x7e // type byte of "f"
+	er = NewElementReader(bad)
+	for er.Next() {
+	}
+	require.ErrorIs(t, er.Err(), ErrInvalidLength)
+
+	bad = append([]byte(nil), doc...)
+	bad[len(bad)-7] = 100 // the length of "f"
+	er = NewElementReader(bad)
+	for er.Next() {
+	}
+	require.ErrorIs(t, er.Err(), ErrInvalidLength)
+	require.Equal(t, "f", er.Err().(Finding).Path)
+	require.Equal(t, len(bad)-7, er.Err().(Finding).Offset)
+}
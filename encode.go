@@ -0,0 +1,78 @@
+package gbson
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Low level helpers appending bson encoded values to a byte slice.
+
+func appendInt32(dst []byte, v int32) []byte {
+	return append(dst, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendInt64(dst []byte, v int64) []byte {
+	return append(dst, byte(v), byte(v>>8), byte(v>>16), byte(v>>24),
+		byte(v>>32), byte(v>>40), byte(v>>48), byte(v>>56))
+}
+
+func appendDouble(dst []byte, v float64) []byte {
+	return appendInt64(dst, int64(math.Float64bits(v)))
+}
+
+func appendCString(dst []byte, s string) ([]byte, error) {
+	if strings.IndexByte(s, 0) >= 0 {
+		return dst, errors.Wrapf(ErrInvalidKey, "%q", s)
+	}
+	dst = append(dst, s...)
+	return append(dst, 0), nil
+}
+
+func appendString(dst []byte, s string) []byte {
+	dst = appendInt32(dst, int32(len(s)+1))
+	dst = append(dst, s...)
+	return append(dst, 0)
+}
+
+func appendBinary(dst []byte, subtype byte, data []byte) []byte {
+	if subtype == 0x02 { // the old binary subtype has an extra length prefix
+		dst = append(appendInt32(dst, int32(len(data)+4)), subtype)
+		return append(appendInt32(dst, int32(len(data))), data...)
+	}
+	dst = appendInt32(dst, int32(len(data)))
+	dst = append(dst, subtype)
+	return append(dst, data...)
+}
+
+// appendElementHeader appends the type byte and the element name.
+func appendElementHeader(dst []byte, tp Type, name string) ([]byte, error) {
+	return appendCString(append(dst, byte(tp)), name)
+}
+
+// beginDocument reserves the length prefix of a document, returns the start offset
+// which should be passed to endDocument later.
+func beginDocument(dst []byte) ([]byte, int) {
+	return append(dst, 0, 0, 0, 0), len(dst)
+}
+
+// endDocument appends the terminating zero and fills the length prefix.
+func endDocument(dst []byte, start int) []byte {
+	dst = append(dst, 0)
+	putInt32(dst[start:], int32(len(dst)-start))
+	return dst
+}
+
+func putInt32(dst []byte, v int32) {
+	binary.LittleEndian.PutUint32(dst, uint32(v))
+}
+
+// appendIndexHeader appends the type byte and the decimal index as the element name,
+// which is how array elements are named.
+func appendIndexHeader(dst []byte, tp Type, idx int) []byte {
+	dst = strconv.AppendInt(append(dst, byte(tp)), int64(idx), 10)
+	return append(dst, 0)
+}
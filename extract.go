@@ -0,0 +1,224 @@
+package gbson
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ExtractFromReader reads a document from r incrementally and returns the first values of the dotted paths,
+// with "*" wildcards as Matcher takes, in the order of the paths. Only the values on the paths are buffered,
+// the elements of the other keys are skipped by their lengths, so extracting a few fields of a huge document
+// doesn't buffer the whole document. Missing values are missing results.
+//
+// r is read exactly to the end of the document, so ExtractFromReader could be called repeatedly on a stream
+// of documents. An r implementing io.ByteReader, such as bufio.Reader, saves a read per byte of keys.
+// It returns io.EOF if r ends before the document, and io.ErrUnexpectedEOF if r ends in the middle of it.
+func ExtractFromReader(r io.Reader, paths ...string) ([]Result, error) {
+	x := readerExtractor{r: r, results: make([]Result, len(paths))}
+	x.byteReader, _ = r.(io.ByteReader)
+	for i := range x.results {
+		x.results[i] = Result{Type: BSONTypeUndefined}
+	}
+	if _, err := io.ReadFull(r, x.prefix[:]); err != nil {
+		return nil, err
+	}
+	x.offset = 4
+	n := int(consumeInt32(x.prefix[:]))
+	if n < 5 {
+		return nil, errors.Wrapf(ErrInvalidLength, "document declares %d bytes", n)
+	}
+	m := NewMatcher(paths...)
+	if err := x.container(n, []*matchNode{&m.root}, 1); err != nil {
+		return nil, err
+	}
+	return x.results, nil
+}
+
+// readerExtractor reads the elements of a document from a reader, matching them against the nodes of a Matcher.
+type readerExtractor struct {
+	r          io.Reader
+	byteReader io.ByteReader
+	offset     int // offset in the document of the next byte to read
+	prefix     [4]byte
+	key        []byte
+	results    []Result
+}
+
+// container reads the elements and the terminating zero byte of the container of n bytes at the depth,
+// whose length prefix is read.
+func (x *readerExtractor) container(n int, nodes []*matchNode, depth int) error {
+	if err := checkDepth(depth); err != nil {
+		return err
+	}
+	end := x.offset + n - 4
+	var next []*matchNode
+	for {
+		start := x.offset
+		b, err := x.readByte()
+		if err != nil {
+			return err
+		}
+		tp := Type(b)
+		if tp == 0 {
+			if x.offset != end {
+				return errors.Wrapf(ErrInvalidLength, "document terminated at %d, %d declared", x.offset, end)
+			}
+			return nil
+		}
+		if x.key, err = x.readCString(x.key[:0], end); err != nil {
+			return err
+		}
+		next = next[:0]
+		for _, n := range nodes {
+			if c, ok := n.children[string(x.key)]; ok {
+				next = append(next, c)
+			}
+			if n.wildcard != nil {
+				next = append(next, n.wildcard)
+			}
+		}
+		wanted, deeper := false, false
+		for _, n := range next {
+			for _, id := range n.ids {
+				wanted = wanted || !x.results[id].Exist()
+			}
+			deeper = deeper || n.children != nil || n.wildcard != nil
+		}
+		prefix, valueLen, err := x.valueLen(tp, end)
+		if err != nil {
+			return errors.WithMessagef(err, "element at %d", start)
+		}
+		switch {
+		case wanted:
+			err = x.readValue(tp, prefix, valueLen, next, depth)
+		case deeper && (tp == BSONTypeObject || tp == BSONTypeArray):
+			err = x.container(valueLen, next, depth+1)
+		default:
+			var skipped int64
+			skipped, err = io.CopyN(io.Discard, x.r, int64(valueLen-len(prefix)))
+			x.offset += int(skipped)
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// valueLen reads the length prefix of the value of the type if it has one, and returns the prefix and the length
+// of the value, which must end before end.
+func (x *readerExtractor) valueLen(tp Type, end int) (prefix []byte, n int, err error) {
+	switch tp {
+	case BSONTypeRegex:
+		// the regex is buffered as a prefix, which is the pattern and the options
+		if x.key, err = x.readCString(x.key[:0], end); err != nil {
+			return nil, 0, err
+		}
+		x.key = append(x.key, 0)
+		if x.key, err = x.readCString(x.key, end); err != nil {
+			return nil, 0, err
+		}
+		prefix = append(x.key, 0)
+		n = len(prefix)
+	case BSONTypeString, BSONTypeJavaScript, BSONTypeSymbol, BSONTypeObject, BSONTypeArray,
+		BSONTypeBinary, BSONTypeDBPointer, BSONTypeJavaScriptWithScope:
+		if err = x.readFull(x.prefix[:]); err != nil {
+			return nil, 0, err
+		}
+		prefix = x.prefix[:]
+		n = declaredValueLen(tp, prefix)
+	default:
+		if n = declaredValueLen(tp, nil); n < 0 {
+			return nil, 0, errors.Wrapf(ErrUnsupportedType, "type %v", tp)
+		}
+	}
+	if n < len(prefix) || x.offset-len(prefix)+n >= end {
+		return nil, 0, errors.Wrapf(ErrInvalidLength, "%v value of %d bytes exceeds the document", tp, n)
+	}
+	return prefix, n, nil
+}
+
+// readValue reads the value of the type into a result of the nodes, and matches the deeper nodes in it.
+func (x *readerExtractor) readValue(tp Type, prefix []byte, n int, nodes []*matchNode, depth int) error {
+	raw, err := x.readGrowing(append(make([]byte, 0, extractInitialSize), prefix...), n)
+	if err != nil {
+		return err
+	}
+	value := Result{Type: tp, Raw: raw}
+	deeper := false
+	for _, n := range nodes {
+		for _, id := range n.ids {
+			if !x.results[id].Exist() {
+				x.results[id] = value
+			}
+		}
+		deeper = deeper || n.children != nil || n.wildcard != nil
+	}
+	if !deeper || !value.IsContainer() {
+		return nil
+	}
+	m := Matcher{}
+	_, err = m.match(value, nodes, depth+1, func(id int, r Result) bool {
+		if !x.results[id].Exist() {
+			x.results[id] = r
+		}
+		return true
+	})
+	return err
+}
+
+// extractInitialSize is the initial capacity of the buffers of values, which grow as the bytes arrive.
+const extractInitialSize = 4 << 10
+
+// readGrowing reads the rest of the value of n bytes into raw, see the package level readGrowing.
+func (x *readerExtractor) readGrowing(raw []byte, n int) ([]byte, error) {
+	start := len(raw)
+	raw, err := readGrowing(x.r, raw, n)
+	x.offset += len(raw) - start
+	if err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// readCString appends the zero terminated string before end to dst without the zero byte.
+func (x *readerExtractor) readCString(dst []byte, end int) ([]byte, error) {
+	for x.offset < end {
+		b, err := x.readByte()
+		if err != nil {
+			return dst, err
+		}
+		if b == 0 {
+			return dst, nil
+		}
+		dst = append(dst, b)
+	}
+	return dst, errors.Wrapf(ErrInvalidLength, "key at %d is not terminated", x.offset)
+}
+
+func (x *readerExtractor) readByte() (byte, error) {
+	if x.byteReader != nil {
+		b, err := x.byteReader.ReadByte()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil {
+			x.offset++
+		}
+		return b, err
+	}
+	err := x.readFull(x.prefix[:1])
+	return x.prefix[0], err
+}
+
+func (x *readerExtractor) readFull(b []byte) error {
+	n, err := io.ReadFull(x.r, b)
+	x.offset += n
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
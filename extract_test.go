@@ -0,0 +1,83 @@
+package gbson
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestExtractFromReader(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	paths := []string{"int32", "doc.b.1", "doc.b.*", "missing", "doc", "doc.a", "regex", "string", "empty.0", "int32.x", "*.a"}
+	expected := []Result{
+		Get(doc, "int32"), Get(doc, "doc", "b", "1"), Get(doc, "doc", "b", "0"), Get(doc, "missing"), Get(doc, "doc"),
+		Get(doc, "doc", "a"), Get(doc, "regex"), Get(doc, "string"), Get(doc, "empty", "0"), Get(doc, "int32", "x"),
+		Get(doc, "doc", "a"),
+	}
+	for _, r := range []io.Reader{bytes.NewReader(doc), iotest.OneByteReader(bytes.NewReader(doc))} {
+		results, err := ExtractFromReader(r, paths...)
+		require.NoError(t, err)
+		require.Len(t, results, len(paths))
+		for i, path := range paths {
+			require.Equal(t, expected[i].Type, results[i].Type, path)
+			require.Equal(t, string(expected[i].Raw), string(results[i].Raw), path)
+		}
+	}
+
+	// a stream of documents is read one by one
+	docs, stream := getTestStream(t)
+	r := bytes.NewReader(stream)
+	for _, doc := range docs {
+		results, err := ExtractFromReader(r, "a")
+		require.NoError(t, err)
+		require.Equal(t, Get(doc, "a").Raw, results[0].Raw)
+	}
+	_, err := ExtractFromReader(r, "a")
+	require.Equal(t, io.EOF, err)
+
+	_, err = ExtractFromReader(bytes.NewReader(doc[:len(doc)-1]), "max")
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+	_, err = ExtractFromReader(bytes.NewReader([]byte{4, 0, 0, 0}), "a")
+	require.ErrorIs(t, err, ErrInvalidLength)
+	short := append([]byte{}, doc...)
+	short[0]-- // the terminator is read as the type of another element
+	_, err = ExtractFromReader(bytes.NewReader(short), "a")
+	require.Error(t, err)
+	deep := nestedDocument(DefaultMaxDepth + 2)
+	path := strings.TrimSuffix(strings.Repeat("a.0.", DefaultMaxDepth/2), ".")
+	_, err = ExtractFromReader(bytes.NewReader(deep), path)
+	require.NoError(t, err, "only the paths are traversed")
+	_, err = ExtractFromReader(bytes.NewReader(deep), path+".a")
+	require.ErrorIs(t, err, ErrMaxDepth)
+}
+
+func TestExtractFromReaderSkips(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "blob", Value: primitive.Binary{Data: make([]byte, 4<<20)}},
+		{Key: "a", Value: bson.D{{Key: "large", Value: make([]byte, 4<<20)}, {Key: "b", Value: "x"}}},
+		{Key: "c", Value: int32(1)},
+	})
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	results, err := ExtractFromReader(bytes.NewReader(doc), "a.b", "c")
+	runtime.ReadMemStats(&after)
+	require.NoError(t, err)
+	require.Equal(t, "x", results[0].Str())
+	require.Equal(t, int32(1), results[1].Int32())
+	require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+
+	// a truncated stream declaring a 1 GiB string doesn't allocate it
+	overDeclared := []byte{0xff, 0xff, 0xff, 0x7f, byte(BSONTypeString), 'a', 0, 0, 0, 0, 0x40, 'x'}
+	runtime.ReadMemStats(&before)
+	_, err = ExtractFromReader(bytes.NewReader(overDeclared), "a")
+	runtime.ReadMemStats(&after)
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+	require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+}
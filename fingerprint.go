@@ -0,0 +1,78 @@
+package gbson
+
+// Fingerprints is a compact table of the top level elements of a wide document, with an 8-bit fingerprint
+// of the key and the offset of each element, costing 5 bytes per element. Lookups compare the fingerprints
+// and only decode the elements whose fingerprints match, which skips most of the keys of documents with
+// hundreds of them. It's cheaper to build than IndexedDocument, and refers to the document without copying,
+// which must not be modified while used.
+type Fingerprints struct {
+	doc     []byte
+	prints  []byte
+	offsets []uint32 // offsets of the elements in the document
+}
+
+// NewFingerprints builds the fingerprints of the top level keys of the document in one pass.
+// The error is the one of scanning a malformed document, see Result.Err.
+func NewFingerprints(doc []byte) (*Fingerprints, error) {
+	elements, ok := containerElements(doc)
+	if !ok {
+		return nil, locateError(doc, ErrInvalidLength)
+	}
+	f := &Fingerprints{
+		doc:     doc,
+		prints:  make([]byte, 0, fingerprintsInitialSize),
+		offsets: make([]uint32, 0, fingerprintsInitialSize),
+	}
+	for pos := 4; len(elements) > 0; {
+		_, name, _, n := consumeElement(elements)
+		if n < 0 {
+			return nil, locateError(doc, ErrInvalidLength)
+		}
+		f.prints = append(f.prints, fingerprint(name))
+		f.offsets = append(f.offsets, uint32(pos))
+		elements, pos = elements[n:], pos+n
+	}
+	return f, nil
+}
+
+// fingerprintsInitialSize is the initial capacity of the tables, which grow with the elements scanned
+// rather than by an estimate from the document length, which a large value inflates.
+const fingerprintsInitialSize = 64
+
+// fingerprint returns the 8-bit fingerprint of the key, which is FNV-1a folded into a byte.
+func fingerprint[S string | []byte](key S) byte {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h = (h ^ uint32(key[i])) * 16777619
+	}
+	return byte(h ^ h>>8 ^ h>>16 ^ h>>24)
+}
+
+// Get gets the first value by the given path like Get, using the fingerprints for the top level key.
+func (f *Fingerprints) Get(path ...string) Result {
+	if len(path) == 0 {
+		return resultFromBytes(f.doc)
+	}
+	key := path[0]
+	fp := fingerprint(key)
+	for i, p := range f.prints {
+		if p != fp {
+			continue
+		}
+		tp, name, value, _ := consumeElement(f.doc[f.offsets[i]:])
+		if !bytesEqualToString(name, key) {
+			continue
+		}
+		r := Result{Type: tp, Raw: value}
+		if len(path) == 1 {
+			return r
+		}
+		return r.Get(path[1:]...)
+	}
+	return Result{Type: BSONTypeUndefined}
+}
+
+// Len returns the number of the top level elements.
+func (f *Fingerprints) Len() int {
+	return len(f.prints)
+}
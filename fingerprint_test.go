@@ -0,0 +1,81 @@
+package gbson
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func getWideDocument(t testing.TB, n int) []byte {
+	d := make(bson.D, n)
+	for i := range d {
+		d[i] = bson.E{Key: fmt.Sprintf("field_%d", i), Value: bson.D{{Key: "v", Value: int32(i)}}}
+	}
+	return mustMarshal(t, d)
+}
+
+func TestFingerprints(t *testing.T) {
+	doc := getWideDocument(t, 500)
+	f, err := NewFingerprints(doc)
+	require.NoError(t, err)
+	require.Equal(t, 500, f.Len())
+	for i := 0; i < 500; i++ {
+		key := fmt.Sprintf("field_%d", i)
+		require.Equal(t, Get(doc, key), f.Get(key), key)
+		require.Equal(t, int32(i), f.Get(key, "v").Int32(), key)
+	}
+	require.Equal(t, Get(doc), f.Get())
+	require.False(t, f.Get("missing").Exist())
+	require.False(t, f.Get("field_1", "missing").Exist())
+
+	dup := mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}, {Key: "a", Value: int32(2)}})
+	f, err = NewFingerprints(dup)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), f.Get("a").Int32())
+
+	// the tables are of the elements, not inflated by a large value
+	large := mustMarshal(t, bson.D{{Key: "n", Value: nil}, {Key: "blob", Value: make([]byte, 8<<20)}})
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	f, err = NewFingerprints(large)
+	runtime.ReadMemStats(&after)
+	require.NoError(t, err)
+	require.Equal(t, 2, f.Len())
+	require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(4<<10))
+
+	_, err = NewFingerprints(doc[:len(doc)-1])
+	require.ErrorIs(t, err, ErrInvalidLength)
+	broken := append([]byte{}, dup...)
+	broken[11] = 0x7f // type of the second element
+	_, err = NewFingerprints(broken)
+	require.ErrorIs(t, err, ErrInvalidLength)
+}
+
+func BenchmarkFingerprints(b *testing.B) {
+	doc := getWideDocument(b, 500)
+	b.Run("gbson get last", func(b *testing.B) {
+		// Gets the last one of 500 first level fields using gbson.Get
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Get(doc, "field_499")
+		}
+	})
+	b.Run("gbson fingerprints get last", func(b *testing.B) {
+		// Gets the last one of 500 first level fields using Fingerprints.Get, built once
+		f, _ := NewFingerprints(doc)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			f.Get("field_499")
+		}
+	})
+	b.Run("gbson fingerprints build", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = NewFingerprints(doc)
+		}
+	})
+}
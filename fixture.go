@@ -0,0 +1,92 @@
+package gbson
+
+import (
+	"strconv"
+)
+
+// goLiteralBytesPerLine is the number of bytes per line of long values.
+const goLiteralBytesPerLine = 16
+
+// goLiteralPreviewLen is the max length of the values in comments.
+const goLiteralPreviewLen = 40
+
+// ToGoLiteral renders the bson document as a Go byte slice literal, so captured payloads could be checked in
+// as readable test fixtures. Every element is on its own lines commented with its key, type and value,
+// and embedded documents and arrays are indented one more level, e.g.
+//
+//	[]byte{
+//		0x0e, 0x00, 0x00, 0x00, // document of 14 bytes
+//		0x10, 0x61, 0x00, // "a": int
+//		0x01, 0x00, 0x00, 0x00, // 1
+//		0x00, // end of document
+//	}
+func ToGoLiteral(doc []byte) ([]byte, error) {
+	dst, err := appendGoLiteralDocument([]byte("[]byte{\n"), Result{Type: BSONTypeObject, Raw: doc}, 1, "document")
+	if err != nil {
+		return nil, locateError(doc, err)
+	}
+	return append(dst, '}'), nil
+}
+
+// appendGoLiteralDocument appends the document or array at the depth, which is its indentation as well.
+func appendGoLiteralDocument(dst []byte, r Result, depth int, name string) ([]byte, error) {
+	if err := checkDepth(depth); err != nil {
+		return dst, err
+	}
+	n := int(consumeInt32(r.Raw))
+	if n < 5 || n > len(r.Raw) {
+		return dst, ErrInvalidLength
+	}
+	dst = appendGoLiteralLine(dst, depth, r.Raw[:4], name+" of "+strconv.Itoa(n)+" bytes")
+	var err error
+	_, iterErr := r.iterFields(func(key []byte, value Result) bool {
+		header := append(append([]byte{byte(value.Type)}, key...), 0)
+		keyComment := strconv.Quote(string(key)) + ": " + typeNames[value.Type]
+		dst = appendGoLiteralLine(dst, depth, header, keyComment)
+		if value.IsContainer() {
+			dst, err = appendGoLiteralDocument(dst, value, depth+1, typeNames[value.Type])
+			return err == nil
+		}
+		dst = appendGoLiteralValue(dst, depth, value)
+		return true
+	})
+	if err != nil {
+		return dst, err
+	}
+	if iterErr != nil {
+		return dst, iterErr
+	}
+	return appendGoLiteralLine(dst, depth, r.Raw[n-1:n], "end of "+name), nil
+}
+
+// appendGoLiteralValue appends the bytes of a value other than documents and arrays,
+// goLiteralBytesPerLine bytes per line, the first line is commented with a preview of the value.
+func appendGoLiteralValue(dst []byte, depth int, r Result) []byte {
+	preview := r.preview(goLiteralPreviewLen)
+	for i := 0; i < len(r.Raw); i += goLiteralBytesPerLine {
+		end := i + goLiteralBytesPerLine
+		if end > len(r.Raw) {
+			end = len(r.Raw)
+		}
+		dst = appendGoLiteralLine(dst, depth, r.Raw[i:end], preview)
+		preview = ""
+	}
+	return dst
+}
+
+// appendGoLiteralLine appends a line of the bytes in hex with an optional comment.
+func appendGoLiteralLine(dst []byte, depth int, bs []byte, comment string) []byte {
+	for i := 0; i < depth; i++ {
+		dst = append(dst, '\t')
+	}
+	for i, b := range bs {
+		if i > 0 {
+			dst = append(dst, ' ')
+		}
+		dst = append(dst, '0', 'x', hexDigits[b>>4], hexDigits[b&0xF], ',')
+	}
+	if comment != "" {
+		dst = append(append(dst, " // "...), comment...)
+	}
+	return append(dst, '\n')
+}
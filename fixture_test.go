@@ -0,0 +1,44 @@
+package gbson
+
+import (
+	"go/ast"
+	"go/parser"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestToGoLiteral(t *testing.T) {
+	out, err := ToGoLiteral(mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}}))
+	require.NoError(t, err)
+	require.Equal(t, `[]byte{
+	0x0c, 0x00, 0x00, 0x00, // document of 12 bytes
+	0x10, 0x61, 0x00, // "a": int
+	0x01, 0x00, 0x00, 0x00, // 1
+	0x00, // end of document
+}`, string(out))
+
+	doc := getTestJSONDocument(t)
+	out, err = ToGoLiteral(doc)
+	require.NoError(t, err)
+	require.Contains(t, string(out), "\t\t0x10, 0x61, 0x00, // \"a\": int\n")
+	require.Contains(t, string(out), `// "quote\" slash\\ tab\t ctrl\x01 <html> ...`+"\n")
+	expr, err := parser.ParseExpr(string(out))
+	require.NoError(t, err)
+	var parsed []byte
+	for _, elt := range expr.(*ast.CompositeLit).Elts {
+		b, err := strconv.ParseUint(elt.(*ast.BasicLit).Value, 0, 8)
+		require.NoError(t, err)
+		parsed = append(parsed, byte(b))
+	}
+	require.Equal(t, doc, parsed)
+	for _, line := range strings.Split(string(out), "\n") {
+		require.LessOrEqual(t, strings.Count(line, "0x"), goLiteralBytesPerLine, line)
+	}
+
+	_, err = ToGoLiteral(doc[:len(doc)-1])
+	require.ErrorIs(t, err, ErrInvalidLength)
+}
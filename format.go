@@ -0,0 +1,141 @@
+package gbson
+
+import (
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Str renders the value as text like gjson's String does, for logging and templating:
+// string types as their text, numbers in decimal, booleans as "true" or "false",
+// datetimes in RFC 3339 of UTC, ObjectIDs in hex, UUIDs in the canonical form, the other binaries
+// in base64, regexes as "/pattern/options", timestamps as "Timestamp(t, i)" and documents and arrays
+// in relaxed Extended JSON. It returns "" for the other types, missing results and malformed documents.
+func (r Result) Str() string {
+	switch r.Type {
+	case BSONTypeString, BSONTypeSymbol, BSONTypeJavaScript:
+		return r.String()
+	case BSONTypeObject, BSONTypeArray:
+		bs, err := r.JSON()
+		if err != nil {
+			return ""
+		}
+		return string(bs)
+	}
+	var buf [64]byte
+	return string(appendScalarString(buf[:0], r))
+}
+
+// AppendStringValue appends the text of the value rendered by Str to dst and returns the extended buffer,
+// so extraction loops could reuse a buffer instead of allocating a string per value. Only decimals, documents
+// and arrays still allocate while rendering. Nothing is appended where Str returns "".
+func AppendStringValue(dst []byte, r Result) []byte {
+	if r.IsContainer() {
+		if out, err := r.AppendJSON(dst); err == nil {
+			return out
+		}
+		return dst
+	}
+	return appendScalarString(dst, r)
+}
+
+// appendScalarString is AppendStringValue of the values other than documents and arrays, which doesn't leak
+// dst so Str could render into a buffer on the stack.
+func appendScalarString(dst []byte, r Result) []byte {
+	switch r.Type {
+	case BSONTypeString, BSONTypeSymbol, BSONTypeJavaScript:
+		return append(dst, r.StringBytes()...)
+	case BSONTypeDouble:
+		return appendFloat(dst, r.Float64())
+	case BSONTypeInt32, BSONTypeInt64:
+		return strconv.AppendInt(dst, r.Int64(), 10)
+	case BSONTypeDecimal128:
+		return append(dst, r.Decimal128().String()...)
+	case BSONTypeBoolean:
+		return strconv.AppendBool(dst, r.Bool())
+	case BSONTypeDateTime:
+		return r.TimeIn(time.UTC).AppendFormat(dst, time.RFC3339Nano)
+	case BSONTypeTimestamp:
+		t, i := r.Timestamp()
+		dst = append(dst, "Timestamp("...)
+		dst = strconv.AppendUint(dst, uint64(t), 10)
+		dst = append(dst, ", "...)
+		dst = strconv.AppendUint(dst, uint64(i), 10)
+		return append(dst, ')')
+	case BSONTypeObjectID:
+		if len(r.Raw) >= 12 {
+			return appendHex(dst, r.Raw[:12])
+		}
+	case BSONTypeBinary:
+		if uuid, ok := r.UUID(); ok {
+			return appendUUID(dst, uuid)
+		}
+		_, data := r.Binary()
+		return appendBase64(dst, data)
+	case BSONTypeRegex:
+		pattern, options := r.regexBytes()
+		dst = append(append(dst, '/'), pattern...)
+		return append(append(dst, '/'), options...)
+	}
+	return dst
+}
+
+// Describe returns the type, the length in bytes and a short preview of the value for debugging,
+// e.g. `string(8 bytes) "abc"` and `object(12 bytes) {"a":1}`.
+func (r Result) Describe() string {
+	s := r.Type.String() + "(" + strconv.Itoa(len(r.Raw)) + " bytes)"
+	if preview := r.preview(describePreviewLen); preview != "" {
+		s += " " + preview
+	}
+	return s
+}
+
+// GoString implements fmt.GoStringer, so %#v prints the description instead of the raw bytes.
+func (r Result) GoString() string {
+	return "gbson.Result{" + r.Describe() + "}"
+}
+
+// describePreviewLen is the max length of the preview of Describe.
+const describePreviewLen = 64
+
+// preview returns the value by Str, or relaxed Extended JSON if Str is empty, quoted if it's a string or
+// not printable, and truncated at a rune boundary to max bytes followed by "...".
+func (r Result) preview(max int) string {
+	preview := r.Str()
+	if preview == "" && !r.IsString() && len(r.Raw) > 0 {
+		if js, err := r.JSON(); err == nil {
+			preview = string(js)
+		}
+	}
+	if r.IsString() || !strconv.CanBackquote(preview) {
+		preview = strconv.Quote(preview)
+	}
+	if len(preview) > max {
+		n := max
+		for n > 0 && !utf8.RuneStart(preview[n]) {
+			n--
+		}
+		preview = preview[:n] + "..."
+	}
+	return preview
+}
+
+// appendFloat appends the shortest decimal representation of the float,
+// in exponent form for very large or small magnitudes the same as encoding/json.
+func appendFloat(dst []byte, f float64) []byte {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		switch {
+		case math.IsNaN(f):
+			return append(dst, "NaN"...)
+		case f > 0:
+			return append(dst, "Infinity"...)
+		}
+		return append(dst, "-Infinity"...)
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	return strconv.AppendFloat(dst, f, format, -1, 64)
+}
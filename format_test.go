@@ -0,0 +1,106 @@
+package gbson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStr(t *testing.T) {
+	oid := primitive.NewObjectID()
+	dec, _ := primitive.ParseDecimal128("1.50")
+	doc := mustMarshal(t, bson.D{
+		{Key: "string", Value: "text"},
+		{Key: "double", Value: 1.25},
+		{Key: "big", Value: 1e22},
+		{Key: "int", Value: int32(-3)},
+		{Key: "long", Value: int64(1) << 40},
+		{Key: "decimal", Value: dec},
+		{Key: "bool", Value: true},
+		{Key: "date", Value: time.Date(2022, 11, 10, 1, 2, 3, 4e6, time.UTC)},
+		{Key: "ts", Value: primitive.Timestamp{T: 5, I: 6}},
+		{Key: "_id", Value: oid},
+		{Key: "binary", Value: []byte("hi")},
+		{Key: "regex", Value: primitive.Regex{Pattern: "^a", Options: "i"}},
+		{Key: "null", Value: nil},
+		{Key: "doc", Value: bson.D{{Key: "a", Value: int32(1)}}},
+		{Key: "array", Value: bson.A{"x", 1.0}},
+	})
+	for key, expected := range map[string]string{
+		"string":  "text",
+		"double":  "1.25",
+		"big":     "1e+22",
+		"int":     "-3",
+		"long":    "1099511627776",
+		"decimal": "1.50",
+		"bool":    "true",
+		"date":    "2022-11-10T01:02:03.004Z",
+		"ts":      "Timestamp(5, 6)",
+		"_id":     oid.Hex(),
+		"binary":  "aGk=",
+		"regex":   "/^a/i",
+		"doc":     `{"a":1}`,
+		"array":   `["x",1.0]`,
+		"null":    "",
+		"missing": "",
+	} {
+		require.Equal(t, expected, Get(doc, key).Str(), key)
+	}
+}
+
+func TestAppendStringValue(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	buf := []byte("prefix")
+	err := Get(doc).IterDocumentE(func(key string, value Result) bool {
+		out := AppendStringValue(buf, value)
+		require.Equal(t, "prefix"+value.Str(), string(out), key)
+		js, err := value.JSON()
+		require.NoError(t, err)
+		out, err = value.AppendJSON(buf)
+		require.NoError(t, err)
+		require.Equal(t, "prefix"+string(js), string(out), key)
+		return true
+	})
+	require.NoError(t, err)
+	uuid := Get(mustMarshal(t, bson.D{{Key: "uuid", Value: primitive.Binary{Subtype: 4, Data: make([]byte, 16)}}}), "uuid")
+	require.Equal(t, "00000000-0000-0000-0000-000000000000", string(AppendStringValue(nil, uuid)))
+	require.Equal(t, uuid.Str(), uuid.UUIDString())
+	out, err := Get(doc, "missing").AppendJSON(buf)
+	require.NoError(t, err)
+	require.Equal(t, "prefixnull", string(out))
+	require.Equal(t, "prefix", string(AppendStringValue(buf, Get(doc, "missing"))))
+
+	buf = make([]byte, 0, 64)
+	for _, key := range []string{"double", "int64", "date", "ts", "_id", "binary", "regex", "string"} {
+		value := Get(doc, key)
+		require.Zero(t, testing.AllocsPerRun(100, func() {
+			buf = AppendStringValue(buf[:0], value)
+		}), key)
+	}
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		buf = AppendStringValue(buf[:0], uuid)
+	}))
+}
+
+func TestDescribe(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "s", Value: "abc"},
+		{Key: "empty", Value: ""},
+		{Key: "doc", Value: bson.D{{Key: "a", Value: int32(1)}}},
+		{Key: "long", Value: strings.Repeat("中", 30)},
+		{Key: "null", Value: nil},
+		{Key: "n", Value: int64(7)},
+	})
+	require.Equal(t, `string(8 bytes) "abc"`, Get(doc, "s").Describe())
+	require.Equal(t, `string(5 bytes) ""`, Get(doc, "empty").Describe())
+	require.Equal(t, `object(12 bytes) {"a":1}`, Get(doc, "doc").Describe())
+	require.Equal(t, `string(95 bytes) "`+strings.Repeat("中", 21)+`...`, Get(doc, "long").Describe())
+	require.Equal(t, "null(0 bytes)", Get(doc, "null").Describe())
+	require.Equal(t, "undefined(0 bytes)", Get(doc, "missing").Describe())
+	require.Equal(t, "gbson.Result{long(8 bytes) 7}", fmt.Sprintf("%#v", Get(doc, "n")))
+}
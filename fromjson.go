@@ -0,0 +1,678 @@
+package gbson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FromJSON parses MongoDB Extended JSON, either canonical or relaxed, into a bson document.
+// Plain JSON is accepted as well: integers are parsed as int32, or int64 if they don't fit,
+// and the other numbers as doubles. An object is taken as a type wrapper like {"$oid": "..."}
+// if its first key is one of the Extended JSON keys.
+func FromJSON(data []byte) ([]byte, error) {
+	return appendFromJSON(nil, data)
+}
+
+func appendFromJSON(dst []byte, data []byte) ([]byte, error) {
+	p := jsonParser{data: data}
+	p.skipSpace()
+	if p.peek() != '{' {
+		return dst, ErrNotObject
+	}
+	out, tp, err := p.parseValue(dst)
+	if err != nil {
+		return dst, err
+	}
+	if tp != BSONTypeObject {
+		return dst, ErrNotObject
+	}
+	p.skipSpace()
+	if p.pos != len(p.data) {
+		return dst, p.errorf("unexpected data after the document")
+	}
+	return out, nil
+}
+
+type jsonParser struct {
+	data  []byte
+	pos   int
+	depth int // depth of the object or array being parsed
+}
+
+type jsonMember struct {
+	key string
+	raw []byte
+}
+
+func (p *jsonParser) errorf(format string, args ...interface{}) error {
+	return errors.Wrapf(ErrInvalidJSON, "offset %d: "+format, append([]interface{}{p.pos}, args...)...)
+}
+
+// enter enters an object or array, the returned function leaves it.
+func (p *jsonParser) enter() (leave func(), err error) {
+	p.depth++
+	if err = checkDepth(p.depth); err != nil {
+		p.depth--
+		return nil, errors.WithMessagef(err, "offset %d", p.pos)
+	}
+	return func() { p.depth-- }, nil
+}
+
+func (p *jsonParser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next byte, 0 at the end of data.
+func (p *jsonParser) peek() byte {
+	if p.pos < len(p.data) {
+		return p.data[p.pos]
+	}
+	return 0
+}
+
+func (p *jsonParser) expect(c byte) error {
+	p.skipSpace()
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *jsonParser) parseValue(dst []byte) ([]byte, Type, error) {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == '{':
+		return p.parseObject(dst)
+	case c == '[':
+		return p.parseArray(dst)
+	case c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		return appendString(dst, s), BSONTypeString, nil
+	case c == 't':
+		if err := p.parseLiteral("true"); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		return append(dst, 1), BSONTypeBoolean, nil
+	case c == 'f':
+		if err := p.parseLiteral("false"); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		return append(dst, 0), BSONTypeBoolean, nil
+	case c == 'n':
+		if err := p.parseLiteral("null"); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		return dst, BSONTypeNull, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumber(dst)
+	}
+	return dst, BSONTypeUndefined, p.errorf("unexpected character")
+}
+
+func (p *jsonParser) parseLiteral(literal string) error {
+	if !bytes.HasPrefix(p.data[p.pos:], []byte(literal)) {
+		return p.errorf("invalid literal")
+	}
+	p.pos += len(literal)
+	return nil
+}
+
+func (p *jsonParser) parseObject(dst []byte) ([]byte, Type, error) {
+	start := p.pos
+	p.pos++ // '{'
+	p.skipSpace()
+	if p.peek() == '"' {
+		key, err := p.parseString()
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		p.pos = start
+		if extJSONKeys[key] {
+			return p.parseWrapper(dst)
+		}
+		p.pos++
+	}
+	leave, err := p.enter()
+	if err != nil {
+		return dst, BSONTypeUndefined, err
+	}
+	defer leave()
+	dst, docStart := beginDocument(dst)
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		return endDocument(dst, docStart), BSONTypeObject, nil
+	}
+	for {
+		p.skipSpace()
+		key, err := p.parseString()
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		if err = p.expect(':'); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		pos := len(dst)
+		if dst, err = appendElementHeader(dst, BSONTypeNull, key); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		var tp Type
+		if dst, tp, err = p.parseValue(dst); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		dst[pos] = byte(tp)
+		if done, err := p.parseSeparator('}'); err != nil || done {
+			return endDocument(dst, docStart), BSONTypeObject, err
+		}
+	}
+}
+
+func (p *jsonParser) parseArray(dst []byte) ([]byte, Type, error) {
+	leave, err := p.enter()
+	if err != nil {
+		return dst, BSONTypeUndefined, err
+	}
+	defer leave()
+	p.pos++ // '['
+	dst, docStart := beginDocument(dst)
+	p.skipSpace()
+	if p.peek() == ']' {
+		p.pos++
+		return endDocument(dst, docStart), BSONTypeArray, nil
+	}
+	for i := 0; ; i++ {
+		pos := len(dst)
+		dst = appendIndexHeader(dst, BSONTypeNull, i)
+		var tp Type
+		var err error
+		if dst, tp, err = p.parseValue(dst); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		dst[pos] = byte(tp)
+		if done, err := p.parseSeparator(']'); err != nil || done {
+			return endDocument(dst, docStart), BSONTypeArray, err
+		}
+	}
+}
+
+// parseSeparator consumes a comma or the closing character, done is true for the latter.
+func (p *jsonParser) parseSeparator(end byte) (done bool, err error) {
+	p.skipSpace()
+	switch p.peek() {
+	case ',':
+		p.pos++
+		return false, nil
+	case end:
+		p.pos++
+		return true, nil
+	}
+	return false, p.errorf("expected ',' or %q", end)
+}
+
+func (p *jsonParser) parseString() (string, error) {
+	if p.peek() != '"' {
+		return "", p.errorf("expected string")
+	}
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == '"' {
+			p.pos++
+			return string(p.data[start : p.pos-1]), nil
+		}
+		if c == '\\' {
+			break
+		}
+		if c < 0x20 {
+			return "", p.errorf("control character in string")
+		}
+		p.pos++
+	}
+	buf := append([]byte(nil), p.data[start:p.pos]...)
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch {
+		case c == '"':
+			return string(buf), nil
+		case c < 0x20:
+			return "", p.errorf("control character in string")
+		case c != '\\':
+			buf = append(buf, c)
+			continue
+		}
+		switch p.peek() {
+		case '"', '\\', '/':
+			buf = append(buf, p.data[p.pos])
+		case 'b':
+			buf = append(buf, '\b')
+		case 'f':
+			buf = append(buf, '\f')
+		case 'n':
+			buf = append(buf, '\n')
+		case 'r':
+			buf = append(buf, '\r')
+		case 't':
+			buf = append(buf, '\t')
+		case 'u':
+			r, ok := p.parseHex4(p.pos + 1)
+			if !ok {
+				return "", p.errorf("invalid unicode escape")
+			}
+			p.pos += 4
+			if utf16.IsSurrogate(r) {
+				r = utf8.RuneError
+				if p.pos+2 < len(p.data) && p.data[p.pos+1] == '\\' && p.data[p.pos+2] == 'u' {
+					if r2, ok := p.parseHex4(p.pos + 3); ok {
+						if r = utf16.DecodeRune(r, r2); r != utf8.RuneError {
+							p.pos += 6
+						}
+					}
+				}
+			}
+			buf = utf8.AppendRune(buf, r)
+		default:
+			return "", p.errorf("invalid escape")
+		}
+		p.pos++
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *jsonParser) parseHex4(pos int) (rune, bool) {
+	if pos+4 > len(p.data) {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(string(p.data[pos:pos+4]), 16, 16)
+	return rune(v), err == nil
+}
+
+// scanNumber consumes a number token, integer is true if it has no fraction or exponent.
+func (p *jsonParser) scanNumber() (token string, integer bool, err error) {
+	start := p.pos
+	integer = true
+	if p.peek() == '-' {
+		p.pos++
+	}
+	digits := p.scanDigits()
+	if digits == 0 || (digits > 1 && p.data[p.pos-digits] == '0') {
+		return "", false, p.errorf("invalid number")
+	}
+	if p.peek() == '.' {
+		p.pos++
+		integer = false
+		if p.scanDigits() == 0 {
+			return "", false, p.errorf("invalid number")
+		}
+	}
+	if c := p.peek(); c == 'e' || c == 'E' {
+		p.pos++
+		integer = false
+		if c := p.peek(); c == '+' || c == '-' {
+			p.pos++
+		}
+		if p.scanDigits() == 0 {
+			return "", false, p.errorf("invalid number")
+		}
+	}
+	return string(p.data[start:p.pos]), integer, nil
+}
+
+func (p *jsonParser) scanDigits() int {
+	start := p.pos
+	for p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+	return p.pos - start
+}
+
+func (p *jsonParser) parseNumber(dst []byte) ([]byte, Type, error) {
+	token, integer, err := p.scanNumber()
+	if err != nil {
+		return dst, BSONTypeUndefined, err
+	}
+	if integer {
+		if v, err := strconv.ParseInt(token, 10, 64); err == nil {
+			if v >= math.MinInt32 && v <= math.MaxInt32 {
+				return appendInt32(dst, int32(v)), BSONTypeInt32, nil
+			}
+			return appendInt64(dst, v), BSONTypeInt64, nil
+		}
+	}
+	f, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return dst, BSONTypeUndefined, p.errorf("number %s out of range", token)
+	}
+	return appendDouble(dst, f), BSONTypeDouble, nil
+}
+
+// skipValue consumes a value of any kind, only checking the syntax.
+func (p *jsonParser) skipValue() error {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == '{' || c == '[':
+		end := byte('}')
+		if c == '[' {
+			end = ']'
+		}
+		leave, err := p.enter()
+		if err != nil {
+			return err
+		}
+		defer leave()
+		p.pos++
+		p.skipSpace()
+		if p.peek() == end {
+			p.pos++
+			return nil
+		}
+		for {
+			if c == '{' {
+				p.skipSpace()
+				if _, err := p.parseString(); err != nil {
+					return err
+				}
+				if err := p.expect(':'); err != nil {
+					return err
+				}
+			}
+			if err := p.skipValue(); err != nil {
+				return err
+			}
+			if done, err := p.parseSeparator(end); err != nil || done {
+				return err
+			}
+		}
+	case c == '"':
+		_, err := p.parseString()
+		return err
+	case c == 't':
+		return p.parseLiteral("true")
+	case c == 'f':
+		return p.parseLiteral("false")
+	case c == 'n':
+		return p.parseLiteral("null")
+	case c == '-' || (c >= '0' && c <= '9'):
+		_, _, err := p.scanNumber()
+		return err
+	}
+	return p.errorf("unexpected character")
+}
+
+// parseMembers consumes an object and returns the raw values of its members.
+func (p *jsonParser) parseMembers() ([]jsonMember, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		return nil, nil
+	}
+	var members []jsonMember
+	for {
+		p.skipSpace()
+		key, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(':'); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		start := p.pos
+		if err = p.skipValue(); err != nil {
+			return nil, err
+		}
+		members = append(members, jsonMember{key: key, raw: p.data[start:p.pos]})
+		if done, err := p.parseSeparator('}'); err != nil || done {
+			return members, err
+		}
+	}
+}
+
+// extJSONKeys are the keys identifying Extended JSON type wrappers.
+var extJSONKeys = map[string]bool{
+	"$oid": true, "$symbol": true, "$numberInt": true, "$numberLong": true, "$numberDouble": true,
+	"$numberDecimal": true, "$binary": true, "$uuid": true, "$code": true, "$scope": true, "$timestamp": true,
+	"$regularExpression": true, "$dbPointer": true, "$date": true, "$minKey": true, "$maxKey": true,
+	"$undefined": true,
+}
+
+// extJSONObject is the members of an object by key.
+type extJSONObject map[string][]byte
+
+func parseExtJSONObject(raw []byte) (extJSONObject, bool) {
+	p := jsonParser{data: raw}
+	members, err := p.parseMembers()
+	if err != nil {
+		return nil, false
+	}
+	obj := make(extJSONObject, len(members))
+	for _, m := range members {
+		if _, ok := obj[m.key]; ok {
+			return nil, false
+		}
+		obj[m.key] = m.raw
+	}
+	return obj, true
+}
+
+// hasKeys reports whether the object has exactly the given keys.
+func (obj extJSONObject) hasKeys(keys ...string) bool {
+	if len(obj) != len(keys) {
+		return false
+	}
+	for _, key := range keys {
+		if _, ok := obj[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (obj extJSONObject) string(key string) (string, bool) {
+	p := jsonParser{data: obj[key]}
+	s, err := p.parseString()
+	return s, err == nil && p.pos == len(p.data)
+}
+
+func (obj extJSONObject) uint32(key string) (uint32, bool) {
+	v, err := strconv.ParseUint(string(obj[key]), 10, 32)
+	return uint32(v), err == nil
+}
+
+// parseWrapper parses an Extended JSON type wrapper like {"$oid": "..."}.
+func (p *jsonParser) parseWrapper(dst []byte) ([]byte, Type, error) {
+	start := p.pos
+	members, err := p.parseMembers()
+	if err != nil {
+		return dst, BSONTypeUndefined, err
+	}
+	obj := make(extJSONObject, len(members))
+	for _, m := range members {
+		obj[m.key] = m.raw
+	}
+	key := members[0].key
+	out, tp, ok := appendExtJSONValue(dst, key, obj)
+	if !ok || len(obj) != len(members) {
+		p.pos = start
+		return dst, BSONTypeUndefined, p.errorf("invalid %s", key)
+	}
+	return out, tp, nil
+}
+
+func appendExtJSONValue(dst []byte, key string, obj extJSONObject) (_ []byte, _ Type, ok bool) {
+	switch key {
+	case "$oid":
+		s, ok := obj.string(key)
+		if !ok || !obj.hasKeys(key) || len(s) != 24 {
+			return dst, BSONTypeUndefined, false
+		}
+		id, err := hex.DecodeString(s)
+		return append(dst, id...), BSONTypeObjectID, err == nil
+	case "$symbol":
+		s, ok := obj.string(key)
+		return appendString(dst, s), BSONTypeSymbol, ok && obj.hasKeys(key)
+	case "$numberInt":
+		s, ok := obj.string(key)
+		v, err := strconv.ParseInt(s, 10, 32)
+		return appendInt32(dst, int32(v)), BSONTypeInt32, ok && err == nil && obj.hasKeys(key)
+	case "$numberLong":
+		s, ok := obj.string(key)
+		v, err := strconv.ParseInt(s, 10, 64)
+		return appendInt64(dst, v), BSONTypeInt64, ok && err == nil && obj.hasKeys(key)
+	case "$numberDouble":
+		s, ok := obj.string(key)
+		var f float64
+		var err error
+		switch s {
+		case "Infinity":
+			f = math.Inf(1)
+		case "-Infinity":
+			f = math.Inf(-1)
+		case "NaN":
+			f = math.NaN()
+		default:
+			f, err = strconv.ParseFloat(s, 64)
+		}
+		return appendDouble(dst, f), BSONTypeDouble, ok && err == nil && obj.hasKeys(key)
+	case "$numberDecimal":
+		s, ok := obj.string(key)
+		d, err := primitive.ParseDecimal128(s)
+		if !ok || err != nil || !obj.hasKeys(key) {
+			return dst, BSONTypeUndefined, false
+		}
+		high, low := d.GetBytes()
+		return appendInt64(appendInt64(dst, int64(low)), int64(high)), BSONTypeDecimal128, true
+	case "$binary":
+		return appendExtJSONBinary(dst, obj)
+	case "$uuid":
+		s, ok := obj.string(key)
+		if !ok || !obj.hasKeys(key) || len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return dst, BSONTypeUndefined, false
+		}
+		data, err := hex.DecodeString(s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:])
+		return appendBinary(dst, 0x04, data), BSONTypeBinary, err == nil
+	case "$code", "$scope":
+		code, ok := obj.string("$code")
+		if !ok {
+			return dst, BSONTypeUndefined, false
+		}
+		if obj.hasKeys("$code") {
+			return appendString(dst, code), BSONTypeJavaScript, true
+		}
+		if !obj.hasKeys("$code", "$scope") {
+			return dst, BSONTypeUndefined, false
+		}
+		pos := len(dst)
+		dst = appendString(appendInt32(dst, 0), code)
+		p := jsonParser{data: obj["$scope"]}
+		dst, tp, err := p.parseValue(dst)
+		if err != nil || tp != BSONTypeObject {
+			return dst, BSONTypeUndefined, false
+		}
+		putInt32(dst[pos:], int32(len(dst)-pos))
+		return dst, BSONTypeJavaScriptWithScope, true
+	case "$timestamp":
+		ts, ok := parseExtJSONObject(obj[key])
+		if !ok || !obj.hasKeys(key) || !ts.hasKeys("t", "i") {
+			return dst, BSONTypeUndefined, false
+		}
+		t, okT := ts.uint32("t")
+		i, okI := ts.uint32("i")
+		return appendInt32(appendInt32(dst, int32(i)), int32(t)), BSONTypeTimestamp, okT && okI
+	case "$regularExpression":
+		re, ok := parseExtJSONObject(obj[key])
+		if !ok || !obj.hasKeys(key) || !re.hasKeys("pattern", "options") {
+			return dst, BSONTypeUndefined, false
+		}
+		pattern, okP := re.string("pattern")
+		options, okO := re.string("options")
+		if !okP || !okO {
+			return dst, BSONTypeUndefined, false
+		}
+		sorted := []byte(options)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		dst, errP := appendCString(dst, pattern)
+		dst, errO := appendCString(dst, string(sorted))
+		return dst, BSONTypeRegex, errP == nil && errO == nil
+	case "$dbPointer":
+		ptr, ok := parseExtJSONObject(obj[key])
+		if !ok || !obj.hasKeys(key) || !ptr.hasKeys("$ref", "$id") {
+			return dst, BSONTypeUndefined, false
+		}
+		ns, ok := ptr.string("$ref")
+		p := jsonParser{data: ptr["$id"]}
+		dst, tp, err := p.parseValue(appendString(dst, ns))
+		return dst, BSONTypeDBPointer, ok && err == nil && tp == BSONTypeObjectID
+	case "$date":
+		if !obj.hasKeys(key) {
+			return dst, BSONTypeUndefined, false
+		}
+		if s, ok := obj.string(key); ok {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			return appendInt64(dst, t.Unix()*1e3+int64(t.Nanosecond())/1e6), BSONTypeDateTime, err == nil
+		}
+		s := string(obj[key]) // legacy form of milliseconds as a number
+		if long, ok := parseExtJSONObject(obj[key]); ok {
+			if s, ok = long.string("$numberLong"); !ok || !long.hasKeys("$numberLong") {
+				return dst, BSONTypeUndefined, false
+			}
+		}
+		ms, err := strconv.ParseInt(s, 10, 64)
+		return appendInt64(dst, ms), BSONTypeDateTime, err == nil
+	case "$minKey":
+		return dst, BSONTypeMinKey, obj.hasKeys(key) && string(obj[key]) == "1"
+	case "$maxKey":
+		return dst, BSONTypeMaxKey, obj.hasKeys(key) && string(obj[key]) == "1"
+	case "$undefined":
+		return dst, BSONTypeUndefined, obj.hasKeys(key) && string(obj[key]) == "true"
+	}
+	return dst, BSONTypeUndefined, false
+}
+
+// appendExtJSONBinary parses {"$binary": {"base64": "...", "subType": "xx"}}
+// or the legacy form {"$binary": "...", "$type": "xx"}.
+func appendExtJSONBinary(dst []byte, obj extJSONObject) ([]byte, Type, bool) {
+	var data, subtype string
+	var okData, okSubtype bool
+	if obj.hasKeys("$binary", "$type") {
+		data, okData = obj.string("$binary")
+		subtype, okSubtype = obj.string("$type")
+	} else if bin, ok := parseExtJSONObject(obj["$binary"]); ok && obj.hasKeys("$binary") && bin.hasKeys("base64", "subType") {
+		data, okData = bin.string("base64")
+		subtype, okSubtype = bin.string("subType")
+	}
+	if !okData || !okSubtype || len(subtype) == 0 || len(subtype) > 2 {
+		return dst, BSONTypeUndefined, false
+	}
+	st, err := strconv.ParseUint(subtype, 16, 8)
+	if err != nil {
+		return dst, BSONTypeUndefined, false
+	}
+	bs, err := base64.StdEncoding.DecodeString(data)
+	return appendBinary(dst, byte(st), bs), BSONTypeBinary, err == nil
+}
@@ -0,0 +1,62 @@
+package gbson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFromJSON(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	canonical, err := ToCanonicalJSON(doc)
+	require.NoError(t, err)
+	var expected bson.Raw
+	require.NoError(t, bson.UnmarshalExtJSON(canonical, true, &expected))
+	actual, err := FromJSON(canonical)
+	require.NoError(t, err)
+	require.Equal(t, []byte(expected), actual)
+
+	relaxed, err := ToJSON(doc)
+	require.NoError(t, err)
+	require.NoError(t, bson.UnmarshalExtJSON(relaxed, false, &expected))
+	actual, err = FromJSON(relaxed)
+	require.NoError(t, err)
+	require.Equal(t, []byte(expected), actual)
+
+	for _, text := range []string{
+		`{}`,
+		` { "a" : [ 1 , -2.5e3 , 9223372036854775807 , true , false , null , "é😀\n\"" ] } `,
+		`{"uuid": {"$uuid": "00112233-4455-6677-8899-aabbccddeeff"}, "bin": {"$binary": "AQI=", "$type": "02"}}`,
+		`{"date": {"$date": 1668000000123}, "code": {"$code": "x", "$scope": {"y": 1}}, "ref": {"$ref": "c", "$id": 1}}`,
+	} {
+		require.NoError(t, bson.UnmarshalExtJSON([]byte(text), false, &expected), text)
+		actual, err = FromJSON([]byte(text))
+		require.NoError(t, err, text)
+		require.Equal(t, []byte(expected), actual, text)
+	}
+
+	for _, text := range []string{
+		``,
+		`[]`,
+		`{"a": 1} x`,
+		`{"a": 01}`,
+		`{"a": 1e400}`,
+		`{"a": tru}`,
+		`{"a": "\x"}`,
+		`{"a": "b`,
+		`{"a" 1}`,
+		`{"a": 1,}`,
+		`{"a": {"$oid": "xyz"}}`,
+		`{"a": {"$numberInt": "2147483648"}}`,
+		`{"a": {"$oid": "000000000000000000000000", "b": 1}}`,
+		`{"a": {"$regularExpression": {"pattern": "a\u0000", "options": ""}}}`,
+	} {
+		_, err = FromJSON([]byte(text))
+		require.Error(t, err, text)
+	}
+	_, err = FromJSON([]byte(`{"a": {"$minKey": 2}}`))
+	require.ErrorIs(t, err, ErrInvalidJSON)
+	_, err = FromJSON([]byte(`{"$numberInt": "1"}`))
+	require.ErrorIs(t, err, ErrNotObject)
+}
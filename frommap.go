@@ -0,0 +1,394 @@
+package gbson
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FromMap encodes the map into a bson document, keys of maps are written in ascending order.
+// bson.M, bson.D and bson.A are accepted as values as well as the other mongo-driver primitive types,
+// only values of uncommon types are encoded by reflection the same as Marshal.
+func FromMap(m map[string]interface{}) ([]byte, error) {
+	return FromMapOrdered(m, nil)
+}
+
+// FromMapOrdered is like FromMap, with keys of every map ordered by less.
+// Keys are sorted ascending if less is nil.
+func FromMapOrdered(m map[string]interface{}, less func(a, b string) bool) ([]byte, error) {
+	e := mapEncoder{less: less}
+	dst, _, err := e.appendMap(allocEncoded(m), m)
+	return dst, err
+}
+
+// FromD encodes the bson.D into a bson document, the order of elements is kept.
+func FromD(d primitive.D) ([]byte, error) {
+	var e mapEncoder
+	dst, _, err := e.appendD(allocEncoded(d), d)
+	return dst, err
+}
+
+// allocEncoded allocates the output buffer of encoding v by EncodedSize, nil if the size is unknown.
+func allocEncoded(v interface{}) []byte {
+	if n := EncodedSize(v); n > 0 {
+		return make([]byte, 0, n)
+	}
+	return nil
+}
+
+// IDFirst is a key ordering for FromMapOrdered, which puts "_id" first and sorts the other keys.
+func IDFirst(a, b string) bool {
+	if a == "_id" || b == "_id" {
+		return a == "_id" && b != "_id"
+	}
+	return a < b
+}
+
+type mapEncoder struct {
+	less func(a, b string) bool
+}
+
+func (e mapEncoder) appendMap(dst []byte, m map[string]interface{}) ([]byte, Type, error) {
+	if m == nil {
+		return dst, BSONTypeNull, nil
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	if e.less == nil {
+		sort.Strings(keys)
+	} else {
+		sort.Slice(keys, func(i, j int) bool { return e.less(keys[i], keys[j]) })
+	}
+	dst, start := beginDocument(dst)
+	for _, key := range keys {
+		var err error
+		if dst, err = e.appendElement(dst, key, m[key]); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+	}
+	return endDocument(dst, start), BSONTypeObject, nil
+}
+
+func (e mapEncoder) appendD(dst []byte, d primitive.D) ([]byte, Type, error) {
+	if d == nil {
+		return dst, BSONTypeNull, nil
+	}
+	dst, start := beginDocument(dst)
+	for _, elem := range d {
+		var err error
+		if dst, err = e.appendElement(dst, elem.Key, elem.Value); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+	}
+	return endDocument(dst, start), BSONTypeObject, nil
+}
+
+func (e mapEncoder) appendArray(dst []byte, a []interface{}) ([]byte, Type, error) {
+	if a == nil {
+		return dst, BSONTypeNull, nil
+	}
+	dst, start := beginDocument(dst)
+	for i, v := range a {
+		pos := len(dst)
+		dst = appendIndexHeader(dst, BSONTypeNull, i)
+		var tp Type
+		var err error
+		if dst, tp, err = e.appendValue(dst, v); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		dst[pos] = byte(tp)
+	}
+	return endDocument(dst, start), BSONTypeArray, nil
+}
+
+func (e mapEncoder) appendElement(dst []byte, key string, v interface{}) ([]byte, error) {
+	pos := len(dst)
+	dst, err := appendElementHeader(dst, BSONTypeNull, key)
+	if err != nil {
+		return dst, err
+	}
+	var tp Type
+	if dst, tp, err = e.appendValue(dst, v); err != nil {
+		return dst, err
+	}
+	dst[pos] = byte(tp)
+	return dst, nil
+}
+
+func (e mapEncoder) appendValue(dst []byte, v interface{}) ([]byte, Type, error) {
+	switch v := v.(type) {
+	case nil:
+		return dst, BSONTypeNull, nil
+	case map[string]interface{}:
+		return e.appendMap(dst, v)
+	case primitive.M:
+		return e.appendMap(dst, v)
+	case primitive.D:
+		return e.appendD(dst, v)
+	case []interface{}:
+		return e.appendArray(dst, v)
+	case primitive.A:
+		return e.appendArray(dst, v)
+	case primitive.CodeWithScope:
+		pos := len(dst)
+		dst = appendString(appendInt32(dst, 0), string(v.Code))
+		dst, tp, err := e.appendValue(dst, v.Scope)
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		if tp != BSONTypeObject {
+			return dst, BSONTypeUndefined, errors.Wrap(ErrNotObject, "scope of code with scope")
+		}
+		putInt32(dst[pos:], int32(len(dst)-pos))
+		return dst, BSONTypeJavaScriptWithScope, nil
+	case bool:
+		if v {
+			return append(dst, 1), BSONTypeBoolean, nil
+		}
+		return append(dst, 0), BSONTypeBoolean, nil
+	case int:
+		if v >= math.MinInt32 && v <= math.MaxInt32 {
+			return appendInt32(dst, int32(v)), BSONTypeInt32, nil
+		}
+		return appendInt64(dst, int64(v)), BSONTypeInt64, nil
+	case int8:
+		return appendInt32(dst, int32(v)), BSONTypeInt32, nil
+	case int16:
+		return appendInt32(dst, int32(v)), BSONTypeInt32, nil
+	case int32:
+		return appendInt32(dst, v), BSONTypeInt32, nil
+	case int64:
+		return appendInt64(dst, v), BSONTypeInt64, nil
+	case uint8:
+		return appendInt32(dst, int32(v)), BSONTypeInt32, nil
+	case uint16:
+		return appendInt32(dst, int32(v)), BSONTypeInt32, nil
+	case uint32:
+		return appendInt64(dst, int64(v)), BSONTypeInt64, nil
+	case uint, uint64:
+		return encodeUint(dst, reflect.ValueOf(v))
+	case float32:
+		return appendDouble(dst, float64(v)), BSONTypeDouble, nil
+	case float64:
+		return appendDouble(dst, v), BSONTypeDouble, nil
+	case string:
+		return appendString(dst, v), BSONTypeString, nil
+	case []byte:
+		if v == nil {
+			return dst, BSONTypeNull, nil
+		}
+		return appendBinary(dst, 0, v), BSONTypeBinary, nil
+	case time.Time:
+		return appendInt64(dst, v.Unix()*1e3+int64(v.Nanosecond())/1e6), BSONTypeDateTime, nil
+	case Result:
+		return append(dst, v.Raw...), v.Type, nil
+	case primitive.Regex:
+		// the pattern and the options are cstrings, which can't hold zero bytes
+		options := []byte(v.Options)
+		sort.Slice(options, func(i, j int) bool { return options[i] < options[j] })
+		dst, err := appendCString(dst, v.Pattern)
+		if err != nil {
+			return dst, BSONTypeUndefined, errors.WithMessage(err, "regex pattern")
+		}
+		if dst, err = appendCString(dst, string(options)); err != nil {
+			return dst, BSONTypeUndefined, errors.WithMessage(err, "regex options")
+		}
+		return dst, BSONTypeRegex, nil
+	}
+	if out, tp, ok := appendPrimitive(dst, v); ok {
+		return out, tp, nil
+	}
+	rv := reflect.ValueOf(v)
+	return encoderOf(rv.Type())(dst, rv)
+}
+
+// driverTypes are the mongo-driver types encoded by mapEncoder rather than reflection in Marshal.
+var driverTypes = map[reflect.Type]bool{
+	reflect.TypeOf(primitive.D{}):             true,
+	reflect.TypeOf(primitive.CodeWithScope{}): true,
+	reflect.TypeOf(primitive.ObjectID{}):      true,
+	reflect.TypeOf(primitive.DateTime(0)):     true,
+	reflect.TypeOf(primitive.Timestamp{}):     true,
+	reflect.TypeOf(primitive.Binary{}):        true,
+	reflect.TypeOf(primitive.Decimal128{}):    true,
+	reflect.TypeOf(primitive.Regex{}):         true,
+	reflect.TypeOf(primitive.DBPointer{}):     true,
+	reflect.TypeOf(primitive.JavaScript("")):  true,
+	reflect.TypeOf(primitive.Symbol("")):      true,
+	reflect.TypeOf(primitive.Null{}):          true,
+	reflect.TypeOf(primitive.Undefined{}):     true,
+	reflect.TypeOf(primitive.MinKey{}):        true,
+	reflect.TypeOf(primitive.MaxKey{}):        true,
+}
+
+func encodeDriverValue(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	var e mapEncoder
+	return e.appendValue(dst, v.Interface())
+}
+
+// appendPrimitive appends the scalar types defined in mongo-driver's primitive package,
+// ok is false if v is not one of them.
+func appendPrimitive(dst []byte, v interface{}) (_ []byte, _ Type, ok bool) {
+	switch v := v.(type) {
+	case primitive.ObjectID:
+		return append(dst, v[:]...), BSONTypeObjectID, true
+	case primitive.DateTime:
+		return appendInt64(dst, int64(v)), BSONTypeDateTime, true
+	case primitive.Timestamp:
+		return appendInt32(appendInt32(dst, int32(v.I)), int32(v.T)), BSONTypeTimestamp, true
+	case primitive.Binary:
+		return appendBinary(dst, v.Subtype, v.Data), BSONTypeBinary, true
+	case primitive.Decimal128:
+		high, low := v.GetBytes()
+		return appendInt64(appendInt64(dst, int64(low)), int64(high)), BSONTypeDecimal128, true
+	case primitive.DBPointer:
+		return append(appendString(dst, v.DB), v.Pointer[:]...), BSONTypeDBPointer, true
+	case primitive.JavaScript:
+		return appendString(dst, string(v)), BSONTypeJavaScript, true
+	case primitive.Symbol:
+		return appendString(dst, string(v)), BSONTypeSymbol, true
+	case primitive.Null:
+		return dst, BSONTypeNull, true
+	case primitive.Undefined:
+		return dst, BSONTypeUndefined, true
+	case primitive.MinKey:
+		return dst, BSONTypeMinKey, true
+	case primitive.MaxKey:
+		return dst, BSONTypeMaxKey, true
+	}
+	return dst, BSONTypeUndefined, false
+}
+
+// EncodedSize returns the size in bytes of the bson document or array FromMap, FromD or Marshal encodes v into,
+// which is a map, bson.D or a slice of the values FromMap takes, so the output could be allocated once.
+// It returns -1 for other values and the ones containing values encoded by reflection, such as structs,
+// whose sizes aren't known without encoding them.
+func EncodedSize(v interface{}) int {
+	switch v.(type) {
+	case map[string]interface{}, primitive.M, primitive.D, []interface{}, primitive.A:
+		if n := encodedValueSize(v); n > 0 {
+			return n
+		}
+	}
+	return -1
+}
+
+// encodedValueSize returns the size of the value mapEncoder.appendValue appends, -1 if it's unknown.
+func encodedValueSize(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int8, int16, int32, uint8, uint16:
+		return 4
+	case map[string]interface{}:
+		return encodedMapSize(v)
+	case primitive.M:
+		return encodedMapSize(v)
+	case primitive.D:
+		if v == nil {
+			return 0
+		}
+		n := 5
+		for _, elem := range v {
+			size := encodedValueSize(elem.Value)
+			if size < 0 {
+				return -1
+			}
+			n += len(elem.Key) + 2 + size
+		}
+		return n
+	case []interface{}:
+		return encodedArraySize(v)
+	case primitive.A:
+		return encodedArraySize(v)
+	case primitive.CodeWithScope:
+		scope := encodedValueSize(v.Scope)
+		if scope < 0 {
+			return -1
+		}
+		return 4 + len(v.Code) + 5 + scope
+	case int:
+		if v >= math.MinInt32 && v <= math.MaxInt32 {
+			return 4
+		}
+		return 8
+	case int64, uint32, uint, uint64, float32, float64, time.Time, primitive.DateTime, primitive.Timestamp:
+		return 8
+	case string:
+		return len(v) + 5
+	case []byte:
+		if v == nil {
+			return 0
+		}
+		return len(v) + 5
+	case Result:
+		return len(v.Raw)
+	case primitive.ObjectID:
+		return 12
+	case primitive.Binary:
+		if v.Subtype == 0x02 {
+			return len(v.Data) + 9
+		}
+		return len(v.Data) + 5
+	case primitive.Decimal128:
+		return 16
+	case primitive.Regex:
+		return len(v.Pattern) + len(v.Options) + 2
+	case primitive.DBPointer:
+		return len(v.DB) + 5 + 12
+	case primitive.JavaScript:
+		return len(v) + 5
+	case primitive.Symbol:
+		return len(v) + 5
+	case primitive.Null, primitive.Undefined, primitive.MinKey, primitive.MaxKey:
+		return 0
+	}
+	return -1
+}
+
+func encodedMapSize(m map[string]interface{}) int {
+	if m == nil {
+		return 0
+	}
+	n := 5
+	for key, v := range m {
+		size := encodedValueSize(v)
+		if size < 0 {
+			return -1
+		}
+		n += len(key) + 2 + size
+	}
+	return n
+}
+
+func encodedArraySize(a []interface{}) int {
+	if a == nil {
+		return 0
+	}
+	n := 5
+	for i, v := range a {
+		size := encodedValueSize(v)
+		if size < 0 {
+			return -1
+		}
+		n += indexLen(i) + 2 + size
+	}
+	return n
+}
+
+// indexLen returns the number of decimal digits of the array index.
+func indexLen(i int) int {
+	n := 1
+	for ; i >= 10; i /= 10 {
+		n++
+	}
+	return n
+}
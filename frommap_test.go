@@ -0,0 +1,131 @@
+package gbson
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFromMap(t *testing.T) {
+	oid := primitive.NewObjectID()
+	dec, err := primitive.ParseDecimal128("12.34")
+	require.NoError(t, err)
+	d := bson.D{
+		{Key: "_id", Value: oid},
+		{Key: "int", Value: 1},
+		{Key: "long", Value: int64(1) << 40},
+		{Key: "double", Value: 1.5},
+		{Key: "string", Value: "text"},
+		{Key: "bool", Value: true},
+		{Key: "null", Value: nil},
+		{Key: "date", Value: primitive.NewDateTimeFromTime(time.UnixMilli(1668000000123))},
+		{Key: "ts", Value: primitive.Timestamp{T: 10, I: 2}},
+		{Key: "binary", Value: primitive.Binary{Subtype: 0x80, Data: []byte{1, 2}}},
+		{Key: "old", Value: primitive.Binary{Subtype: 0x02, Data: []byte{1, 2}}},
+		{Key: "decimal", Value: dec},
+		{Key: "regex", Value: primitive.Regex{Pattern: "^a", Options: "mi"}},
+		{Key: "code", Value: primitive.CodeWithScope{Code: "x", Scope: bson.M{"x": 1}}},
+		{Key: "array", Value: bson.A{1, "a", bson.D{{Key: "z", Value: 1}, {Key: "y", Value: 2}}}},
+		{Key: "slice", Value: []string{"a", "b"}},
+		{Key: "min", Value: primitive.MinKey{}},
+	}
+	expected, err := bson.Marshal(d)
+	require.NoError(t, err)
+	actual, err := FromD(d)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	// structs containing driver types encode the same by Marshal
+	actual, err = Marshal(struct {
+		Doc bson.D `bson:"doc"`
+	}{Doc: d})
+	require.NoError(t, err)
+	expected, err = bson.Marshal(bson.D{{Key: "doc", Value: d}})
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	m := bson.M{"b": 1, "_id": "id", "a": map[string]interface{}{"y": 1, "x": 2}}
+	actual, err = FromMap(m)
+	require.NoError(t, err)
+	expected, err = bson.Marshal(bson.D{
+		{Key: "_id", Value: "id"},
+		{Key: "a", Value: bson.D{{Key: "x", Value: 2}, {Key: "y", Value: 1}}},
+		{Key: "b", Value: 1},
+	})
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	actual, err = FromMapOrdered(map[string]interface{}{"a": 1, "_id": 2, "b": 3}, IDFirst)
+	require.NoError(t, err)
+	expected, err = bson.Marshal(bson.D{{Key: "_id", Value: 2}, {Key: "a", Value: 1}, {Key: "b", Value: 3}})
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	_, err = FromMap(map[string]interface{}{"c": make(chan int)})
+	require.ErrorIs(t, err, ErrUnsupportedType)
+	_, err = FromMap(map[string]interface{}{"r": primitive.Regex{Pattern: "a\x00b"}})
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = Marshal(struct{ R primitive.Regex }{primitive.Regex{Pattern: "a", Options: "i\x00"}})
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestEncodedSize(t *testing.T) {
+	dec, err := primitive.ParseDecimal128("12.34")
+	require.NoError(t, err)
+	d := bson.D{
+		{Key: "_id", Value: primitive.NewObjectID()},
+		{Key: "int", Value: 1},
+		{Key: "large", Value: 1 << 40},
+		{Key: "small", Value: int8(1)},
+		{Key: "uint", Value: uint32(1)},
+		{Key: "double", Value: float32(1.5)},
+		{Key: "string", Value: "text"},
+		{Key: "bytes", Value: []byte("ab")},
+		{Key: "bool", Value: true},
+		{Key: "null", Value: nil},
+		{Key: "time", Value: time.UnixMilli(1668000000123)},
+		{Key: "ts", Value: primitive.Timestamp{T: 10, I: 2}},
+		{Key: "binary", Value: primitive.Binary{Subtype: 0x80, Data: []byte{1, 2}}},
+		{Key: "old", Value: primitive.Binary{Subtype: 0x02, Data: []byte{1, 2}}},
+		{Key: "decimal", Value: dec},
+		{Key: "regex", Value: primitive.Regex{Pattern: "^a", Options: "mi"}},
+		{Key: "pointer", Value: primitive.DBPointer{DB: "db.c", Pointer: primitive.NewObjectID()}},
+		{Key: "js", Value: primitive.JavaScript("x")},
+		{Key: "code", Value: primitive.CodeWithScope{Code: "x", Scope: bson.M{"x": 1}}},
+		{Key: "array", Value: bson.A{1, "a", bson.D{{Key: "z", Value: 1}}, nil, nil, nil, nil, nil, nil, nil, 1}},
+		{Key: "result", Value: Get(mustMarshal(t, bson.M{"a": "b"}), "a")},
+		{Key: "min", Value: primitive.MinKey{}},
+	}
+	doc, err := FromD(d)
+	require.NoError(t, err)
+	require.Equal(t, len(doc), EncodedSize(d))
+	require.Equal(t, len(doc), cap(doc))
+
+	m := bson.M{"d": d, "m": map[string]interface{}{"a": []interface{}{"x"}}}
+	doc, err = FromMap(m)
+	require.NoError(t, err)
+	require.Equal(t, len(doc), EncodedSize(m))
+	marshaled, err := Marshal(m)
+	require.NoError(t, err)
+	require.Equal(t, doc, marshaled)
+
+	require.Equal(t, -1, EncodedSize(bson.M{"s": struct{}{}}))
+	require.Equal(t, -1, EncodedSize(struct{}{}))
+	require.Equal(t, -1, EncodedSize("text"))
+	require.Equal(t, -1, EncodedSize(bson.M(nil)))
+}
+
+func BenchmarkFromMap(b *testing.B) {
+	m := make(map[string]interface{})
+	for i := 0; i < 100; i++ {
+		m[fmt.Sprintf("field%d", i)] = bson.M{"name": "some text", "count": i, "tags": bson.A{"a", "b", "c"}}
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = FromMap(m)
+	}
+}
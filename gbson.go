@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/binary"
 	"math"
+	"strconv"
+	"strings"
 	"time"
-	"unsafe"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 )
@@ -16,10 +18,62 @@ import (
 // BSON format specification: https://bsonspec.org/spec.html
 
 var (
-	ErrInvalidLength = errors.New("invalid length")
-	ErrNotObject     = errors.New("not an object")
+	ErrInvalidLength   = errors.New("invalid length")
+	ErrNotObject       = errors.New("not an object")
+	ErrInvalidKey      = errors.New("invalid key")
+	ErrUnsupportedType = errors.New("unsupported type")
+	ErrTypeMismatch    = errors.New("type mismatch")
+	ErrNotExist        = errors.New("not exist")
+	ErrLossyConversion = errors.New("lossy conversion")
+	ErrInvalidJSON     = errors.New("invalid json")
+	ErrInvalidMsgPack  = errors.New("invalid msgpack")
+	ErrInvalidValue    = errors.New("invalid value")
+	ErrMaxDepth        = errors.New("max depth exceeded")
+	ErrTooLarge        = errors.New("size limit exceeded")
+	ErrDuplicateKey    = errors.New("duplicate key")
+	ErrBudgetExceeded  = errors.New("budget exceeded")
+	ErrPanic           = errors.New("panic")
 )
 
+// DefaultMaxDepth is the max nesting depth of documents and arrays, where the top level document is at depth 1.
+// Deeper values are rejected with ErrMaxDepth by GetIter, validation, Walk, ElementReader, Unmarshal
+// and the converters, which bounds the recursion on malicious documents. Value and ToMap return
+// the Result itself for containers deeper than it. GetOptions and Validator set the depth per call.
+const DefaultMaxDepth = 200
+
+// checkDepth returns ErrMaxDepth if the depth exceeds DefaultMaxDepth.
+func checkDepth(depth int) error {
+	if depth > DefaultMaxDepth {
+		return errors.Wrapf(ErrMaxDepth, "depth %d", depth)
+	}
+	return nil
+}
+
+// checkNesting checks the containers in the document or array at the depth are not nested deeper than
+// DefaultMaxDepth, it skips over the other values without looking into them and leaves invalid lengths to the caller.
+func checkNesting(raw []byte, depth int) error {
+	if err := checkDepth(depth); err != nil {
+		return err
+	}
+	elements, ok := containerElements(raw)
+	if !ok {
+		return nil
+	}
+	for len(elements) > 0 {
+		tp, _, value, n := consumeElement(elements)
+		if n < 0 {
+			return nil
+		}
+		elements = elements[n:]
+		if tp == BSONTypeObject || tp == BSONTypeArray {
+			if err := checkNesting(value, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 type Type uint8
 
 const (
@@ -46,9 +100,49 @@ const (
 	BSONTypeMaxKey              Type = 0x7F
 )
 
+// typeNames are the names of types used by MongoDB's $type operator.
+var typeNames = map[Type]string{
+	BSONTypeDouble:              "double",
+	BSONTypeString:              "string",
+	BSONTypeObject:              "object",
+	BSONTypeArray:               "array",
+	BSONTypeBinary:              "binData",
+	BSONTypeUndefined:           "undefined",
+	BSONTypeObjectID:            "objectId",
+	BSONTypeBoolean:             "bool",
+	BSONTypeDateTime:            "date",
+	BSONTypeNull:                "null",
+	BSONTypeRegex:               "regex",
+	BSONTypeDBPointer:           "dbPointer",
+	BSONTypeJavaScript:          "javascript",
+	BSONTypeSymbol:              "symbol",
+	BSONTypeJavaScriptWithScope: "javascriptWithScope",
+	BSONTypeInt32:               "int",
+	BSONTypeTimestamp:           "timestamp",
+	BSONTypeInt64:               "long",
+	BSONTypeDecimal128:          "decimal",
+	BSONTypeMinKey:              "minKey",
+	BSONTypeMaxKey:              "maxKey",
+}
+
+// String returns the name of the type used by MongoDB's $type operator, e.g. "objectId" and "long",
+// or the type code in hex like "Type(0x14)" for unknown types.
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return "Type(0x" + string([]byte{hexDigits[t>>4], hexDigits[t&0xF]}) + ")"
+}
+
+// Result is a value of a bson document referring to its raw bytes.
+// Accessors never panic on malformed values, such as ones cut short: they return zero values,
+// or errors for the checked variants. Err tells whether the value is malformed.
 type Result struct {
 	Type Type
 	Raw  []byte // value part
+	err  error  // error of getting the value, see Err
+	// strict verifies texts, see GetOptions.StrictStrings
+	strict bool
 }
 
 // Get gets the first value by the given path.
@@ -60,28 +154,179 @@ func Get(pb []byte, path ...string) Result {
 	return state.Get(path...)
 }
 
-// Get gets the first value by the given path.
+// GetSorted is Get for documents whose top level keys are sorted in ascending byte order, such as the ones
+// encoded by FromMap and Marshal of maps. The scan for the first key stops as soon as a greater key is scanned,
+// which halves the scan of misses on average. Values of unsorted documents may be missed.
+func GetSorted(doc []byte, path ...string) Result {
+	if len(path) == 0 {
+		return Get(doc)
+	}
+	c := resultFromBytes(doc).Cursor()
+	for c.Next() {
+		if key := c.Key(); bytesEqualToString(key, path[0]) {
+			if len(path) == 1 {
+				return c.Value()
+			}
+			return c.Value().Get(path[1:]...)
+		} else if string(key) > path[0] {
+			break
+		}
+	}
+	if err := c.Err(); err != nil {
+		return Result{Type: BSONTypeUndefined, err: locateError(doc, err)}
+	}
+	return Result{Type: BSONTypeUndefined}
+}
+
+// GetPath gets the first value by the dotted path like "a.b.0", the same as Get with the keys separated by dots.
+// The keys are taken from the path in place without allocations. Keys containing dots could be got by Get.
+func GetPath(doc []byte, path string) Result {
+	return resultFromBytes(doc).GetPath(path)
+}
+
+// GetPath gets the first value by the dotted path, see the package level GetPath.
+func (r Result) GetPath(path string) (result Result) {
+	if r.err != nil {
+		return r
+	}
+	result.Type = BSONTypeUndefined
+	var err error
+	if depth := strings.Count(path, ".") + 1; depth > DefaultMaxDepth {
+		err = errors.Wrapf(ErrMaxDepth, "depth %d", depth)
+	} else {
+		if result, err = r.getPath(path); err != nil && r.IsContainer() {
+			err = locateError(r.Raw, err)
+		}
+	}
+	if err != nil && !result.Exist() && !errors.Is(err, ErrNotObject) {
+		result.err = err
+	}
+	return
+}
+
+// getPath gets the first value by the dotted path in the container the same as GetIter, taking the keys of
+// the path in place.
+func (r Result) getPath(path string) (Result, error) {
+	missing := Result{Type: BSONTypeUndefined}
+	key, rest, last := path, "", true
+	if i := strings.IndexByte(path, '.'); i >= 0 {
+		key, rest, last = path[:i], path[i+1:], false
+	}
+	if !r.IsContainer() {
+		return missing, ErrNotObject
+	}
+	elements, ok := containerElements(r.Raw)
+	if !ok {
+		return missing, ErrInvalidLength
+	}
+	for len(elements) > 0 {
+		tp, name, value, n := consumeElement(elements)
+		if n < 0 {
+			return missing, ErrInvalidLength
+		}
+		elements = elements[n:]
+		if !bytesEqualToString(name, key) {
+			continue
+		}
+		found := Result{Type: tp, Raw: value}
+		if last {
+			return found, nil
+		}
+		if found, err := found.getPath(rest); err != nil || found.Exist() {
+			return found, err
+		}
+	}
+	return missing, nil
+}
+
+// Get gets the first value by the given path. If the document is malformed before the value is found,
+// the returned value is missing with the error carried by Err, and so are values got from it.
 func (r Result) Get(path ...string) (result Result) {
+	return r.get(defaultGetLimits, path)
+}
+
+// get is Get with the limits.
+func (r Result) get(limits getLimits, path []string) (result Result) {
+	if r.err != nil {
+		return r
+	}
 	result.Type = BSONTypeUndefined
 	// use callback to avoid heap memory allocation
-	_ = r.GetIter(func(r Result) bool {
+	err := r.getIter(limits, func(r Result) bool {
 		result = r
 		return false
-	}, path...)
+	}, path)
+	if err != nil && !result.Exist() && !errors.Is(err, ErrNotObject) {
+		result.err = err
+	}
 	return
 }
 
+// Err returns the error of getting the value, e.g. a Finding locating where the document is malformed,
+// so chained calls like Get(doc, "a").Get("b").Int64E() report where the parsing went wrong.
+// Otherwise it checks the raw bytes against the type: the length the value declares or has by its type
+// must be the length of the raw bytes, and documents, arrays and strings must end with a zero byte.
+// The elements of documents and arrays are not checked, which Validate does.
+// Missing values of well-formed documents and values of unknown types have no errors.
+func (r Result) Err() error {
+	if r.err != nil || !r.Exist() || !isKnownType(r.Type) {
+		return r.err
+	}
+	n := declaredValueLen(r.Type, r.Raw)
+	if n != len(r.Raw) {
+		return errors.Wrapf(LengthError{Expected: n, Actual: len(r.Raw)}, "%v declares %d bytes, %d available", r.Type, n, len(r.Raw))
+	}
+	switch r.Type {
+	case BSONTypeObject, BSONTypeArray:
+		if _, ok := containerElements(r.Raw); !ok {
+			return errors.Wrapf(ErrInvalidLength, "%v is not terminated by a zero byte", r.Type)
+		}
+	case BSONTypeString, BSONTypeJavaScript, BSONTypeSymbol:
+		if _, n = consumeString(r.Raw); n == 0 || r.Raw[n-1] != 0 {
+			return errors.Wrap(ErrInvalidLength, "malformed string")
+		}
+	}
+	return nil
+}
+
 // GetIter gets all the values until the resultSink returns false.
-// Get calls this method internally.
+// Get calls this method internally. Errors of invalid lengths are Findings locating the invalid part.
 func (r Result) GetIter(resultSink func(Result) bool, path ...string) (err error) {
+	return r.getIter(defaultGetLimits, resultSink, path)
+}
+
+// getLimits are the limits and the policy of a Get call, by GetOptions or the defaults.
+type getLimits struct {
+	maxDepth     int
+	budget       int
+	unknownTypes *UnknownTypePolicy
+}
+
+// defaultGetLimits are the limits of the calls without GetOptions.
+var defaultGetLimits = getLimits{maxDepth: DefaultMaxDepth, unknownTypes: &defaultUnknownTypes}
+
+// getIter is GetIter with the limits.
+func (r Result) getIter(limits getLimits, resultSink func(Result) bool, path []string) (err error) {
+	if r.err != nil {
+		return r.err
+	}
+	if len(path) > limits.maxDepth {
+		return errors.Wrapf(ErrMaxDepth, "depth %d", len(path))
+	}
 	// use recursion calls to iterate through the data in depth first order.
 	var skip bool
-	var depth int
+	var depth, visited int
+	budget := limits.budget
 	var walkFunc func([]byte, Result) bool
 	walkFunc = func(key []byte, it Result) bool {
 		if skip {
 			return false
 		}
+		if visited++; budget > 0 && visited > budget {
+			err = errors.Wrapf(ErrBudgetExceeded, "more than %d elements visited", budget)
+			skip = true
+			return false
+		}
 		if !bytesEqualToString(key, path[depth]) {
 			// not the desired field
 			return true
@@ -95,7 +340,7 @@ func (r Result) GetIter(resultSink func(Result) bool, path ...string) (err error
 		}
 		// recursion call
 		depth++
-		if _, innerErr := it.iterFields(walkFunc); innerErr != nil {
+		if _, innerErr := it.iterFieldsPolicy(limits.unknownTypes, walkFunc); innerErr != nil {
 			err = innerErr
 			skip = true
 			return false
@@ -103,22 +348,58 @@ func (r Result) GetIter(resultSink func(Result) bool, path ...string) (err error
 		depth--
 		return true
 	}
-	if _, innerErr := r.iterFields(walkFunc); innerErr != nil {
-		return innerErr
+	if _, innerErr := r.iterFieldsPolicy(limits.unknownTypes, walkFunc); innerErr != nil {
+		err = innerErr
 	}
-	return nil
+	if err != nil && r.IsContainer() {
+		return locateError(r.Raw, err)
+	}
+	return err
 }
 
 func resultFromBytes(bs []byte) Result {
 	return Result{Type: BSONTypeObject, Raw: bs}
 }
 
+// ReadElement reads the element at the beginning of bs, returns the type, the name, the value bytes in the same
+// layout as Result.Raw and the total length of the element, so custom scanners could be built on the same
+// tokenizer as Get. name and value refer to bs without copying. Elements of a document start after its
+// 4 bytes length, and end before its terminating zero byte:
+//
+//	elements := doc[4 : len(doc)-1]
+//	for len(elements) > 0 {
+//		tp, name, value, n, err := gbson.ReadElement(elements)
+//		if err != nil {
+//			return err
+//		}
+//		elements = elements[n:]
+//		...
+//	}
+//
+// It returns ErrUnsupportedType for an unknown type byte, and ErrInvalidLength if bs ends before the element.
+func ReadElement(bs []byte) (tp Type, name []byte, value []byte, n int, err error) {
+	tp, name, value, n = consumeElement(bs)
+	if n < 0 {
+		if len(bs) > 0 && typeNames[Type(bs[0])] == "" {
+			return 0, nil, nil, 0, errors.Wrapf(ErrUnsupportedType, "type %v", Type(bs[0]))
+		}
+		return 0, nil, nil, 0, ErrInvalidLength
+	}
+	return tp, name, value, n, nil
+}
+
 func consumeElement(bs []byte) (tp Type, name []byte, value []byte, totalLen int) {
+	return consumeElementPolicy(bs, &defaultUnknownTypes)
+}
+
+// consumeElementPolicy is consumeElement reading unknown types by the policy.
+func consumeElementPolicy(bs []byte, policy *UnknownTypePolicy) (tp Type, name []byte, value []byte, totalLen int) {
 	if len(bs) == 0 { // empty binary
 		return BSONTypeUndefined, nil, nil, -1
 	}
 	tp = Type(bs[0])
-	if !(tp >= 0x01 && tp <= 0x13) && tp != 0xFF && tp != 0x7F { // invalid type
+	known := isKnownType(tp)
+	if !known && (policy.Mode == UnknownTypeError || policy.Length == nil) { // invalid type
 		return BSONTypeUndefined, nil, nil, -1
 	}
 	name, nameLen := consumeCString(bs[1:])
@@ -127,6 +408,56 @@ func consumeElement(bs []byte) (tp Type, name []byte, value []byte, totalLen int
 	}
 	bs = bs[nameLen+1:]
 	var valueLen int
+	if known {
+		valueLen = consumeValue(tp, bs)
+	} else if valueLen = policy.Length(tp, bs); valueLen > len(bs) {
+		valueLen = -1
+	}
+	if valueLen < 0 {
+		return BSONTypeUndefined, nil, nil, -1
+	}
+	return tp, name, bs[:valueLen], 1 + nameLen + valueLen
+}
+
+// isKnownType reports whether the type is defined by the bson specification.
+func isKnownType(tp Type) bool {
+	return (tp >= 0x01 && tp <= 0x13) || tp == 0xFF || tp == 0x7F
+}
+
+// UnknownTypeMode is the mode of UnknownTypePolicy.
+type UnknownTypeMode uint8
+
+const (
+	UnknownTypeError  UnknownTypeMode = iota // fail the traversal with ErrInvalidLength, the default
+	UnknownTypeSkip                          // skip the elements as if they were absent
+	UnknownTypeOpaque                        // surface the elements as Results of their types with the raw values
+)
+
+// UnknownTypePolicy decides how elements of types unknown to the package, e.g. added by future
+// bson specifications, are read by a Get call with GetOptions.UnknownTypes.
+// Their lengths are derived by Length, which returns the length of the value of the type at the beginning
+// of value, or -1 if it's not derivable. Unknown types fail the traversal if Length is nil or fails.
+// The iterators, Walk, ElementReader and the converters fail on unknown types, and validation reports them.
+type UnknownTypePolicy struct {
+	Mode   UnknownTypeMode
+	Length func(tp Type, value []byte) int
+}
+
+// defaultUnknownTypes is the policy of the calls without GetOptions, which fails on unknown types.
+var defaultUnknownTypes UnknownTypePolicy
+
+// consumeValue returns the length of the value of the type at the beginning of bs, -1 if it's invalid.
+func consumeValue(tp Type, bs []byte) (valueLen int) {
+	valueLen = declaredValueLen(tp, bs)
+	if valueLen < 0 || len(bs) < valueLen {
+		return -1
+	}
+	return valueLen
+}
+
+// declaredValueLen returns the length of the value at the beginning of bs, which is fixed by the type or
+// declared by the value, without checking it against len(bs). It returns -1 for unknown types.
+func declaredValueLen(tp Type, bs []byte) (valueLen int) {
 	switch tp {
 	case BSONTypeUndefined, BSONTypeNull, BSONTypeMinKey, BSONTypeMaxKey:
 		valueLen = 0
@@ -155,12 +486,9 @@ func consumeElement(bs []byte) (tp Type, name []byte, value []byte, totalLen int
 	case BSONTypeJavaScriptWithScope:
 		valueLen = int(consumeInt32(bs))
 	default:
-		return BSONTypeUndefined, nil, nil, -1
+		return -1
 	}
-	if len(bs) < valueLen {
-		return BSONTypeUndefined, nil, nil, -1
-	}
-	return tp, name, bs[:valueLen], 1 + nameLen + valueLen
+	return valueLen
 }
 
 func consumeCString(bs []byte) (value []byte, totalLen int) {
@@ -171,6 +499,15 @@ func consumeCString(bs []byte) (value []byte, totalLen int) {
 	return bs[:idx], idx + 1
 }
 
+// consumeString reads a length prefixed string which ends with a zero byte.
+func consumeString(bs []byte) (value []byte, totalLen int) {
+	n := int(consumeInt32(bs))
+	if n < 1 || len(bs)-4 < n {
+		return nil, 0
+	}
+	return bs[4 : 4+n-1], 4 + n
+}
+
 func consumeInt32(bs []byte) (value int32) {
 	if len(bs) < 4 {
 		return 0
@@ -180,27 +517,36 @@ func consumeInt32(bs []byte) (value int32) {
 
 // iterFields read through the binary data stored in r.Raw field-by-field.
 func (r Result) iterFields(resultSink func(key []byte, r Result) bool) (int, error) {
+	return r.iterFieldsPolicy(&defaultUnknownTypes, resultSink)
+}
+
+// iterFieldsPolicy is iterFields reading unknown types by the policy.
+func (r Result) iterFieldsPolicy(policy *UnknownTypePolicy, resultSink func(key []byte, r Result) bool) (int, error) {
 	var field Result
 	var consumedLength int
 	var bs []byte
 	if r.Type == BSONTypeObject || r.Type == BSONTypeArray {
-		totalLength := consumeInt32(r.Raw)
-		if len(r.Raw) < int(totalLength) {
+		// the terminating zero byte is checked, so a nested length shorter than its elements, which
+		// ends at the type of the following element, is an error rather than skipping the elements
+		var ok bool
+		if bs, ok = containerElements(r.Raw); !ok {
 			return 0, ErrInvalidLength
 		}
-		bs = r.Raw[4 : totalLength-1]
 	} else {
 		return 0, ErrNotObject
 	}
 	// fields are not organized in order, so we need to iterate through all fields
 	for len(bs) > 0 {
-		tp, name, value, totalLen := consumeElement(bs)
+		tp, name, value, totalLen := consumeElementPolicy(bs, policy)
 		if totalLen < 0 {
 			// error occurred when totalLen is negative
 			return consumedLength, ErrInvalidLength
 		}
 		bs = bs[totalLen:]
 		consumedLength += totalLen
+		if policy.Mode == UnknownTypeSkip && !isKnownType(tp) {
+			continue
+		}
 		field.Type = tp
 		field.Raw = value
 		if !resultSink(name, field) {
@@ -210,30 +556,169 @@ func (r Result) iterFields(resultSink func(key []byte, r Result) bool) (int, err
 	return consumedLength, nil
 }
 
-func bytesEqualToString(left []byte, right string) bool {
-	return *(*string)(unsafe.Pointer(&left)) == right
+// TypeString returns the name of the value type, see Type.String.
+func (r Result) TypeString() string {
+	return r.Type.String()
 }
 
 func (r Result) Exist() bool {
 	return r.Type != BSONTypeUndefined
 }
 
+// Missing reports whether the value is missing, the opposite of Exist. A stored undefined value,
+// which is deprecated, is taken as missing too.
+func (r Result) Missing() bool {
+	return r.Type == BSONTypeUndefined
+}
+
+// FieldState is the tri-state of a field for patch-style semantics, where a missing field keeps
+// the current value and a null one clears it.
+type FieldState uint8
+
+const (
+	FieldMissing FieldState = iota // the field is absent
+	FieldNull                      // the field is a bson null
+	FieldSet                       // the field has a value other than null
+)
+
+// State returns the tri-state of the value.
+func (r Result) State() FieldState {
+	switch r.Type {
+	case BSONTypeUndefined:
+		return FieldMissing
+	case BSONTypeNull:
+		return FieldNull
+	}
+	return FieldSet
+}
+
+// IsNull reports whether the value is a bson null.
+func (r Result) IsNull() bool {
+	return r.Type == BSONTypeNull
+}
+
+// IsNumber reports whether the value is an int32, int64, double or decimal128.
+func (r Result) IsNumber() bool {
+	switch r.Type {
+	case BSONTypeInt32, BSONTypeInt64, BSONTypeDouble, BSONTypeDecimal128:
+		return true
+	}
+	return false
+}
+
+// IsString reports whether the value has text returned by String,
+// which are the String, Symbol and JavaScript types.
+func (r Result) IsString() bool {
+	return r.Type == BSONTypeString || r.Type == BSONTypeSymbol || r.Type == BSONTypeJavaScript
+}
+
+// IsObject reports whether the value is an embedded document.
+func (r Result) IsObject() bool {
+	return r.Type == BSONTypeObject
+}
+
+// IsArray reports whether the value is an array.
+func (r Result) IsArray() bool {
+	return r.Type == BSONTypeArray
+}
+
+// IsContainer reports whether the value is a document or an array.
+func (r Result) IsContainer() bool {
+	return r.Type == BSONTypeObject || r.Type == BSONTypeArray
+}
+
+// String returns the text of String, Symbol and JavaScript values, which share the same layout.
+// It returns "" for the other types.
 func (r Result) String() string {
-	if r.Type == BSONTypeString {
-		return string(r.Raw[4 : len(r.Raw)-1])
+	if r.IsString() {
+		value, _ := consumeString(r.Raw)
+		if r.strict && !isStrictText(value) {
+			return ""
+		}
+		return string(value)
 	}
 	return ""
 }
 
+// isStrictText reports whether the text is valid UTF-8 without zero bytes.
+func isStrictText(s []byte) bool {
+	return utf8.Valid(s) && bytes.IndexByte(s, 0) < 0
+}
+
+// StringBytes is like String, but returns the text without copying.
+// The returned slice aliases the source buffer: it must not be modified,
+// and it's only valid as long as the source buffer isn't reused.
+func (r Result) StringBytes() []byte {
+	if r.IsString() {
+		value, _ := consumeString(r.Raw)
+		if r.strict && !isStrictText(value) {
+			return nil
+		}
+		return value
+	}
+	return nil
+}
+
+// Bytes returns a copy of the payload which is safe to retain after the source buffer is reused:
+// the text of string types, the data of binaries, the whole raw document of objects and arrays,
+// and the raw value bytes of the other types. It returns nil for missing results.
+func (r Result) Bytes() []byte {
+	var payload []byte
+	switch r.Type {
+	case BSONTypeString, BSONTypeSymbol, BSONTypeJavaScript:
+		payload = r.StringBytes()
+	case BSONTypeBinary:
+		_, payload = r.Binary()
+	default:
+		if !r.Exist() {
+			return nil
+		}
+		payload = r.Raw
+	}
+	return append(make([]byte, 0, len(payload)), payload...)
+}
+
 func (r Result) Bool() bool {
-	if r.Type == BSONTypeBoolean && r.Raw[0] == 0x01 {
+	if r.Type == BSONTypeBoolean && len(r.Raw) >= 1 && r.Raw[0] == 0x01 {
 		return true
 	}
 	return false
 }
 
+// Truthy coerces the value into a boolean for fields stored inconsistently as flags:
+// booleans are themselves, numbers are true if non-zero (NaN is false), strings parsed by
+// strconv.ParseBool like "true", "1" and "false" are their boolean values and the other non-empty
+// strings are true. Null, undefined and missing values are false, values of the other types are true.
+func (r Result) Truthy() bool {
+	switch r.Type {
+	case BSONTypeBoolean:
+		return r.Bool()
+	case BSONTypeInt32, BSONTypeInt64:
+		return r.Int64() != 0
+	case BSONTypeDouble:
+		f := r.Float64()
+		return f != 0 && !math.IsNaN(f)
+	case BSONTypeDecimal128:
+		d := r.Decimal128()
+		if d.IsNaN() {
+			return false
+		}
+		_, coefficient, _ := d.parts()
+		return d.IsInf(0) || coefficient.Sign() != 0
+	case BSONTypeString, BSONTypeSymbol:
+		s := r.String()
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+		return s != ""
+	case BSONTypeNull, BSONTypeUndefined:
+		return false
+	}
+	return true
+}
+
 func (r Result) Float64() float64 {
-	if r.Type == BSONTypeDouble {
+	if r.Type == BSONTypeDouble && len(r.Raw) >= 8 {
 		return math.Float64frombits(binary.LittleEndian.Uint64(r.Raw))
 	}
 	if r.Type == BSONTypeInt32 {
@@ -245,8 +730,21 @@ func (r Result) Float64() float64 {
 	return 0
 }
 
+// Float32 returns the value of double, int32 and int64 types as float32, rounded to the nearest.
+// Integers are converted directly rather than through float64 to avoid double rounding,
+// doubles beyond the float32 range become infinities.
+func (r Result) Float32() float32 {
+	switch r.Type {
+	case BSONTypeDouble:
+		return float32(r.Float64())
+	case BSONTypeInt32, BSONTypeInt64:
+		return float32(r.Int64())
+	}
+	return 0
+}
+
 func (r Result) Int32() int32 {
-	if r.Type == BSONTypeInt32 {
+	if r.Type == BSONTypeInt32 && len(r.Raw) >= 4 {
 		return int32(binary.LittleEndian.Uint32(r.Raw))
 	}
 	if r.Type == BSONTypeInt64 {
@@ -259,7 +757,7 @@ func (r Result) Int32() int32 {
 }
 
 func (r Result) Int64() int64 {
-	if r.Type == BSONTypeInt64 {
+	if r.Type == BSONTypeInt64 && len(r.Raw) >= 8 {
 		return int64(binary.LittleEndian.Uint64(r.Raw))
 	}
 	if r.Type == BSONTypeInt32 {
@@ -271,16 +769,101 @@ func (r Result) Int64() int64 {
 	return 0
 }
 
+// Int returns the value of int32, int64 and double types as a native int, coerced the same as Int64.
+func (r Result) Int() int {
+	return int(r.Int64())
+}
+
+// Uint64 returns the value of int32, int64 and double types as an unsigned integer.
+// Negative values return 0 instead of wrapping around, doubles are truncated toward zero
+// and saturated at math.MaxUint64.
+func (r Result) Uint64() uint64 {
+	if r.Type == BSONTypeDouble {
+		f := r.Float64()
+		if !(f > 0) { // negative or NaN
+			return 0
+		}
+		if f >= math.MaxUint64 {
+			return math.MaxUint64
+		}
+		return uint64(f)
+	}
+	if i := r.Int64(); i > 0 {
+		return uint64(i)
+	}
+	return 0
+}
+
+// Uint32 is like Uint64, but saturates at math.MaxUint32.
+func (r Result) Uint32() uint32 {
+	if u := r.Uint64(); u < math.MaxUint32 {
+		return uint32(u)
+	}
+	return math.MaxUint32
+}
+
+// Time returns the time of DateTime and Timestamp values in the local timezone.
+// DateTime values are converted without overflow for the whole int64 millisecond range.
 func (r Result) Time() time.Time {
-	if r.Type == BSONTypeDateTime {
-		return time.Unix(0, r.Int64()*int64(time.Millisecond))
+	if r.Type == BSONTypeDateTime && len(r.Raw) >= 8 {
+		ms := int64(binary.LittleEndian.Uint64(r.Raw))
+		return time.Unix(ms/1e3, ms%1e3*int64(time.Millisecond))
 	}
-	if r.Type == BSONTypeTimestamp {
+	if r.Type == BSONTypeTimestamp && len(r.Raw) >= 8 {
 		return time.Unix(int64(binary.LittleEndian.Uint32(r.Raw[4:8])), 0)
 	}
 	return time.Time{}
 }
 
+// TimeIn is like Time, but returns the time in the given location, nil means UTC.
+func (r Result) TimeIn(loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if t := r.Time(); !t.IsZero() {
+		return t.In(loc)
+	}
+	return time.Time{}
+}
+
+// UnixMilli returns the milliseconds since the Unix epoch of a DateTime value as stored, 0 for the other types.
+// Unlike Time().UnixMilli(), it's exact without going through time.Time, which overflows in
+// time.Time.UnixNano for dates out of the years 1678 to 2262.
+func (r Result) UnixMilli() int64 {
+	if r.Type == BSONTypeDateTime && len(r.Raw) >= 8 {
+		return int64(binary.LittleEndian.Uint64(r.Raw))
+	}
+	return 0
+}
+
+// Duration converts numeric values counted in the given unit into a duration,
+// e.g. r.Duration(time.Millisecond) for fields storing milliseconds.
+// Doubles keep their fractional part, results out of the duration range are saturated.
+func (r Result) Duration(unit time.Duration) time.Duration {
+	switch r.Type {
+	case BSONTypeDouble:
+		d := r.Float64() * float64(unit)
+		if d >= math.MaxInt64 {
+			return math.MaxInt64
+		}
+		if d <= math.MinInt64 {
+			return math.MinInt64
+		}
+		return time.Duration(d)
+	case BSONTypeInt32, BSONTypeInt64:
+		v := r.Int64()
+		d := time.Duration(v) * unit
+		if unit != 0 && int64(d/unit) != v {
+			if (v < 0) != (unit < 0) {
+				return math.MinInt64
+			}
+			return math.MaxInt64
+		}
+		return d
+	}
+	return 0
+}
+
 func (r Result) IterArray(consumer func(Result) bool) {
 	if r.Type != BSONTypeArray {
 		return
@@ -299,17 +882,57 @@ func (r Result) IterDocument(consumer func(key string, r Result) bool) {
 	})
 }
 
+// IterArrayE is like IterArray, but returns an error if the value is not an array, or is malformed,
+// so a truncated array could be told from a shorter one. The items before the malformed one are consumed.
+func (r Result) IterArrayE(consumer func(Result) bool) error {
+	if err := r.checkType(BSONTypeArray); err != nil {
+		return err
+	}
+	_, err := r.iterFields(func(_ []byte, r Result) bool {
+		return consumer(r)
+	})
+	return locateError(r.Raw, err)
+}
+
+// IterDocumentE is like IterDocument, but returns an error if the value is not a document, or is malformed.
+// The elements before the malformed one are consumed.
+func (r Result) IterDocumentE(consumer func(key string, r Result) bool) error {
+	if err := r.checkType(BSONTypeObject); err != nil {
+		return err
+	}
+	_, err := r.iterFields(func(key []byte, r Result) bool {
+		return consumer(string(key), r)
+	})
+	return locateError(r.Raw, err)
+}
+
 func (r Result) Array() []Result {
-	a := make([]Result, 0)
+	return r.AppendArray(make([]Result, 0))
+}
+
+// AppendArray appends the items of the array to dst and returns the extended slice, like Array but reusing
+// the slice in hot loops, e.g. a = r.AppendArray(a[:0]).
+func (r Result) AppendArray(dst []Result) []Result {
 	r.IterArray(func(r Result) bool {
-		a = append(a, r)
+		dst = append(dst, r)
 		return true
 	})
-	return a
+	return dst
 }
 
 func (r Result) Map() map[string]Result {
-	m := make(map[string]Result)
+	return r.MapInto(make(map[string]Result))
+}
+
+// MapInto clears m and fills it with the elements of the document like Map, reusing the map in hot loops.
+// If m is nil, a new map is returned.
+func (r Result) MapInto(m map[string]Result) map[string]Result {
+	if m == nil {
+		m = make(map[string]Result)
+	}
+	for key := range m {
+		delete(m, key)
+	}
 	r.IterDocument(func(key string, r Result) bool {
 		m[key] = r
 		return true
@@ -317,38 +940,46 @@ func (r Result) Map() map[string]Result {
 	return m
 }
 
+// SizedArray is Array with the capacity of size, e.g. the count of documents of the same shape reused.
+// If size is zero, the slice grows as the items are read in a single pass, the same as Array.
 func (r Result) SizedArray(size int) []Result {
-	if size == 0 {
-		size = r.Length()
-	}
-	a := make([]Result, 0, size)
-	r.IterArray(func(r Result) bool {
-		a = append(a, r)
-		return true
-	})
-	return a
+	return r.AppendArray(make([]Result, 0, size))
 }
 
+// SizedMap is Map with the size hint of size, which grows as the elements are read if zero, see SizedArray.
 func (r Result) SizedMap(size int) map[string]Result {
-	if size == 0 {
-		size = r.Length()
+	return r.MapInto(make(map[string]Result, size))
+}
+
+// LengthHint estimates the number of elements of the document or array by the length of its first element,
+// 0 for the other values. It's inexact unless the elements are of the same length, see Length.
+func (r Result) LengthHint() int {
+	elements, ok := containerElements(r.Raw)
+	if !r.IsContainer() || !ok || len(elements) == 0 {
+		return 0
 	}
-	m := make(map[string]Result, size)
-	r.IterDocument(func(key string, r Result) bool {
-		m[key] = r
-		return true
-	})
-	return m
+	_, _, _, n := consumeElement(elements)
+	if n <= 0 {
+		return 0
+	}
+	return (len(elements) + n - 1) / n
 }
 
+// Length returns the number of elements of the document or array, 0 for the other values.
+// It's a pass reading the lengths only.
 func (r Result) Length() int {
-	if r.Type == BSONTypeObject || r.Type == BSONTypeArray {
-		var count int
-		_, _ = r.iterFields(func(_ []byte, _ Result) bool {
-			count++
-			return true
-		})
-		return count
+	if !r.IsContainer() {
+		return 0
 	}
-	return 0
+	elements, _ := containerElements(r.Raw)
+	count := 0
+	for len(elements) > 0 {
+		_, _, _, n := consumeElement(elements)
+		if n < 0 {
+			break
+		}
+		elements = elements[n:]
+		count++
+	}
+	return count
 }
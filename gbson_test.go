@@ -1,12 +1,20 @@
 package gbson
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var (
@@ -48,6 +56,116 @@ func TestGet(t *testing.T) {
 		require.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, arr)
 	}
 	require.Equal(t, int64(48), Get(getTestLoad(), "value-48").Int64())
+	require.Equal(t, 48, Get(getTestLoad(), "value-48").Int())
+}
+
+func TestAppendArrayMapInto(t *testing.T) {
+	doc := mustMarshal(t, bson.D{{Key: "a", Value: bson.A{"x", "y"}}, {Key: "d", Value: bson.D{{Key: "k", Value: 1}}}})
+	items := Get(doc, "a").AppendArray(nil)
+	require.Equal(t, Get(doc, "a").Array(), items)
+	items = Get(doc, "a").AppendArray(items[:1])
+	require.Len(t, items, 3)
+	require.Equal(t, "y", items[2].String())
+	require.Empty(t, Get(doc, "d").AppendArray(nil))
+
+	m := Get(doc).MapInto(nil)
+	require.Equal(t, Get(doc).Map(), m)
+	m = Get(doc, "d").MapInto(m)
+	require.Equal(t, Get(doc, "d").Map(), m)
+}
+
+func TestGetPath(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	for _, path := range []string{"int32", "doc.b.1", "doc.b.2", "doc.missing", "doc..a", "", "doc.b.1.x"} {
+		require.Equal(t, Get(doc, strings.Split(path, ".")...), GetPath(doc, path), path)
+	}
+	deep := nestedDocument(20)
+	path := strings.Repeat("a.0.", 9) + "a"
+	require.Equal(t, Get(deep, strings.Split(path, ".")...), Get(deep).GetPath(path))
+	require.True(t, GetPath(deep, path).IsContainer())
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		GetPath(doc, "doc.b.1")
+	}))
+	deeper := strings.Repeat("a.0.", 15) + "a" // beyond 16 keys
+	deep = nestedDocument(40)
+	require.Equal(t, Get(deep, strings.Split(deeper, ".")...), GetPath(deep, deeper))
+	require.True(t, GetPath(deep, deeper).Exist())
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		GetPath(deep, deeper)
+	}))
+
+	dup := mustMarshal(t, bson.D{{Key: "a", Value: bson.D{{Key: "x", Value: 1}}}, {Key: "a", Value: bson.D{{Key: "b", Value: 2}}}})
+	require.Equal(t, Get(dup, "a", "b"), GetPath(dup, "a.b"))
+	r := GetPath(doc[:len(doc)-1], "max")
+	require.False(t, r.Exist())
+	require.ErrorIs(t, r.Err(), ErrInvalidLength)
+}
+
+func TestGetSorted(t *testing.T) {
+	doc, err := FromMap(map[string]interface{}{"b": int32(1), "d": bson.M{"x": "y"}, "f": true})
+	require.NoError(t, err)
+	for _, path := range [][]string{{}, {"a"}, {"b"}, {"c"}, {"d", "x"}, {"d", "z"}, {"f"}, {"g"}} {
+		require.Equal(t, Get(doc, path...), GetSorted(doc, path...), "%v", path)
+	}
+	unsorted := mustMarshal(t, bson.D{{Key: "b", Value: 1}, {Key: "a", Value: 2}})
+	require.False(t, GetSorted(unsorted, "a").Exist(), "stopped at b")
+	r := GetSorted(doc[:len(doc)-1], "b")
+	require.False(t, r.Exist())
+	require.ErrorIs(t, r.Err(), ErrInvalidLength)
+}
+
+func TestLength(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "ints", Value: bson.A{int32(1), int32(2), int32(3)}},
+		{Key: "mixed", Value: bson.A{int32(1), "long string", true}},
+		{Key: "empty", Value: bson.D{}},
+		{Key: "hundred", Value: make([]int32, 100)},
+	})
+	for key, hint := range map[string]int{"ints": 3, "mixed": 5, "empty": 0, "hundred": 113} {
+		r := Get(doc, key)
+		require.Equal(t, len(r.Array())+len(r.Map()), r.Length(), key)
+		require.Equal(t, hint, r.LengthHint(), key)
+		require.Equal(t, r.Length(), len(r.SizedArray(0))+len(r.SizedMap(0)), key)
+		require.Equal(t, r.Array(), r.SizedArray(0), key)
+		require.Equal(t, r.Map(), r.SizedMap(0), key)
+	}
+	require.Equal(t, 4, Get(doc).Length())
+	require.Zero(t, Get(doc, "ints", "0").Length())
+	require.Zero(t, Get(doc, "ints", "0").LengthHint())
+	require.Zero(t, Get(doc[:len(doc)-1]).Length())
+}
+
+func TestGetNoAllocs(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	path := []string{"doc", "b", "1"}
+	for name, get := range map[string]func(){
+		"key":      func() { Get(doc, "int32") },
+		"path":     func() { Get(doc, path...) },
+		"chained":  func() { Get(doc, "doc").Get("b", "1") },
+		"missing":  func() { Get(doc, "doc", "missing") },
+		"iterated": func() { _ = Get(doc).GetIter(func(Result) bool { return true }, path...) },
+	} {
+		require.Zero(t, testing.AllocsPerRun(100, get), name)
+	}
+}
+
+func TestUnsigned(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "int32", Value: int32(7)},
+		{Key: "negative", Value: int64(-1)},
+		{Key: "large", Value: int64(1) << 40},
+		{Key: "double", Value: 3.9},
+		{Key: "huge", Value: 1e30},
+		{Key: "string", Value: "1"},
+	})
+	require.Equal(t, uint64(7), Get(doc, "int32").Uint64())
+	require.Equal(t, uint64(0), Get(doc, "negative").Uint64())
+	require.Equal(t, uint32(0), Get(doc, "negative").Uint32())
+	require.Equal(t, uint64(1)<<40, Get(doc, "large").Uint64())
+	require.Equal(t, uint32(math.MaxUint32), Get(doc, "large").Uint32())
+	require.Equal(t, uint64(3), Get(doc, "double").Uint64())
+	require.Equal(t, uint64(math.MaxUint64), Get(doc, "huge").Uint64())
+	require.Equal(t, uint64(0), Get(doc, "string").Uint64())
 }
 
 func BenchmarkGetAllFields(b *testing.B) {
@@ -64,24 +182,72 @@ func BenchmarkGetAllFields(b *testing.B) {
 	})
 	b.Run("gbson get all", func(b *testing.B) {
 		// Gets all first level fields using gbson.Get
+		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
 			for _, elem := range d {
 				Get(load, elem.Key)
 			}
 		}
 	})
+	b.Run("gbson get path all", func(b *testing.B) {
+		// Gets all first level fields using gbson.GetPath
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, elem := range d {
+				GetPath(load, elem.Key)
+			}
+		}
+	})
 	b.Run("gbson get first", func(b *testing.B) {
 		// Gets the first single key with gbson.Get
+		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
 			Get(load, d[0].Key)
 		}
 	})
 	b.Run("gbson get last", func(b *testing.B) {
 		// Gets the last single key with gbson.Get
+		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
 			Get(load, d[len(d)-1].Key)
 		}
 	})
+	b.Run("gbson fields", func(b *testing.B) {
+		// Gets all first level fields in a single scan using gbson.Fields
+		for i := 0; i < b.N; i++ {
+			require.Equal(b, len(d), len(Fields(load)))
+		}
+	})
+	b.Run("gbson index get all", func(b *testing.B) {
+		// Indexes the document once and gets all first level fields using IndexedDocument.Get
+		for i := 0; i < b.N; i++ {
+			ix, _ := Index(load, false)
+			for _, elem := range d {
+				ix.Get(elem.Key)
+			}
+		}
+	})
+	b.Run("gbson iterate", func(b *testing.B) {
+		// Iterates all first level fields using gbson.IterDocument
+		for i := 0; i < b.N; i++ {
+			n := 0
+			Get(load).IterDocument(func(_ string, _ Result) bool {
+				n++
+				return true
+			})
+			require.Equal(b, len(d), n)
+		}
+	})
+	b.Run("gbson cursor", func(b *testing.B) {
+		// Iterates all first level fields using gbson.Cursor
+		for i := 0; i < b.N; i++ {
+			n := 0
+			for c := Get(load).Cursor(); c.Next(); {
+				n++
+			}
+			require.Equal(b, len(d), n)
+		}
+	})
 	b.Run("gbson map", func(b *testing.B) {
 		// Parse the document into a map[string]Result using gbson.Map
 		for i := 0; i < b.N; i++ {
@@ -89,6 +255,14 @@ func BenchmarkGetAllFields(b *testing.B) {
 			require.Equal(b, len(d), len(kvs))
 		}
 	})
+	b.Run("gbson map into", func(b *testing.B) {
+		// Parse the document into a reused map[string]Result using gbson.MapInto
+		kvs := make(map[string]Result)
+		for i := 0; i < b.N; i++ {
+			kvs = Get(load).MapInto(kvs)
+			require.Equal(b, len(d), len(kvs))
+		}
+	})
 	for _, multi := range []int{0, 1, 2} {
 		b.Run(fmt.Sprintf("gbson sized *%d map", multi), func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
@@ -98,3 +272,506 @@ func BenchmarkGetAllFields(b *testing.B) {
 		})
 	}
 }
+
+func TestInt(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{
+		{Key: "int32", Value: int32(-7)},
+		{Key: "int64", Value: int64(1) << 40},
+		{Key: "double", Value: 2.9},
+		{Key: "string", Value: "1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, -7, Get(doc, "int32").Int())
+	require.Equal(t, 1<<40, Get(doc, "int64").Int())
+	require.Equal(t, 2, Get(doc, "double").Int())
+	require.Equal(t, 0, Get(doc, "string").Int())
+}
+
+func TestTimeIn(t *testing.T) {
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	far := time.Date(9999, 12, 31, 23, 59, 59, 999e6, time.UTC)
+	doc, err := bson.Marshal(bson.D{
+		{Key: "date", Value: time.Date(2022, 11, 10, 0, 0, 0, 0, time.UTC)},
+		{Key: "far", Value: far},
+		{Key: "ancient", Value: primitive.DateTime(math.MinInt64)},
+	})
+	require.NoError(t, err)
+	tm := Get(doc, "date").TimeIn(shanghai)
+	require.Equal(t, 8, tm.Hour())
+	require.Equal(t, shanghai, tm.Location())
+	require.Equal(t, time.UTC, Get(doc, "date").TimeIn(nil).Location())
+	require.True(t, far.Equal(Get(doc, "far").Time()))
+	require.Equal(t, int64(math.MinInt64)/1e3-1, Get(doc, "ancient").Time().Unix())
+	require.True(t, Get(doc, "missing").TimeIn(shanghai).IsZero())
+}
+
+func TestUnixMilli(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "ancient", Value: primitive.DateTime(math.MinInt64)},
+		{Key: "future", Value: primitive.DateTime(math.MaxInt64)},
+		{Key: "date", Value: primitive.DateTime(1668000000123)},
+		{Key: "ts", Value: primitive.Timestamp{T: 1, I: 2}},
+	})
+	require.Equal(t, int64(math.MinInt64), Get(doc, "ancient").UnixMilli())
+	require.Equal(t, int64(math.MaxInt64), Get(doc, "future").UnixMilli())
+	require.Equal(t, Get(doc, "ancient").UnixMilli(), Get(doc, "ancient").Time().UnixMilli())
+	require.Equal(t, Get(doc, "future").UnixMilli(), Get(doc, "future").Time().UnixMilli())
+	ms, err := Get(doc, "date").UnixMilliE()
+	require.NoError(t, err)
+	require.Equal(t, int64(1668000000123), ms)
+	require.Zero(t, Get(doc, "ts").UnixMilli())
+	_, err = Get(doc, "ts").UnixMilliE()
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	_, err = Result{Type: BSONTypeDateTime, Raw: []byte{1}}.UnixMilliE()
+	require.ErrorIs(t, err, ErrInvalidLength)
+}
+
+func TestDuration(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{
+		{Key: "millis", Value: int64(1500)},
+		{Key: "seconds", Value: int32(3)},
+		{Key: "fraction", Value: 0.25},
+		{Key: "huge", Value: int64(math.MaxInt64 / 10)},
+		{Key: "string", Value: "1s"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1500*time.Millisecond, Get(doc, "millis").Duration(time.Millisecond))
+	require.Equal(t, 3*time.Second, Get(doc, "seconds").Duration(time.Second))
+	require.Equal(t, 250*time.Millisecond, Get(doc, "fraction").Duration(time.Second))
+	require.Equal(t, time.Duration(math.MaxInt64), Get(doc, "huge").Duration(time.Hour))
+	require.Equal(t, time.Duration(0), Get(doc, "string").Duration(time.Second))
+}
+
+func TestStringBytes(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{{Key: "s", Value: "text"}, {Key: "i", Value: 1}})
+	require.NoError(t, err)
+	bs := Get(doc, "s").StringBytes()
+	require.Equal(t, []byte("text"), bs)
+	bs[0] = 'n' // aliases the document
+	require.Equal(t, "next", Get(doc, "s").String())
+	require.Nil(t, Get(doc, "i").StringBytes())
+	require.Zero(t, testing.AllocsPerRun(10, func() { Get(doc, "s").StringBytes() }))
+}
+
+func TestBytes(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{
+		{Key: "s", Value: "text"},
+		{Key: "b", Value: []byte{1, 2}},
+		{Key: "d", Value: bson.D{{Key: "x", Value: 1}}},
+	})
+	require.NoError(t, err)
+	s := Get(doc, "s").Bytes()
+	require.Equal(t, []byte("text"), s)
+	s[0] = 'n'
+	require.Equal(t, "text", Get(doc, "s").String())
+	require.Equal(t, []byte{1, 2}, Get(doc, "b").Bytes())
+	sub := Get(doc, "d").Bytes()
+	require.Equal(t, int64(1), Get(sub, "x").Int64())
+	require.Nil(t, Get(doc, "missing").Bytes())
+}
+
+func TestPredicates(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{
+		{Key: "null", Value: nil},
+		{Key: "int", Value: 1},
+		{Key: "long", Value: int64(1)},
+		{Key: "double", Value: 1.5},
+		{Key: "decimal", Value: primitive.NewDecimal128(0, 1)},
+		{Key: "string", Value: "x"},
+		{Key: "symbol", Value: primitive.Symbol("x")},
+		{Key: "doc", Value: bson.D{}},
+		{Key: "array", Value: bson.A{}},
+	})
+	require.NoError(t, err)
+	require.True(t, Get(doc, "null").IsNull())
+	require.False(t, Get(doc, "missing").IsNull())
+	for _, key := range []string{"int", "long", "double", "decimal"} {
+		require.True(t, Get(doc, key).IsNumber(), key)
+	}
+	require.False(t, Get(doc, "string").IsNumber())
+	require.True(t, Get(doc, "string").IsString())
+	require.True(t, Get(doc, "symbol").IsString())
+	require.False(t, Get(doc, "int").IsString())
+	require.True(t, Get(doc, "doc").IsContainer())
+	require.True(t, Get(doc, "doc").IsObject())
+	require.True(t, Get(doc, "array").IsContainer())
+	require.True(t, Get(doc, "array").IsArray())
+	require.False(t, Get(doc, "null").IsContainer())
+}
+
+func TestFloat32(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{
+		{Key: "double", Value: 0.1},
+		{Key: "long", Value: int64(1)<<60 + 1},
+		{Key: "huge", Value: 1e300},
+		{Key: "string", Value: "1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, float32(0.1), Get(doc, "double").Float32())
+	require.Equal(t, float32(1<<60), Get(doc, "long").Float32())
+	require.True(t, math.IsInf(float64(Get(doc, "huge").Float32()), 1))
+	require.Equal(t, float32(0), Get(doc, "string").Float32())
+	features, ok := GetAs[[]float32](mustMarshal(t, bson.D{{Key: "v", Value: bson.A{1, 0.5}}}), "v")
+	require.True(t, ok)
+	require.Equal(t, []float32{1, 0.5}, features)
+}
+
+func TestTruthy(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{
+		{Key: "true", Value: true},
+		{Key: "false", Value: false},
+		{Key: "one", Value: 1},
+		{Key: "zero", Value: int64(0)},
+		{Key: "half", Value: 0.5},
+		{Key: "nan", Value: math.NaN()},
+		{Key: "yes", Value: "true"},
+		{Key: "no", Value: "false"},
+		{Key: "text", Value: "enabled"},
+		{Key: "empty", Value: ""},
+		{Key: "doc", Value: bson.D{}},
+		{Key: "null", Value: nil},
+		{Key: "decimal", Value: primitive.NewDecimal128(0, 0)},
+	})
+	require.NoError(t, err)
+	for _, key := range []string{"true", "one", "half", "yes", "text", "doc"} {
+		require.True(t, Get(doc, key).Truthy(), key)
+	}
+	for _, key := range []string{"false", "zero", "nan", "no", "empty", "null", "decimal", "missing"} {
+		require.False(t, Get(doc, key).Truthy(), key)
+	}
+}
+
+func TestTypeString(t *testing.T) {
+	require.Equal(t, "objectId", BSONTypeObjectID.String())
+	require.Equal(t, "long", BSONTypeInt64.String())
+	require.Equal(t, "minKey", BSONTypeMinKey.String())
+	require.Equal(t, "Type(0x14)", Type(0x14).String())
+	require.Equal(t, "type double", fmt.Sprintf("type %v", BSONTypeDouble))
+	doc := mustMarshal(t, bson.D{{Key: "a", Value: "x"}})
+	require.Equal(t, "string", Get(doc, "a").TypeString())
+	require.Equal(t, "undefined", Get(doc, "missing").TypeString())
+}
+
+func TestReadElement(t *testing.T) {
+	doc := mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}, {Key: "b", Value: bson.D{{Key: "c", Value: "x"}}}})
+	elements := doc[4 : len(doc)-1]
+	var names []string
+	for len(elements) > 0 {
+		tp, name, value, n, err := ReadElement(elements)
+		require.NoError(t, err)
+		require.Equal(t, Get(doc, string(name)), Result{Type: tp, Raw: value})
+		names = append(names, string(name))
+		elements = elements[n:]
+	}
+	require.Equal(t, []string{"a", "b"}, names)
+
+	_, _, _, _, err := ReadElement(doc[4 : len(doc)-2])
+	require.NoError(t, err)
+	_, _, _, _, err = ReadElement(doc[11 : len(doc)-2])
+	require.ErrorIs(t, err, ErrInvalidLength)
+	_, _, _, _, err = ReadElement(nil)
+	require.ErrorIs(t, err, ErrInvalidLength)
+	_, _, _, _, err = ReadElement([]byte{0x14, 'a', 0})
+	require.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+// nestedDocument returns a document of the depth, where documents and arrays alternate,
+// e.g. {"a": [{}]} of depth 3.
+func nestedDocument(depth int) []byte {
+	typeAt := func(d int) Type {
+		if d%2 == 1 {
+			return BSONTypeObject
+		}
+		return BSONTypeArray
+	}
+	doc := []byte{5, 0, 0, 0, 0}
+	for d := depth - 1; d >= 1; d-- {
+		key := "a"
+		if typeAt(d) == BSONTypeArray {
+			key = "0"
+		}
+		raw := append([]byte{0, 0, 0, 0, byte(typeAt(d + 1))}, key...)
+		raw = append(append(append(raw, 0), doc...), 0)
+		raw[0], raw[1], raw[2], raw[3] = byte(len(raw)), byte(len(raw)>>8), byte(len(raw)>>16), byte(len(raw)>>24)
+		doc = raw
+	}
+	return doc
+}
+
+func TestMaxDepth(t *testing.T) {
+	ok, deep := nestedDocument(DefaultMaxDepth), nestedDocument(DefaultMaxDepth+1)
+	keys := strings.Split(strings.TrimSuffix(strings.Repeat("a.0.", DefaultMaxDepth/2), "."), ".")
+	okJSON, err := ToJSON(ok)
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat(`{"a":[`, DefaultMaxDepth/2)+strings.Repeat(`]}`, DefaultMaxDepth/2), string(okJSON))
+
+	// paths
+	require.NoError(t, resultFromBytes(ok).GetIter(func(Result) bool { return true }, keys[:DefaultMaxDepth-1]...))
+	require.ErrorIs(t, resultFromBytes(ok).GetIter(func(Result) bool { return true }, append(keys, "a")...), ErrMaxDepth)
+
+	// validation
+	require.NoError(t, Validate(ok))
+	err = Validate(deep)
+	require.ErrorIs(t, err, ErrMaxDepth)
+	require.Equal(t, strings.Join(keys, "."), err.(Finding).Path)
+	require.NoError(t, Validator{MaxDepth: DefaultMaxDepth + 1}.Validate(deep))
+
+	// traversal
+	require.NoError(t, Walk(ok, &recordingHandler{}))
+	require.ErrorIs(t, Walk(deep, &recordingHandler{}), ErrMaxDepth)
+	er := NewElementReader(deep)
+	for er.Next() {
+		er.Enter()
+	}
+	require.ErrorIs(t, er.Err(), ErrMaxDepth)
+
+	// decoding
+	var v interface{}
+	require.NoError(t, Unmarshal(ok, &v))
+	require.ErrorIs(t, Unmarshal(deep, &v), ErrMaxDepth)
+	innermost := resultFromBytes(deep).Value()
+	for depth := 1; depth < DefaultMaxDepth; depth++ {
+		switch value := innermost.(type) {
+		case map[string]interface{}:
+			innermost = value["a"]
+		case []interface{}:
+			innermost = value[0]
+		}
+	}
+	require.Equal(t, Result{Type: BSONTypeObject, Raw: []byte{5, 0, 0, 0, 0}}, innermost.([]interface{})[0])
+	_, err = resultFromBytes(ok).ValueE()
+	require.NoError(t, err)
+	_, err = resultFromBytes(deep).ValueE()
+	require.ErrorIs(t, err, ErrMaxDepth)
+	_, err = ToStruct(deep)
+	require.ErrorIs(t, err, ErrMaxDepth)
+
+	// converters
+	_, err = ToJSON(deep)
+	require.ErrorIs(t, err, ErrMaxDepth)
+	_, err = ToYAML(deep)
+	require.ErrorIs(t, err, ErrMaxDepth)
+	_, err = ToMsgPack(deep)
+	require.ErrorIs(t, err, ErrMaxDepth)
+	_, err = ToGoLiteral(ok)
+	require.NoError(t, err)
+	_, err = ToGoLiteral(deep)
+	require.ErrorIs(t, err, ErrMaxDepth)
+	require.NoError(t, ExportCSV(bytes.NewReader(ok), io.Discard, nil))
+	require.ErrorIs(t, ExportCSV(bytes.NewReader(nestedDocument(DefaultMaxDepth+2)), io.Discard, nil), ErrMaxDepth, "empty containers are leaves")
+	_, err = resultFromBytes(ok).ToBSONDE()
+	require.NoError(t, err)
+	_, err = resultFromBytes(deep).ToBSONDE()
+	require.ErrorIs(t, err, ErrMaxDepth)
+	_, err = resultFromBytes(deep).ToBSONME()
+	require.ErrorIs(t, err, ErrMaxDepth)
+	require.Nil(t, resultFromBytes(deep).ToBSOND())
+	msgpack, err := ToMsgPack(ok)
+	require.NoError(t, err)
+	_, err = FromMsgPack(msgpack)
+	require.NoError(t, err)
+	_, err = FromMsgPack(append(bytes.Repeat([]byte{0x81, 0xa1, 'a', 0x91}, DefaultMaxDepth/2), 0x80))
+	require.ErrorIs(t, err, ErrMaxDepth)
+	_, err = FromJSON(okJSON)
+	require.NoError(t, err)
+	nestedJSON := func(value string) []byte { // the value at DefaultMaxDepth
+		return []byte(strings.Repeat(`{"a":[`, DefaultMaxDepth/2-1) + `{"a":` + value + `}` + strings.Repeat(`]}`, DefaultMaxDepth/2-1))
+	}
+	_, err = FromJSON(nestedJSON(`[]`))
+	require.NoError(t, err)
+	_, err = FromJSON(nestedJSON(`[{}]`))
+	require.ErrorIs(t, err, ErrMaxDepth)
+	_, err = FromJSON(nestedJSON(`{"$oid":"0123456789abcdef01234567"}`))
+	require.NoError(t, err)
+	_, err = FromJSON(nestedJSON(`{"$code":"","$scope":{"x":[]}}`))
+	require.ErrorIs(t, err, ErrMaxDepth)
+}
+
+// callAccessors calls every method of the result without arguments.
+func callAccessors(r Result) {
+	rt := reflect.TypeOf(r)
+	for i := 0; i < rt.NumMethod(); i++ {
+		if m := rt.Method(i); m.Type.NumIn() == 1 {
+			m.Func.Call([]reflect.Value{reflect.ValueOf(r)})
+		}
+	}
+}
+
+// exerciseDocument calls the functions accepting documents on the possibly malformed document.
+func exerciseDocument(doc []byte) {
+	_, _ = Repair(doc)
+	_ = HasDuplicateKeys(doc, true)
+	for _, convert := range []func([]byte) ([]byte, error){ToJSON, ToCanonicalJSON, ToYAML, ToMsgPack, ToGoLiteral} {
+		_, _ = convert(doc)
+	}
+	_, _ = ToStruct(doc)
+	_ = Walk(doc, &recordingHandler{})
+	er := NewElementReader(doc)
+	for er.Next() {
+		er.Enter()
+	}
+	var v interface{}
+	_ = Unmarshal(doc, &v)
+	_, _ = ReadGridFSFile(doc)
+	_ = Get(doc, "doc", "b", "1")
+	Get(doc).IterDocument(func(_ string, r Result) bool {
+		_ = r.Get("a")
+		_ = r.Map()
+		return true
+	})
+}
+
+func TestIterE(t *testing.T) {
+	doc := mustMarshal(t, bson.D{{Key: "a", Value: bson.A{"x", "y"}}, {Key: "b", Value: bson.A{}}})
+	var items []string
+	collect := func(r Result) bool {
+		items = append(items, r.Str())
+		return true
+	}
+	require.NoError(t, Get(doc, "a").IterArrayE(collect))
+	require.Equal(t, []string{"x", "y"}, items)
+	items = nil
+	require.NoError(t, Get(doc, "b").IterArrayE(collect))
+	require.Empty(t, items)
+	require.ErrorIs(t, Get(doc, "c").IterArrayE(collect), ErrNotExist)
+	require.ErrorIs(t, Get(doc).IterArrayE(collect), ErrTypeMismatch)
+	require.ErrorIs(t, Get(doc, "a").IterDocumentE(nil), ErrTypeMismatch)
+
+	var keys []string
+	require.NoError(t, Get(doc).IterDocumentE(func(key string, _ Result) bool {
+		keys = append(keys, key)
+		return false
+	}))
+	require.Equal(t, []string{"a"}, keys)
+
+	// the length of "y" exceeds the array, which looks shorter to IterArray
+	a := Get(doc, "a")
+	a.Raw[16] = 100
+	items = nil
+	a.IterArray(collect)
+	require.Equal(t, []string{"x"}, items)
+	items = nil
+	err := a.IterArrayE(collect)
+	require.Equal(t, []string{"x"}, items)
+	require.ErrorIs(t, err, ErrInvalidLength)
+	var f Finding
+	require.True(t, errors.As(err, &f))
+	require.Equal(t, "1", f.Path)
+	require.Equal(t, 16, f.Offset)
+}
+
+func TestResultErr(t *testing.T) {
+	doc := mustMarshal(t, bson.D{{Key: "s", Value: "x"}, {Key: "a", Value: bson.D{{Key: "b", Value: int64(1)}}}})
+	require.NoError(t, Get(doc, "a").Err())
+	require.NoError(t, Get(doc, "a", "b").Err())
+	require.NoError(t, Get(doc, "c").Err())
+	require.NoError(t, Get(doc, "s", "b").Err(), "a missing value of a string")
+	b, err := Get(doc, "a").Get("b").Int64E()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), b)
+
+	// the length of "s" exceeds the document
+	bad := append([]byte(nil), doc...)
+	bad[7] = 100
+	r := Get(bad, "a").Get("b")
+	require.False(t, r.Exist())
+	require.ErrorIs(t, r.Err(), ErrInvalidLength)
+	var f Finding
+	require.True(t, errors.As(r.Err(), &f))
+	require.Equal(t, "s", f.Path)
+	require.Equal(t, 7, f.Offset)
+	_, err = r.Int64E()
+	require.Equal(t, r.Err(), err)
+	require.Equal(t, r.Err(), r.GetIter(func(Result) bool { return true }, "b"))
+
+	require.ErrorIs(t, Get(doc, make([]string, DefaultMaxDepth+1)...).Err(), ErrMaxDepth)
+	require.ErrorIs(t, Result{Type: BSONTypeInt64, Raw: []byte{1}}.Err(), ErrInvalidLength)
+	require.ErrorIs(t, Result{Type: BSONTypeString, Raw: []byte{2, 0, 0, 0, 'x', 'y'}}.Err(), ErrInvalidLength)
+	require.ErrorIs(t, Result{Type: BSONTypeObject, Raw: []byte{5, 0, 0, 0, 1}}.Err(), ErrInvalidLength)
+	require.NoError(t, Result{Type: Type(0x20)}.Err())
+}
+
+func TestShortNestedLength(t *testing.T) {
+	doc := mustMarshal(t, bson.D{{Key: "d", Value: bson.D{{Key: "a", Value: int32(1)}, {Key: "b", Value: int32(2)}}}, {Key: "c", Value: int32(3)}})
+	doc[7] = 12 // d ends at the type of b, which would be skipped
+	r := Get(doc, "d", "b")
+	require.False(t, r.Exist())
+	require.ErrorIs(t, r.Err(), ErrInvalidLength)
+	require.Equal(t, Finding{Offset: 18, Path: "d", Reason: "document is not terminated by a zero byte", Cause: ErrInvalidLength}, Validate(doc))
+	require.ErrorIs(t, Get(doc, "d").IterDocumentE(func(string, Result) bool { return true }), ErrInvalidLength)
+}
+
+func TestMaxGetElements(t *testing.T) {
+	var d bson.D
+	for i := 0; i < 10; i++ {
+		d = append(d, bson.E{Key: fmt.Sprint("k", i), Value: bson.D{{Key: "a", Value: int32(i)}, {Key: "b", Value: int32(i)}}})
+	}
+	doc := mustMarshal(t, d)
+	limited := GetOptions{MaxElements: 7} // k0 to k4, a and b
+	require.Equal(t, int32(4), GetLimited(doc, limited, "k4", "b").Int32())
+	r := GetLimited(doc, limited, "k5", "b")
+	require.False(t, r.Exist())
+	require.ErrorIs(t, r.Err(), ErrBudgetExceeded)
+	require.ErrorIs(t, GetLimited(doc, limited, "k9").Err(), ErrBudgetExceeded)
+	require.Equal(t, int32(9), Get(doc, "k9", "b").Int32())
+}
+
+func TestMalformedNoPanic(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	// accessors on values of every type cut to every length
+	Get(doc).IterDocument(func(_ string, r Result) bool {
+		for n := 0; n <= len(r.Raw); n++ {
+			require.NotPanics(t, func() { callAccessors(Result{Type: r.Type, Raw: r.Raw[:n]}) }, "%v of %d bytes", r.Type, n)
+		}
+		return true
+	})
+	// documents truncated or with a byte changed at every position
+	for n := 0; n <= len(doc); n++ {
+		require.NotPanics(t, func() { exerciseDocument(doc[:n]) }, "truncated to %d bytes", n)
+	}
+	for i := range doc {
+		for _, b := range []byte{0, 0xff} {
+			bad := append([]byte(nil), doc...)
+			bad[i] = b
+			require.NotPanics(t, func() { exerciseDocument(bad) }, "byte %d changed to %d", i, b)
+		}
+	}
+}
+
+func FuzzMalformed(f *testing.F) {
+	doc := getTestJSONDocument(f)
+	f.Add(doc)
+	f.Add(doc[:len(doc)/2])
+	f.Fuzz(func(t *testing.T, doc []byte) {
+		exerciseDocument(doc)
+		callAccessors(Get(doc))
+	})
+}
+
+func TestUnknownTypes(t *testing.T) {
+	doc := mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}, {Key: "x", Value: "future"}, {Key: "b", Value: int32(2)}})
+	doc[11] = 0x20 // x is of an unknown type laid out like strings
+	policy := &UnknownTypePolicy{}
+	opts := GetOptions{UnknownTypes: policy}
+
+	require.False(t, Get(doc, "b").Exist())
+	policy.Mode = UnknownTypeSkip
+	require.False(t, GetLimited(doc, opts, "b").Exist(), "lengths are not derivable without Length")
+	policy.Length = func(tp Type, value []byte) int {
+		return int(consumeInt32(value)) + 4
+	}
+	require.Equal(t, int32(2), GetLimited(doc, opts, "b").Int32())
+	require.False(t, GetLimited(doc, opts, "x").Exist())
+	require.False(t, Get(doc, "b").Exist(), "the policy applies to the call only")
+
+	policy.Mode = UnknownTypeOpaque
+	x := GetLimited(doc, opts, "x")
+	require.Equal(t, Type(0x20), x.Type)
+	require.Equal(t, appendString(nil, "future"), x.Raw)
+	require.Equal(t, int32(2), GetLimited(doc, opts, "b").Int32())
+
+	require.ErrorIs(t, Walk(doc, &recordingHandler{}), ErrInvalidLength)
+	_, err := ToJSON(doc)
+	require.Error(t, err)
+	require.ErrorIs(t, Validate(doc), ErrUnsupportedType)
+}
@@ -0,0 +1,185 @@
+package gbson
+
+import "time"
+
+// Convertible is the type set of the types As converts values into, so converting into other types fails to
+// compile rather than looking like a missing or mismatched value.
+type Convertible interface {
+	string | bool | int | int32 | int64 | uint32 | uint64 | float32 | float64 | time.Time | []byte | [12]byte |
+		Decimal128 | Result | []string | []bool | []int | []int32 | []int64 | []uint32 | []uint64 | []float32 |
+		[]float64 | []time.Time | [][]byte | [][12]byte | []Decimal128 | []Result
+}
+
+// GetAs gets the first value by the given path and converts it into T, see As for the supported types.
+func GetAs[T Convertible](doc []byte, path ...string) (T, bool) {
+	return As[T](Get(doc, path...))
+}
+
+// As converts the result into T, which could be one of
+// string, bool, int, int32, int64, uint32, uint64, float32, float64, time.Time, []byte (binary data),
+// [12]byte (ObjectID), Decimal128, Result, or slices of them which are decoded from arrays, see Convertible.
+// Values are coerced the same as the error-returning accessors like Int64E,
+// ok is false if the value is missing or mismatched, or the array is malformed.
+// The accessor is picked by T without boxing the value, so converting into the scalar types allocates nothing.
+func As[T Convertible](r Result) (v T, ok bool) {
+	v, err := converterOf[T]()(r)
+	return v, err == nil
+}
+
+// AsField is As with the tri-state of the value for patch-style semantics: v is converted only if the state
+// is FieldSet, and ok is false if it could not be converted. Missing and null values are ok.
+//
+//	name, state, ok := gbson.AsField[string](patch.Get("name"))
+//	switch {
+//	case !ok:
+//		return errInvalidPatch
+//	case state == gbson.FieldNull:
+//		user.Name = "" // clear
+//	case state == gbson.FieldSet:
+//		user.Name = name
+//	}
+func AsField[T Convertible](r Result) (v T, state FieldState, ok bool) {
+	if state = r.State(); state != FieldSet {
+		return v, state, true
+	}
+	v, ok = As[T](r)
+	return v, state, ok
+}
+
+// converterOf returns the accessor converting results into T. The case is selected by a nil *T, and the
+// accessors are static functions, so neither is allocated.
+func converterOf[T Convertible]() func(Result) (T, error) {
+	var get interface{}
+	switch any((*T)(nil)).(type) {
+	case *string:
+		get = Result.StringE
+	case *bool:
+		get = Result.BoolE
+	case *int:
+		get = Result.IntE
+	case *int32:
+		get = Result.Int32E
+	case *int64:
+		get = Result.Int64E
+	case *uint32:
+		get = asUint32
+	case *uint64:
+		get = asUint64
+	case *float32:
+		get = asFloat32
+	case *float64:
+		get = Result.Float64E
+	case *time.Time:
+		get = Result.TimeE
+	case *[]byte:
+		get = asBytes
+	case *[12]byte:
+		get = Result.ObjectIDE
+	case *Decimal128:
+		get = asDecimal128
+	case *Result:
+		get = asExisting
+	case *[]string:
+		get = sliceOf(Result.StringE)
+	case *[]bool:
+		get = sliceOf(Result.BoolE)
+	case *[]int:
+		get = sliceOf(Result.IntE)
+	case *[]int32:
+		get = sliceOf(Result.Int32E)
+	case *[]int64:
+		get = sliceOf(Result.Int64E)
+	case *[]uint32:
+		get = sliceOf(asUint32)
+	case *[]uint64:
+		get = sliceOf(asUint64)
+	case *[]float32:
+		get = sliceOf(asFloat32)
+	case *[]float64:
+		get = sliceOf(Result.Float64E)
+	case *[]time.Time:
+		get = sliceOf(Result.TimeE)
+	case *[][]byte:
+		get = sliceOf(asBytes)
+	case *[][12]byte:
+		get = sliceOf(Result.ObjectIDE)
+	case *[]Decimal128:
+		get = sliceOf(asDecimal128)
+	case *[]Result:
+		get = sliceOf(asResult)
+	}
+	return get.(func(Result) (T, error))
+}
+
+// sliceOf returns the accessor converting arrays into slices of the items converted by get.
+func sliceOf[E any](get func(Result) (E, error)) func(Result) ([]E, error) {
+	return func(r Result) ([]E, error) {
+		s := make([]E, 0)
+		var err error
+		iterErr := r.IterArrayE(func(item Result) bool {
+			var v E
+			if v, err = get(item); err != nil {
+				return false
+			}
+			s = append(s, v)
+			return true
+		})
+		if iterErr != nil {
+			return nil, iterErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+}
+
+func asUint32(r Result) (uint32, error) {
+	if _, err := r.Int64E(); err != nil {
+		return 0, err
+	}
+	return r.Uint32(), nil
+}
+
+func asUint64(r Result) (uint64, error) {
+	if _, err := r.Int64E(); err != nil {
+		return 0, err
+	}
+	return r.Uint64(), nil
+}
+
+func asFloat32(r Result) (float32, error) {
+	if _, err := r.Float64E(); err != nil {
+		return 0, err
+	}
+	return r.Float32(), nil
+}
+
+func asBytes(r Result) ([]byte, error) {
+	if err := r.checkType(BSONTypeBinary); err != nil {
+		return nil, err
+	}
+	_, data := r.BinaryCopy()
+	return data, nil
+}
+
+func asDecimal128(r Result) (Decimal128, error) {
+	if err := r.checkType(BSONTypeDecimal128); err != nil {
+		return Decimal128{}, err
+	}
+	if err := r.checkLength(); err != nil {
+		return Decimal128{}, err
+	}
+	return r.Decimal128(), nil
+}
+
+func asResult(r Result) (Result, error) {
+	return r, nil
+}
+
+func asExisting(r Result) (Result, error) {
+	if !r.Exist() {
+		return r, ErrNotExist
+	}
+	return r, nil
+}
@@ -0,0 +1,99 @@
+package gbson
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGetAs(t *testing.T) {
+	oid := primitive.NewObjectID()
+	when := time.UnixMilli(1668000000123)
+	doc := mustMarshal(t, bson.D{
+		{Key: "_id", Value: oid},
+		{Key: "name", Value: "gbson"},
+		{Key: "count", Value: int32(3)},
+		{Key: "ratio", Value: 0.5},
+		{Key: "when", Value: when},
+		{Key: "data", Value: []byte{1}},
+		{Key: "tags", Value: bson.A{"a", "b"}},
+		{Key: "scores", Value: bson.A{int32(1), int64(2), 3.0}},
+		{Key: "mixed", Value: bson.A{1, "x"}},
+		{Key: "nested", Value: bson.D{{Key: "flag", Value: true}}},
+	})
+
+	name, ok := GetAs[string](doc, "name")
+	require.True(t, ok)
+	require.Equal(t, "gbson", name)
+	count, ok := GetAs[int](doc, "count")
+	require.True(t, ok)
+	require.Equal(t, 3, count)
+	ratio, ok := GetAs[float64](doc, "ratio")
+	require.True(t, ok)
+	require.Equal(t, 0.5, ratio)
+	tm, ok := GetAs[time.Time](doc, "when")
+	require.True(t, ok)
+	require.True(t, when.Equal(tm))
+	id, ok := GetAs[[12]byte](doc, "_id")
+	require.True(t, ok)
+	require.Equal(t, [12]byte(oid), id)
+	data, ok := GetAs[[]byte](doc, "data")
+	require.True(t, ok)
+	require.Equal(t, []byte{1}, data)
+	flag, ok := GetAs[bool](doc, "nested", "flag")
+	require.True(t, ok)
+	require.True(t, flag)
+	tags, ok := GetAs[[]string](doc, "tags")
+	require.True(t, ok)
+	require.Equal(t, []string{"a", "b"}, tags)
+	scores, ok := GetAs[[]int64](doc, "scores")
+	require.True(t, ok)
+	require.Equal(t, []int64{1, 2, 3}, scores)
+
+	_, ok = GetAs[[]int](doc, "mixed")
+	require.False(t, ok)
+	_, ok = GetAs[string](doc, "count")
+	require.False(t, ok)
+	_, ok = GetAs[int](doc, "missing")
+	require.False(t, ok)
+	missing, ok := GetAs[Result](doc, "missing")
+	require.False(t, ok)
+	require.False(t, missing.Exist())
+
+	malformed := append([]byte{}, doc...)
+	tagsAt := bytes.Index(malformed, []byte("tags\x00")) + 5
+	malformed[tagsAt+4+9+3] = 0x7f // the second item of tags exceeds the array
+	_, ok = GetAs[[]string](malformed, "tags")
+	require.False(t, ok, "malformed arrays are not converted partially")
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		GetAs[int64](doc, "count")
+		GetAs[time.Time](doc, "when")
+		GetAs[Result](doc, "nested")
+	}))
+}
+
+func TestAsField(t *testing.T) {
+	patch := resultFromBytes(mustMarshal(t, bson.D{
+		{Key: "name", Value: "x"},
+		{Key: "email", Value: nil},
+		{Key: "age", Value: "old"},
+	}))
+	require.Equal(t, FieldSet, patch.Get("name").State())
+	require.Equal(t, FieldNull, patch.Get("email").State())
+	require.Equal(t, FieldMissing, patch.Get("phone").State())
+	require.True(t, patch.Get("phone").Missing())
+	require.False(t, patch.Get("email").Missing())
+
+	name, state, ok := AsField[string](patch.Get("name"))
+	require.Equal(t, []interface{}{"x", FieldSet, true}, []interface{}{name, state, ok})
+	name, state, ok = AsField[string](patch.Get("email"))
+	require.Equal(t, []interface{}{"", FieldNull, true}, []interface{}{name, state, ok})
+	name, state, ok = AsField[string](patch.Get("phone"))
+	require.Equal(t, []interface{}{"", FieldMissing, true}, []interface{}{name, state, ok})
+	age, state, ok := AsField[int](patch.Get("age"))
+	require.Equal(t, []interface{}{0, FieldSet, false}, []interface{}{age, state, ok})
+}
@@ -0,0 +1,137 @@
+package gbson
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// GridFS stores a file as a document of the fs.files collection and its content split into documents of
+// the fs.chunks collection. See https://www.mongodb.com/docs/manual/core/gridfs/
+
+// GridFSFile is a document of the fs.files collection, results refer to the bytes of the document.
+type GridFSFile struct {
+	ID         Result
+	Length     int64
+	ChunkSize  int32
+	UploadDate time.Time
+	Filename   string
+	Metadata   Result // missing if the file has no metadata
+}
+
+// ReadGridFSFile reads a document of the fs.files collection, "_id", "length" and "chunkSize" are required.
+func ReadGridFSFile(doc []byte) (file GridFSFile, err error) {
+	r := resultFromBytes(doc)
+	if file.ID = r.Get("_id"); !file.ID.Exist() {
+		return file, errors.Wrap(ErrNotExist, "_id")
+	}
+	if file.Length, err = r.Get("length").Int64Checked(); err != nil {
+		return file, errors.WithMessage(err, "length")
+	}
+	if file.ChunkSize, err = r.Get("chunkSize").Int32Checked(); err != nil {
+		return file, errors.WithMessage(err, "chunkSize")
+	}
+	if file.Length < 0 || file.ChunkSize <= 0 {
+		return file, errors.Wrapf(ErrInvalidLength, "length %d and chunkSize %d", file.Length, file.ChunkSize)
+	}
+	if uploadDate := r.Get("uploadDate"); uploadDate.Exist() {
+		file.UploadDate = uploadDate.TimeIn(time.UTC)
+	}
+	file.Filename = r.Get("filename").String()
+	file.Metadata = r.Get("metadata")
+	return file, nil
+}
+
+// NumChunks returns the number of chunks of the file content.
+func (f GridFSFile) NumChunks() int64 {
+	return (f.Length + int64(f.ChunkSize) - 1) / int64(f.ChunkSize)
+}
+
+// GridFSChunk is a document of the fs.chunks collection, results refer to the bytes of the document.
+type GridFSChunk struct {
+	FilesID Result
+	N       int32
+	Data    []byte
+}
+
+// ReadGridFSChunk reads a document of the fs.chunks collection, "files_id", "n" and "data" are required.
+func ReadGridFSChunk(doc []byte) (chunk GridFSChunk, err error) {
+	r := resultFromBytes(doc)
+	if chunk.FilesID = r.Get("files_id"); !chunk.FilesID.Exist() {
+		return chunk, errors.Wrap(ErrNotExist, "files_id")
+	}
+	if chunk.N, err = r.Get("n").Int32Checked(); err != nil {
+		return chunk, errors.WithMessage(err, "n")
+	}
+	data := r.Get("data")
+	if data.Type != BSONTypeBinary {
+		return chunk, errors.Wrapf(ErrTypeMismatch, "data of type %v", data.Type)
+	}
+	_, chunk.Data = data.Binary()
+	return chunk, nil
+}
+
+// gridFSReader reads the content of a file from a stream of chunks.
+type gridFSReader struct {
+	file   GridFSFile
+	chunks *Decoder
+	n      int64  // index of the next chunk
+	doc    []byte // buffer of the current chunk document
+	data   []byte // unread data of the current chunk
+}
+
+// NewGridFSReader returns a reader of the file content, stitching the chunks read from a stream of
+// fs.chunks documents, such as a dump sorted by files_id and n. Chunks of the other files are skipped.
+// It's an error if a chunk is missing or its size doesn't match the chunkSize and the length of the file.
+func NewGridFSReader(file GridFSFile, chunks io.Reader) io.Reader {
+	return &gridFSReader{file: file, chunks: NewDecoder(chunks)}
+}
+
+func (g *gridFSReader) Read(p []byte) (int, error) {
+	for len(g.data) == 0 {
+		if g.n == g.file.NumChunks() {
+			return 0, io.EOF
+		}
+		if err := g.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, g.data)
+	g.data = g.data[n:]
+	return n, nil
+}
+
+// nextChunk reads the chunk g.n of the file.
+func (g *gridFSReader) nextChunk() error {
+	for {
+		var err error
+		if g.doc, err = g.chunks.readDocument(g.doc); err != nil {
+			if err == io.EOF {
+				return errors.Wrapf(io.ErrUnexpectedEOF, "chunk %d is missing", g.n)
+			}
+			return err
+		}
+		chunk, err := ReadGridFSChunk(g.doc)
+		if err != nil {
+			return errors.WithMessagef(err, "chunk document %d", g.chunks.count-1)
+		}
+		if chunk.FilesID.Type != g.file.ID.Type || !bytes.Equal(chunk.FilesID.Raw, g.file.ID.Raw) {
+			continue
+		}
+		if int64(chunk.N) != g.n {
+			return errors.Wrapf(ErrInvalidLength, "chunk %d is missing, got chunk %d", g.n, chunk.N)
+		}
+		size := int64(g.file.ChunkSize)
+		if rest := g.file.Length - g.n*size; rest < size {
+			size = rest
+		}
+		if int64(len(chunk.Data)) != size {
+			return errors.Wrapf(ErrInvalidLength, "chunk %d has %d bytes, expects %d", g.n, len(chunk.Data), size)
+		}
+		g.n++
+		g.data = chunk.Data
+		return nil
+	}
+}
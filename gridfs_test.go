@@ -0,0 +1,81 @@
+package gbson
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGridFS(t *testing.T) {
+	id := primitive.NewObjectID()
+	uploaded := time.Date(2022, 11, 10, 1, 2, 3, 0, time.UTC)
+	file, err := ReadGridFSFile(mustMarshal(t, bson.D{
+		{Key: "_id", Value: id},
+		{Key: "length", Value: int64(10)},
+		{Key: "chunkSize", Value: int32(4)},
+		{Key: "uploadDate", Value: uploaded},
+		{Key: "filename", Value: "a.txt"},
+		{Key: "metadata", Value: bson.D{{Key: "owner", Value: "x"}}},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, id.Hex(), file.ID.ObjectIDHex())
+	require.Equal(t, int64(10), file.Length)
+	require.Equal(t, int32(4), file.ChunkSize)
+	require.Equal(t, uploaded, file.UploadDate)
+	require.Equal(t, "a.txt", file.Filename)
+	require.Equal(t, "x", file.Metadata.Get("owner").String())
+	require.Equal(t, int64(3), file.NumChunks())
+
+	content := []byte("0123456789")
+	chunk := func(filesID interface{}, n int, data []byte) []byte {
+		return mustMarshal(t, bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "files_id", Value: filesID},
+			{Key: "n", Value: int32(n)},
+			{Key: "data", Value: primitive.Binary{Data: data}},
+		})
+	}
+	var stream []byte
+	stream = append(stream, chunk("other", 0, []byte("zz"))...)
+	stream = append(stream, chunk(id, 0, content[0:4])...)
+	stream = append(stream, chunk(id, 1, content[4:8])...)
+	stream = append(stream, chunk("other", 1, []byte("zz"))...)
+	stream = append(stream, chunk(id, 2, content[8:])...)
+	got, err := io.ReadAll(NewGridFSReader(file, bytes.NewReader(stream)))
+	require.NoError(t, err)
+	require.Equal(t, content, got)
+
+	c, err := ReadGridFSChunk(chunk(id, 2, content[8:]))
+	require.NoError(t, err)
+	require.Equal(t, int32(2), c.N)
+	require.Equal(t, content[8:], c.Data)
+
+	// missing chunks and wrong sizes
+	stream = append(chunk(id, 0, content[0:4]), chunk(id, 2, content[8:])...)
+	_, err = io.ReadAll(NewGridFSReader(file, bytes.NewReader(stream)))
+	require.ErrorIs(t, err, ErrInvalidLength)
+	stream = append(chunk(id, 0, content[0:4]), chunk(id, 1, content[4:8])...)
+	_, err = io.ReadAll(NewGridFSReader(file, bytes.NewReader(stream)))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	stream = append(chunk(id, 0, content[0:4]), chunk(id, 1, content[4:9])...)
+	_, err = io.ReadAll(NewGridFSReader(file, bytes.NewReader(stream)))
+	require.ErrorIs(t, err, ErrInvalidLength)
+
+	// empty files have no chunks
+	file.Length = 0
+	got, err = io.ReadAll(NewGridFSReader(file, bytes.NewReader(nil)))
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	_, err = ReadGridFSFile(mustMarshal(t, bson.D{{Key: "_id", Value: 1}, {Key: "length", Value: 1.5}, {Key: "chunkSize", Value: 1}}))
+	require.ErrorIs(t, err, ErrLossyConversion)
+	_, err = ReadGridFSFile(mustMarshal(t, bson.D{{Key: "length", Value: 1}}))
+	require.ErrorIs(t, err, ErrNotExist)
+	_, err = ReadGridFSChunk(mustMarshal(t, bson.D{{Key: "files_id", Value: 1}, {Key: "n", Value: 0}, {Key: "data", Value: "x"}}))
+	require.ErrorIs(t, err, ErrTypeMismatch)
+}
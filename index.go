@@ -0,0 +1,64 @@
+package gbson
+
+// IndexedDocument is a document scanned once into a table of its elements, serving lookups of many fields
+// of the same document without rescanning it from the top for each, as Get does. It refers to the document
+// without copying, which must not be modified while indexed.
+type IndexedDocument struct {
+	root indexNode
+}
+
+// indexNode is an indexed value, fields are the first values of the keys of an indexed document or array.
+type indexNode struct {
+	value  Result
+	fields map[string]*indexNode
+}
+
+// Index scans the document into an IndexedDocument. If recursive is true, embedded documents and arrays
+// are indexed too, otherwise lookups of nested paths scan from the indexed top level value.
+// The error is the one of scanning a malformed document, see Result.Err.
+func Index(doc []byte, recursive bool) (*IndexedDocument, error) {
+	ix := &IndexedDocument{}
+	if err := ix.root.index(resultFromBytes(doc), recursive, 1); err != nil {
+		return nil, locateError(doc, err)
+	}
+	return ix, nil
+}
+
+// index indexes the fields of the document or array at the depth.
+func (n *indexNode) index(r Result, recursive bool, depth int) error {
+	if err := checkDepth(depth); err != nil {
+		return err
+	}
+	n.value = r
+	n.fields = make(map[string]*indexNode)
+	var err error
+	_, iterErr := r.iterFields(func(key []byte, value Result) bool {
+		if _, ok := n.fields[string(key)]; ok {
+			return true // the first one is got
+		}
+		field := &indexNode{value: value}
+		n.fields[string(key)] = field
+		if recursive && value.IsContainer() {
+			err = field.index(value, recursive, depth+1)
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return iterErr
+}
+
+// Get gets the first value by the given path like Get, with a table lookup per indexed level.
+func (ix *IndexedDocument) Get(path ...string) Result {
+	n := &ix.root
+	for i, key := range path {
+		if n.fields == nil {
+			return n.value.Get(path[i:]...)
+		}
+		if n = n.fields[key]; n == nil {
+			return Result{Type: BSONTypeUndefined}
+		}
+	}
+	return n.value
+}
@@ -0,0 +1,34 @@
+package gbson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestIndex(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	paths := [][]string{
+		{}, {"int32"}, {"doc", "b", "1"}, {"doc", "b", "2"}, {"doc", "missing"}, {"int32", "x"}, {"missing"}, {"empty", "0"},
+	}
+	for _, recursive := range []bool{false, true} {
+		ix, err := Index(doc, recursive)
+		require.NoError(t, err)
+		for _, path := range paths {
+			require.Equal(t, Get(doc, path...), ix.Get(path...), "%v", path)
+		}
+	}
+
+	dup := mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}, {Key: "a", Value: int32(2)}})
+	ix, err := Index(dup, true)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), ix.Get("a").Int32())
+
+	_, err = Index(doc[:len(doc)-1], false)
+	require.ErrorIs(t, err, ErrInvalidLength)
+	_, err = Index(nestedDocument(DefaultMaxDepth+1), false)
+	require.NoError(t, err)
+	_, err = Index(nestedDocument(DefaultMaxDepth+1), true)
+	require.ErrorIs(t, err, ErrMaxDepth)
+}
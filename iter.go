@@ -0,0 +1,35 @@
+//go:build go1.23
+
+package gbson
+
+import "iter"
+
+// Elements returns an iterator of the keys and values of the elements of a document, or the indexes and items
+// of an array, which scans lazily and stops on break:
+//
+//	for key, value := range r.Elements() {
+//		...
+//	}
+//
+// It yields nothing for the other values, and stops at malformed elements, see Cursor for the errors.
+func (r Result) Elements() iter.Seq2[string, Result] {
+	return func(yield func(string, Result) bool) {
+		for c := r.Cursor(); c.Next(); {
+			if !yield(string(c.Key()), c.Value()) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator of the values of the elements of a document or the items of an array,
+// like Elements without the keys.
+func (r Result) Values() iter.Seq[Result] {
+	return func(yield func(Result) bool) {
+		for c := r.Cursor(); c.Next(); {
+			if !yield(c.Value()) {
+				return
+			}
+		}
+	}
+}
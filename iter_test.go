@@ -0,0 +1,31 @@
+//go:build go1.23
+
+package gbson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestIterators(t *testing.T) {
+	doc := mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}, {Key: "b", Value: bson.A{"x", "y", "z"}}, {Key: "c", Value: true}})
+	var keys []string
+	for key, value := range Get(doc).Elements() {
+		keys = append(keys, key)
+		if value.IsContainer() {
+			break
+		}
+	}
+	require.Equal(t, []string{"a", "b"}, keys)
+
+	var items []string
+	for value := range Get(doc, "b").Values() {
+		items = append(items, value.String())
+	}
+	require.Equal(t, []string{"x", "y", "z"}, items)
+	for range Get(doc, "a").Values() {
+		t.Fatal("values of an integer")
+	}
+}
@@ -0,0 +1,373 @@
+package gbson
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// ToJSON converts the bson document into MongoDB relaxed Extended JSON in a single pass.
+func ToJSON(doc []byte) ([]byte, error) {
+	return Result{Type: BSONTypeObject, Raw: doc}.JSON()
+}
+
+// JSON renders the value in MongoDB relaxed Extended JSON, e.g. {"$oid": "..."} for ObjectIDs,
+// directly from the raw bytes. A missing result is rendered as null.
+func (r Result) JSON() ([]byte, error) {
+	return r.AppendJSON(nil)
+}
+
+// AppendJSON appends the value rendered by JSON to dst and returns the extended buffer, so a buffer could be
+// reused across values. On errors, the returned buffer may have a part of the value appended.
+func (r Result) AppendJSON(dst []byte) ([]byte, error) {
+	if !r.Exist() {
+		return append(dst, "null"...), nil
+	}
+	var w jsonWriter
+	return r.locate(w.appendValue(dst, r))
+}
+
+// ToCanonicalJSON converts the bson document into MongoDB canonical Extended JSON, which keeps the exact
+// types of numbers and datetimes, e.g. {"$numberLong": "1"}, for archiving and cross-language interchange.
+func ToCanonicalJSON(doc []byte) ([]byte, error) {
+	return Result{Type: BSONTypeObject, Raw: doc}.CanonicalJSON()
+}
+
+// CanonicalJSON renders the value in MongoDB canonical Extended JSON. A missing result is rendered as null.
+func (r Result) CanonicalJSON() ([]byte, error) {
+	if !r.Exist() {
+		return []byte("null"), nil
+	}
+	w := jsonWriter{canonical: true}
+	return r.locate(w.appendValue(nil, r))
+}
+
+// MarshalJSON implements json.Marshaler, the same as JSON.
+func (r Result) MarshalJSON() ([]byte, error) {
+	return r.JSON()
+}
+
+// jsonWriter writes Extended JSON directly from the raw bytes.
+// See https://www.mongodb.com/docs/manual/reference/mongodb-extended-json/
+type jsonWriter struct {
+	canonical bool
+	depth     int // depth of the container being written
+}
+
+const rfc3339Milli = "2006-01-02T15:04:05.999Z07:00"
+
+func (w jsonWriter) appendValue(dst []byte, r Result) ([]byte, error) {
+	switch r.Type {
+	case BSONTypeDouble:
+		if len(r.Raw) < 8 {
+			return dst, ErrInvalidLength
+		}
+		f := r.Float64()
+		if w.canonical || math.IsInf(f, 0) || math.IsNaN(f) {
+			return append(appendExtJSONFloat(append(dst, `{"$numberDouble":"`...), f), `"}`...), nil
+		}
+		return appendExtJSONFloat(dst, f), nil
+	case BSONTypeString:
+		return w.appendString(dst, r)
+	case BSONTypeObject:
+		return w.appendDocument(dst, r)
+	case BSONTypeArray:
+		return w.appendArray(dst, r)
+	case BSONTypeBinary:
+		if len(r.Raw) < 5 {
+			return dst, ErrInvalidLength
+		}
+		subtype, data := r.Binary()
+		dst = append(dst, `{"$binary":{"base64":"`...)
+		dst = appendBase64(dst, data)
+		dst = append(dst, `","subType":"`...)
+		dst = append(dst, hexDigits[subtype>>4], hexDigits[subtype&0xF])
+		return append(dst, `"}}`...), nil
+	case BSONTypeUndefined:
+		return append(dst, `{"$undefined":true}`...), nil
+	case BSONTypeObjectID:
+		if len(r.Raw) < 12 {
+			return dst, ErrInvalidLength
+		}
+		dst = append(dst, `{"$oid":"`...)
+		dst = appendHex(dst, r.Raw[:12])
+		return append(dst, `"}`...), nil
+	case BSONTypeBoolean:
+		if len(r.Raw) < 1 {
+			return dst, ErrInvalidLength
+		}
+		return strconv.AppendBool(dst, r.Bool()), nil
+	case BSONTypeDateTime:
+		if len(r.Raw) < 8 {
+			return dst, ErrInvalidLength
+		}
+		t := r.TimeIn(time.UTC)
+		if w.canonical || t.Year() < 1970 || t.Year() > 9999 {
+			dst = append(dst, `{"$date":{"$numberLong":"`...)
+			dst = strconv.AppendInt(dst, int64(binary.LittleEndian.Uint64(r.Raw)), 10)
+			return append(dst, `"}}`...), nil
+		}
+		dst = append(dst, `{"$date":"`...)
+		dst = t.AppendFormat(dst, rfc3339Milli)
+		return append(dst, `"}`...), nil
+	case BSONTypeNull:
+		return append(dst, "null"...), nil
+	case BSONTypeRegex:
+		pattern, options := r.Regex()
+		dst = append(dst, `{"$regularExpression":{"pattern":`...)
+		dst = appendJSONString(dst, pattern)
+		dst = append(dst, `,"options":`...)
+		dst = appendJSONString(dst, sortString(options))
+		return append(dst, `}}`...), nil
+	case BSONTypeDBPointer:
+		ns, id, ok := r.DBPointer()
+		if !ok {
+			return dst, ErrInvalidLength
+		}
+		dst = append(dst, `{"$dbPointer":{"$ref":`...)
+		dst = appendJSONString(dst, ns)
+		dst = append(dst, `,"$id":{"$oid":"`...)
+		dst = appendHex(dst, id[:])
+		return append(dst, `"}}}`...), nil
+	case BSONTypeJavaScript:
+		dst = append(dst, `{"$code":`...)
+		dst, err := w.appendString(dst, r)
+		if err != nil {
+			return dst, err
+		}
+		return append(dst, '}'), nil
+	case BSONTypeSymbol:
+		dst = append(dst, `{"$symbol":`...)
+		dst, err := w.appendString(dst, r)
+		if err != nil {
+			return dst, err
+		}
+		return append(dst, '}'), nil
+	case BSONTypeJavaScriptWithScope:
+		code, scope := r.JavaScriptWithScope()
+		if !scope.Exist() {
+			return dst, ErrInvalidLength
+		}
+		dst = append(dst, `{"$code":`...)
+		dst = appendJSONString(dst, code)
+		dst = append(dst, `,"$scope":`...)
+		dst, err := w.appendDocument(dst, scope)
+		if err != nil {
+			return dst, err
+		}
+		return append(dst, '}'), nil
+	case BSONTypeInt32:
+		if len(r.Raw) < 4 {
+			return dst, ErrInvalidLength
+		}
+		if w.canonical {
+			dst = strconv.AppendInt(append(dst, `{"$numberInt":"`...), int64(r.Int32()), 10)
+			return append(dst, `"}`...), nil
+		}
+		return strconv.AppendInt(dst, int64(r.Int32()), 10), nil
+	case BSONTypeTimestamp:
+		if len(r.Raw) < 8 {
+			return dst, ErrInvalidLength
+		}
+		t, i := r.Timestamp()
+		dst = strconv.AppendUint(append(dst, `{"$timestamp":{"t":`...), uint64(t), 10)
+		dst = strconv.AppendUint(append(dst, `,"i":`...), uint64(i), 10)
+		return append(dst, `}}`...), nil
+	case BSONTypeInt64:
+		if len(r.Raw) < 8 {
+			return dst, ErrInvalidLength
+		}
+		if w.canonical {
+			dst = strconv.AppendInt(append(dst, `{"$numberLong":"`...), r.Int64(), 10)
+			return append(dst, `"}`...), nil
+		}
+		return strconv.AppendInt(dst, r.Int64(), 10), nil
+	case BSONTypeDecimal128:
+		if len(r.Raw) < 16 {
+			return dst, ErrInvalidLength
+		}
+		dst = append(dst, `{"$numberDecimal":"`...)
+		dst = append(dst, r.Decimal128().String()...)
+		return append(dst, `"}`...), nil
+	case BSONTypeMinKey:
+		return append(dst, `{"$minKey":1}`...), nil
+	case BSONTypeMaxKey:
+		return append(dst, `{"$maxKey":1}`...), nil
+	}
+	return dst, errors.Wrapf(ErrUnsupportedType, "type %v", r.Type)
+}
+
+func (w jsonWriter) appendString(dst []byte, r Result) ([]byte, error) {
+	value, n := consumeString(r.Raw)
+	if n == 0 {
+		return dst, ErrInvalidLength
+	}
+	return appendJSONString(dst, value), nil
+}
+
+func (w jsonWriter) appendDocument(dst []byte, r Result) ([]byte, error) {
+	w.depth++
+	if err := checkDepth(w.depth); err != nil {
+		return dst, err
+	}
+	dst = append(dst, '{')
+	first := true
+	var err error
+	_, iterErr := r.iterFields(func(key []byte, value Result) bool {
+		if !first {
+			dst = append(dst, ',')
+		}
+		first = false
+		dst = appendJSONString(dst, key)
+		dst = append(dst, ':')
+		dst, err = w.appendValue(dst, value)
+		return err == nil
+	})
+	if err != nil {
+		return dst, err
+	}
+	if iterErr != nil {
+		return dst, iterErr
+	}
+	return append(dst, '}'), nil
+}
+
+func (w jsonWriter) appendArray(dst []byte, r Result) ([]byte, error) {
+	w.depth++
+	if err := checkDepth(w.depth); err != nil {
+		return dst, err
+	}
+	dst = append(dst, '[')
+	first := true
+	var err error
+	_, iterErr := r.iterFields(func(_ []byte, value Result) bool {
+		if !first {
+			dst = append(dst, ',')
+		}
+		first = false
+		dst, err = w.appendValue(dst, value)
+		return err == nil
+	})
+	if err != nil {
+		return dst, err
+	}
+	if iterErr != nil {
+		return dst, iterErr
+	}
+	return append(dst, ']'), nil
+}
+
+// appendExtJSONFloat formats the float the same as mongo-driver, integral values keep a ".0" suffix.
+func appendExtJSONFloat(dst []byte, f float64) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return append(dst, "Infinity"...)
+	case math.IsInf(f, -1):
+		return append(dst, "-Infinity"...)
+	case math.IsNaN(f):
+		return append(dst, "NaN"...)
+	}
+	start := len(dst)
+	dst = strconv.AppendFloat(dst, f, 'G', -1, 64)
+	for _, c := range dst[start:] {
+		if c == 'E' || c == '.' {
+			return dst
+		}
+	}
+	return append(dst, ".0"...)
+}
+
+const hexDigits = "0123456789abcdef"
+
+func appendHex(dst []byte, bs []byte) []byte {
+	for _, b := range bs {
+		dst = append(dst, hexDigits[b>>4], hexDigits[b&0xF])
+	}
+	return dst
+}
+
+func appendBase64(dst []byte, bs []byte) []byte {
+	n := base64.StdEncoding.EncodedLen(len(bs))
+	if cap(dst)-len(dst) < n {
+		grown := make([]byte, len(dst), 2*cap(dst)+n)
+		copy(grown, dst)
+		dst = grown
+	}
+	base64.StdEncoding.Encode(dst[len(dst):len(dst)+n], bs)
+	return dst[:len(dst)+n]
+}
+
+// appendJSONString appends the quoted and escaped string, invalid UTF-8 bytes are replaced by U+FFFD.
+// HTML characters are not escaped.
+func appendJSONString[S string | []byte](dst []byte, s S) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := decodeRune(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid in JSON but not in JavaScript, escape them as encoding/json does
+		if c == '\u2028' || c == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// decodeRune decodes the first rune of s without converting s into another type.
+func decodeRune[S string | []byte](s S) (rune, int) {
+	var buf [utf8.UTFMax]byte
+	return utf8.DecodeRune(buf[:copy(buf[:], s)])
+}
+
+func sortString(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	bs := []byte(s)
+	sort.Slice(bs, func(i, j int) bool { return bs[i] < bs[j] })
+	return string(bs)
+}
@@ -0,0 +1,100 @@
+package gbson
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func getTestJSONDocument(t testing.TB) []byte {
+	dec, _ := primitive.ParseDecimal128("-1.50E-3")
+	return mustMarshal(t, bson.D{
+		{Key: "_id", Value: primitive.NewObjectID()},
+		{Key: "double", Value: 1.5},
+		{Key: "integral", Value: 2.0},
+		{Key: "large", Value: 1e300},
+		{Key: "inf", Value: math.Inf(-1)},
+		{Key: "nan", Value: math.NaN()},
+		{Key: "string", Value: "quote\" slash\\ tab\t ctrl\x01 <html> 中文   bad\xff"},
+		{Key: "doc", Value: bson.D{{Key: "a", Value: int32(1)}, {Key: "b", Value: bson.A{int64(2), "x", bson.D{}}}}},
+		{Key: "empty", Value: bson.A{}},
+		{Key: "binary", Value: primitive.Binary{Subtype: 0x80, Data: []byte{1, 2, 3}}},
+		{Key: "undefined", Value: primitive.Undefined{}},
+		{Key: "bool", Value: false},
+		{Key: "date", Value: time.Date(2022, 11, 10, 1, 2, 3, 4e6, time.UTC)},
+		{Key: "date0", Value: time.Date(2022, 11, 10, 1, 2, 3, 0, time.UTC)},
+		{Key: "ancient", Value: time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Key: "null", Value: nil},
+		{Key: "regex", Value: primitive.Regex{Pattern: "^a\"", Options: "mi"}},
+		{Key: "pointer", Value: primitive.DBPointer{DB: "db.coll", Pointer: primitive.NewObjectID()}},
+		{Key: "js", Value: primitive.JavaScript("return 1")},
+		{Key: "symbol", Value: primitive.Symbol("sym")},
+		{Key: "scope", Value: primitive.CodeWithScope{Code: "x", Scope: bson.D{{Key: "x", Value: int32(1)}}}},
+		{Key: "int32", Value: int32(-7)},
+		{Key: "ts", Value: primitive.Timestamp{T: 1, I: 2}},
+		{Key: "int64", Value: int64(1) << 40},
+		{Key: "decimal", Value: dec},
+		{Key: "min", Value: primitive.MinKey{}},
+		{Key: "max", Value: primitive.MaxKey{}},
+	})
+}
+
+func TestMarshalJSON(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	expected, err := bson.MarshalExtJSON(bson.Raw(doc), false, false)
+	require.NoError(t, err)
+	actual, err := Get(doc).MarshalJSON()
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(actual))
+
+	embedded, err := json.Marshal(map[string]interface{}{
+		"id":      Get(doc, "_id"),
+		"count":   Get(doc, "int32"),
+		"missing": Get(doc, "missing"),
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":{"$oid":"`+Get(doc, "_id").ObjectIDHex()+`"},"count":-7,"missing":null}`, string(embedded))
+
+	_, err = Result{Type: BSONTypeObject, Raw: doc[:len(doc)/2]}.MarshalJSON()
+	require.Error(t, err)
+}
+
+func TestToJSON(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	expected, err := bson.MarshalExtJSON(bson.Raw(doc), false, false)
+	require.NoError(t, err)
+	actual, err := ToJSON(doc)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(actual))
+
+	actual, err = Get(doc, "doc", "b").JSON()
+	require.NoError(t, err)
+	require.Equal(t, `[2,"x",{}]`, string(actual))
+	actual, err = Get(doc, "missing").JSON()
+	require.NoError(t, err)
+	require.Equal(t, "null", string(actual))
+
+	_, err = ToJSON(doc[:len(doc)-3])
+	require.Error(t, err)
+}
+
+func TestToCanonicalJSON(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	expected, err := bson.MarshalExtJSON(bson.Raw(doc), true, false)
+	require.NoError(t, err)
+	actual, err := ToCanonicalJSON(doc)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(actual))
+
+	actual, err = Get(doc, "doc").CanonicalJSON()
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"$numberInt":"1"},"b":[{"$numberLong":"2"},"x",{}]}`, string(actual))
+	actual, err = Get(doc, "missing").CanonicalJSON()
+	require.NoError(t, err)
+	require.Equal(t, "null", string(actual))
+}
@@ -0,0 +1,382 @@
+package gbson
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// valueEncoder appends the bson value of v to dst and reports the bson type of the value.
+type valueEncoder func(dst []byte, v reflect.Value) ([]byte, Type, error)
+
+var (
+	encoderCache sync.Map // map[reflect.Type]valueEncoder
+
+	timeType   = reflect.TypeOf(time.Time{})
+	resultType = reflect.TypeOf(Result{})
+)
+
+// Marshal encodes v into a bson document.
+//
+// v could be a struct, a map with string keys or a slice (encoded as an array document),
+// pointers and interfaces are dereferenced. Map keys are written in sorted order so the output is deterministic.
+//
+// Struct fields are encoded compatibly with mongo-driver: fields are named by their lowercased
+// names unless renamed by the `bson` tag, and the tag options omitempty, minsize and inline
+// (for structs, struct pointers and maps with string keys) are honored. The truncate option only applies to
+// decoding, see Unmarshal, so it's accepted without effect.
+// Types of mongo-driver's primitive package, such as ObjectID and bson.D, are encoded as their bson types.
+func Marshal(v interface{}) ([]byte, error) {
+	return appendMarshal(allocEncoded(v), v)
+}
+
+func appendMarshal(dst []byte, v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return dst, errors.Wrap(ErrNotObject, "nil value")
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return dst, errors.Wrap(ErrNotObject, "nil value")
+	}
+	out, tp, err := encoderOf(rv.Type())(dst, rv)
+	if err != nil {
+		return dst, err
+	}
+	if tp != BSONTypeObject && tp != BSONTypeArray {
+		return dst, errors.Wrapf(ErrNotObject, "%s", rv.Type())
+	}
+	return out, nil
+}
+
+// encoderOf returns the cached encoder of the type, building it when missing.
+func encoderOf(t reflect.Type) valueEncoder {
+	if enc, ok := encoderCache.Load(t); ok {
+		return enc.(valueEncoder)
+	}
+	// store an indirect encoder first to support recursive types,
+	// it waits until the real encoder is built.
+	var (
+		wg  sync.WaitGroup
+		enc valueEncoder
+	)
+	wg.Add(1)
+	indirect, loaded := encoderCache.LoadOrStore(t, valueEncoder(func(dst []byte, v reflect.Value) ([]byte, Type, error) {
+		wg.Wait()
+		return enc(dst, v)
+	}))
+	if loaded {
+		return indirect.(valueEncoder)
+	}
+	enc = newEncoder(t)
+	wg.Done()
+	encoderCache.Store(t, enc)
+	return enc
+}
+
+func newEncoder(t reflect.Type) valueEncoder {
+	switch t {
+	case timeType:
+		return encodeTime
+	case resultType:
+		return encodeResult
+	}
+	if driverTypes[t] {
+		return encodeDriverValue
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return encodeBool
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return encodeInt32
+	case reflect.Int:
+		return encodeInt
+	case reflect.Int64:
+		return encodeInt64
+	case reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return encodeUint
+	case reflect.Float32, reflect.Float64:
+		return encodeFloat
+	case reflect.String:
+		return encodeString
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return encodeByteSlice
+		}
+		return newArrayEncoder(t)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return encodeByteArray
+		}
+		return newArrayEncoder(t)
+	case reflect.Map:
+		return newMapEncoder(t)
+	case reflect.Struct:
+		return newStructEncoder(t)
+	case reflect.Ptr:
+		return newPtrEncoder(t)
+	case reflect.Interface:
+		return encodeInterface
+	}
+	return newUnsupportedEncoder(t)
+}
+
+func newUnsupportedEncoder(t reflect.Type) valueEncoder {
+	return func(dst []byte, _ reflect.Value) ([]byte, Type, error) {
+		return dst, BSONTypeUndefined, errors.Wrapf(ErrUnsupportedType, "%s", t)
+	}
+}
+
+func encodeTime(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	t := v.Interface().(time.Time)
+	return appendInt64(dst, t.Unix()*1e3+int64(t.Nanosecond())/1e6), BSONTypeDateTime, nil
+}
+
+func encodeResult(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	return append(dst, v.Field(1).Bytes()...), Type(v.Field(0).Uint()), nil
+}
+
+func encodeBool(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	if v.Bool() {
+		return append(dst, 1), BSONTypeBoolean, nil
+	}
+	return append(dst, 0), BSONTypeBoolean, nil
+}
+
+func encodeInt32(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	if v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uintptr {
+		return appendInt32(dst, int32(v.Uint())), BSONTypeInt32, nil
+	}
+	return appendInt32(dst, int32(v.Int())), BSONTypeInt32, nil
+}
+
+func encodeInt(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	i := v.Int()
+	if i >= math.MinInt32 && i <= math.MaxInt32 {
+		return appendInt32(dst, int32(i)), BSONTypeInt32, nil
+	}
+	return appendInt64(dst, i), BSONTypeInt64, nil
+}
+
+func encodeInt64(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	return appendInt64(dst, v.Int()), BSONTypeInt64, nil
+}
+
+func encodeUint(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	u := v.Uint()
+	if u > math.MaxInt64 {
+		return dst, BSONTypeUndefined, errors.Errorf("%d overflows int64", u)
+	}
+	return appendInt64(dst, int64(u)), BSONTypeInt64, nil
+}
+
+func encodeUintMinSize(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	if u := v.Uint(); u <= math.MaxInt32 {
+		return appendInt32(dst, int32(u)), BSONTypeInt32, nil
+	}
+	return encodeUint(dst, v)
+}
+
+func encodeFloat(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	return appendDouble(dst, v.Float()), BSONTypeDouble, nil
+}
+
+func encodeString(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	return appendString(dst, v.String()), BSONTypeString, nil
+}
+
+func encodeByteSlice(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	if v.IsNil() {
+		return dst, BSONTypeNull, nil
+	}
+	return appendBinary(dst, 0, v.Bytes()), BSONTypeBinary, nil
+}
+
+func encodeByteArray(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	n := v.Len()
+	dst = appendInt32(dst, int32(n))
+	dst = append(dst, 0)
+	for i := 0; i < n; i++ {
+		dst = append(dst, byte(v.Index(i).Uint()))
+	}
+	return dst, BSONTypeBinary, nil
+}
+
+func encodeInterface(dst []byte, v reflect.Value) ([]byte, Type, error) {
+	if v.IsNil() {
+		return dst, BSONTypeNull, nil
+	}
+	elem := v.Elem()
+	return encoderOf(elem.Type())(dst, elem)
+}
+
+func newPtrEncoder(t reflect.Type) valueEncoder {
+	elem := encoderOf(t.Elem())
+	return func(dst []byte, v reflect.Value) ([]byte, Type, error) {
+		if v.IsNil() {
+			return dst, BSONTypeNull, nil
+		}
+		return elem(dst, v.Elem())
+	}
+}
+
+func newArrayEncoder(t reflect.Type) valueEncoder {
+	elem := encoderOf(t.Elem())
+	return func(dst []byte, v reflect.Value) ([]byte, Type, error) {
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return dst, BSONTypeNull, nil
+		}
+		dst, start := beginDocument(dst)
+		for i, n := 0, v.Len(); i < n; i++ {
+			pos := len(dst)
+			dst = appendIndexHeader(dst, BSONTypeNull, i)
+			var tp Type
+			var err error
+			if dst, tp, err = elem(dst, v.Index(i)); err != nil {
+				return dst, BSONTypeUndefined, err
+			}
+			dst[pos] = byte(tp)
+		}
+		return endDocument(dst, start), BSONTypeArray, nil
+	}
+}
+
+func newMapEncoder(t reflect.Type) valueEncoder {
+	keyString := mapKeyStringer(t.Key())
+	if keyString == nil {
+		return newUnsupportedEncoder(t)
+	}
+	elem := encoderOf(t.Elem())
+	return func(dst []byte, v reflect.Value) ([]byte, Type, error) {
+		if v.IsNil() {
+			return dst, BSONTypeNull, nil
+		}
+		dst, start := beginDocument(dst)
+		dst, err := appendMapElements(dst, v, keyString, elem, nil)
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		return endDocument(dst, start), BSONTypeObject, nil
+	}
+}
+
+// appendMapElements appends all entries of the map in the order of sorted keys,
+// keys reported by conflict are rejected.
+func appendMapElements(dst []byte, v reflect.Value, keyString func(reflect.Value) string,
+	elem valueEncoder, conflict func(string) bool) ([]byte, error) {
+	keys := make([]string, 0, v.Len())
+	values := make(map[string]reflect.Value, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key := keyString(iter.Key())
+		if conflict != nil && conflict(key) {
+			return dst, errors.Errorf("key %s of inlined map conflicts with a struct field name", key)
+		}
+		keys = append(keys, key)
+		values[key] = iter.Value()
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var err error
+		if dst, err = appendElement(dst, key, elem, values[key]); err != nil {
+			return dst, err
+		}
+	}
+	return dst, nil
+}
+
+// mapKeyStringer returns the function formatting map keys of the type, nil if not supported.
+func mapKeyStringer(t reflect.Type) func(reflect.Value) string {
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.Value.String
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(v reflect.Value) string { return strconv.FormatInt(v.Int(), 10) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(v reflect.Value) string { return strconv.FormatUint(v.Uint(), 10) }
+	}
+	return nil
+}
+
+type fieldEncoder struct {
+	structField
+	enc valueEncoder
+}
+
+func newStructEncoder(t reflect.Type) valueEncoder {
+	info, err := cachedStructInfo(t)
+	if err != nil {
+		return func(dst []byte, _ reflect.Value) ([]byte, Type, error) {
+			return dst, BSONTypeUndefined, err
+		}
+	}
+	fields := make([]fieldEncoder, len(info.fields))
+	for i, f := range info.fields {
+		fields[i] = fieldEncoder{structField: f, enc: newFieldEncoder(f)}
+	}
+	var inlineEncoder valueEncoder
+	if info.inlineMap >= 0 {
+		inlineEncoder = encoderOf(t.Field(info.inlineMap).Type.Elem())
+	}
+	conflict := func(key string) bool {
+		_, ok := info.names[key]
+		return ok
+	}
+	return func(dst []byte, v reflect.Value) ([]byte, Type, error) {
+		dst, start := beginDocument(dst)
+		var err error
+		for i := range fields {
+			f := &fields[i]
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+				continue
+			}
+			if dst, err = appendElement(dst, f.name, f.enc, fv); err != nil {
+				return dst, BSONTypeUndefined, err
+			}
+		}
+		if inlineEncoder != nil {
+			if mv := v.Field(info.inlineMap); !mv.IsNil() {
+				if dst, err = appendMapElements(dst, mv, reflect.Value.String, inlineEncoder, conflict); err != nil {
+					return dst, BSONTypeUndefined, err
+				}
+			}
+		}
+		return endDocument(dst, start), BSONTypeObject, nil
+	}
+}
+
+// newFieldEncoder returns the encoder of the struct field, with the minsize option applied.
+func newFieldEncoder(f structField) valueEncoder {
+	if f.minSize {
+		switch f.typ.Kind() {
+		case reflect.Int64:
+			return encodeInt
+		case reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return encodeUintMinSize
+		}
+	}
+	return encoderOf(f.typ)
+}
+
+// appendElement appends a whole element, the type byte is filled after the value is encoded.
+func appendElement(dst []byte, name string, enc valueEncoder, v reflect.Value) ([]byte, error) {
+	pos := len(dst)
+	dst, err := appendElementHeader(dst, BSONTypeNull, name)
+	if err != nil {
+		return dst, err
+	}
+	var tp Type
+	if dst, tp, err = enc(dst, v); err != nil {
+		return dst, err
+	}
+	dst[pos] = byte(tp)
+	return dst, nil
+}
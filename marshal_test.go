@@ -0,0 +1,147 @@
+package gbson
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type testMarshalChild struct {
+	Name  string
+	Score float64
+}
+
+type testMarshalStruct struct {
+	ID       int64 `bson:"_id"`
+	Count    int
+	Big      int
+	Small    int8
+	Unsigned uint32
+	Flag     bool
+	Text     string
+	Data     []byte
+	Hash     [4]byte
+	When     time.Time
+	Tags     []string
+	Nil      []int
+	Child    testMarshalChild
+	ChildPtr *testMarshalChild
+	Any      interface{}
+	Skipped  string `bson:"-"`
+	private  int
+}
+
+func TestMarshal(t *testing.T) {
+	v := testMarshalStruct{
+		ID:       42,
+		Count:    7,
+		Big:      1 << 40,
+		Small:    -3,
+		Unsigned: 9,
+		Flag:     true,
+		Text:     "hello",
+		Data:     []byte{1, 2, 3},
+		Hash:     [4]byte{4, 5, 6, 7},
+		When:     time.UnixMilli(1668000000123),
+		Tags:     []string{"a", "b"},
+		Child:    testMarshalChild{Name: "child", Score: 1.5},
+		Any:      map[string]interface{}{"x": int32(1)},
+		Skipped:  "skipped",
+		private:  1,
+	}
+	expected, err := bson.Marshal(v)
+	require.NoError(t, err)
+	actual, err := Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	actual, err = Marshal(&v)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	m := map[string]interface{}{"b": 1, "a": "x", "c": []interface{}{1.5, nil, true}}
+	actual, err = Marshal(m)
+	require.NoError(t, err)
+	require.Equal(t, "x", Get(actual, "a").String())
+	require.Equal(t, int64(1), Get(actual, "b").Int64())
+	require.Equal(t, 3, Get(actual, "c").Length())
+
+	actual, err = Marshal([]int{1, 2, 3})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), Get(actual, "2").Int64())
+
+	_, err = Marshal(1)
+	require.ErrorIs(t, err, ErrNotObject)
+	_, err = Marshal(map[string]interface{}{"f": func() {}})
+	require.ErrorIs(t, err, ErrUnsupportedType)
+	_, err = Marshal(map[string]int{"a\x00b": 1})
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+type testMarshalRecursive struct {
+	Value int32
+	Next  *testMarshalRecursive
+}
+
+func TestMarshalRecursive(t *testing.T) {
+	v := testMarshalRecursive{Value: 1, Next: &testMarshalRecursive{Value: 2}}
+	expected, err := bson.Marshal(v)
+	require.NoError(t, err)
+	actual, err := Marshal(v)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+type testMarshalBase struct {
+	ID      string `bson:"_id"`
+	Version int64  `bson:"v,minsize"`
+}
+
+type testMarshalTagged struct {
+	Base    testMarshalBase        `bson:",inline"`
+	Meta    *testMarshalChild      `bson:",inline"`
+	Name    string                 `bson:"name,omitempty"`
+	Count   int                    `bson:",omitempty"`
+	When    time.Time              `bson:"when,omitempty"`
+	Ptr     *int                   `bson:"ptr,omitempty"`
+	Size    uint64                 `bson:"size,minsize"`
+	Version string                 `bson:"v"` // dominates the inlined field
+	Extra   map[string]interface{} `bson:",inline"`
+	Empty   map[string]int         `bson:"empty,omitempty"`
+	Nested  []testMarshalChild     `bson:"nested,omitempty"`
+	Table   map[string]*testMarshalChild
+}
+
+func TestMarshalStructTags(t *testing.T) {
+	for _, v := range []testMarshalTagged{
+		{},
+		{
+			Base:    testMarshalBase{ID: "id", Version: 3},
+			Meta:    &testMarshalChild{Name: "meta", Score: 2},
+			Name:    "name",
+			Count:   1,
+			When:    time.UnixMilli(1668000000000),
+			Size:    5,
+			Version: "v1",
+			Extra:   map[string]interface{}{"extra": "x"},
+			Nested:  []testMarshalChild{{Name: "n"}},
+			Table:   map[string]*testMarshalChild{"k": nil},
+		},
+	} {
+		expected, err := bson.Marshal(v)
+		require.NoError(t, err)
+		actual, err := Marshal(v)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	}
+
+	_, err := Marshal(testMarshalTagged{Extra: map[string]interface{}{"name": 1}})
+	require.Error(t, err)
+	_, err = Marshal(struct {
+		A int `bson:"x"`
+		B int `bson:"x"`
+	}{})
+	require.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package gbson
+
+import "strings"
+
+// Matcher matches a set of dotted paths against documents in a single pass, rather than a traversal per path
+// by Get. The paths are compiled into a trie, where the key "*" is a wildcard matching any key of a document
+// or any index of an array, e.g. "items.*.price". A Matcher is safe for concurrent use.
+type Matcher struct {
+	root matchNode
+}
+
+// matchNode is a node of the trie, whose ids are the ones of the paths ending at it.
+type matchNode struct {
+	children map[string]*matchNode
+	wildcard *matchNode
+	ids      []int
+}
+
+// NewMatcher compiles the dotted paths into a Matcher, the ids of the paths are their indexes.
+func NewMatcher(paths ...string) *Matcher {
+	m := &Matcher{}
+	for id, path := range paths {
+		n := &m.root
+		for _, key := range strings.Split(path, ".") {
+			n = n.child(key)
+		}
+		n.ids = append(n.ids, id)
+	}
+	return m
+}
+
+// child returns the child of the key, adding it if missing.
+func (n *matchNode) child(key string) *matchNode {
+	if key == "*" {
+		if n.wildcard == nil {
+			n.wildcard = &matchNode{}
+		}
+		return n.wildcard
+	}
+	if n.children == nil {
+		n.children = make(map[string]*matchNode)
+	}
+	c, ok := n.children[key]
+	if !ok {
+		c = &matchNode{}
+		n.children[key] = c
+	}
+	return c
+}
+
+// Match scans the document once and calls fn with the id of the path and the value for every match in
+// the order of the document, until fn returns false. A value matching several paths is passed once per path.
+// The error is the one of the malformed document or ErrMaxDepth, with the matches before it passed.
+func (m *Matcher) Match(doc []byte, fn func(id int, r Result) bool) error {
+	_, err := m.match(resultFromBytes(doc), []*matchNode{&m.root}, 1, fn)
+	return locateError(doc, err)
+}
+
+// match matches the elements of the container at the depth against the active nodes,
+// it returns false if fn stops the match.
+func (m *Matcher) match(r Result, nodes []*matchNode, depth int, fn func(id int, r Result) bool) (bool, error) {
+	if err := checkDepth(depth); err != nil {
+		return false, err
+	}
+	var next []*matchNode
+	goOn := true
+	var err error
+	_, iterErr := r.iterFields(func(key []byte, value Result) bool {
+		next = next[:0]
+		for _, n := range nodes {
+			if c, ok := n.children[string(key)]; ok {
+				next = append(next, c)
+			}
+			if n.wildcard != nil {
+				next = append(next, n.wildcard)
+			}
+		}
+		deeper := false
+		for _, n := range next {
+			for _, id := range n.ids {
+				if !fn(id, value) {
+					goOn = false
+					return false
+				}
+			}
+			deeper = deeper || n.children != nil || n.wildcard != nil
+		}
+		if deeper && value.IsContainer() {
+			goOn, err = m.match(value, next, depth+1, fn)
+		}
+		return goOn && err == nil
+	})
+	if err == nil {
+		err = iterErr
+	}
+	return goOn && err == nil, err
+}
@@ -0,0 +1,41 @@
+package gbson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMatcher(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "id", Value: int32(7)},
+		{Key: "items", Value: bson.A{
+			bson.D{{Key: "price", Value: 1.5}, {Key: "tags", Value: bson.A{"a"}}},
+			bson.D{{Key: "price", Value: 2.5}},
+		}},
+		{Key: "meta", Value: bson.D{{Key: "price", Value: 9.0}}},
+	})
+	m := NewMatcher("id", "items.*.price", "*.price", "items.0", "missing", "items.0.tags.0")
+	var matches []string
+	require.NoError(t, m.Match(doc, func(id int, r Result) bool {
+		matches = append(matches, fmt.Sprint(id, ":", r.Str()))
+		return true
+	}))
+	require.Equal(t, []string{"0:7", "3:{\"price\":1.5,\"tags\":[\"a\"]}", "1:1.5", "5:a", "1:2.5", "2:9"}, matches)
+
+	matches = nil
+	require.NoError(t, m.Match(doc, func(id int, r Result) bool {
+		matches = append(matches, fmt.Sprint(id))
+		return len(matches) < 3
+	}))
+	require.Equal(t, []string{"0", "3", "1"}, matches)
+
+	require.ErrorIs(t, m.Match(doc[:len(doc)-1], func(int, Result) bool { return true }), ErrInvalidLength)
+	deep := nestedDocument(DefaultMaxDepth + 2)
+	path := strings.TrimSuffix(strings.Repeat("a.0.", DefaultMaxDepth/2), ".")
+	require.NoError(t, NewMatcher(path).Match(deep, func(int, Result) bool { return true }), "only the paths are traversed")
+	require.ErrorIs(t, NewMatcher(path+".a").Match(deep, func(int, Result) bool { return true }), ErrMaxDepth)
+}
@@ -0,0 +1,467 @@
+package gbson
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// MessagePack conversion, see https://github.com/msgpack/msgpack/blob/master/spec.md
+//
+// Values are converted type to type: documents into maps, arrays into arrays, int32 into the smallest
+// integer format and int64 always into the int 64 format so it's converted back into int64, datetimes
+// into the timestamp extension (-1) and generic binaries into bin. The other BSON types have no
+// MessagePack counterpart, they are converted into an extension of the BSON type code, except MinKey
+// uses 0, whose data is the raw bson value.
+
+// msgpackExtTimestamp is the extension type of timestamps predefined by MessagePack.
+const msgpackExtTimestamp = -1
+
+// ToMsgPack converts the bson document into a MessagePack map.
+func ToMsgPack(doc []byte) ([]byte, error) {
+	r := Result{Type: BSONTypeObject, Raw: doc}
+	return r.locate(appendMsgPackValue(nil, r, 1))
+}
+
+// appendMsgPackValue appends the value, which is at the depth if it's a container.
+func appendMsgPackValue(dst []byte, r Result, depth int) ([]byte, error) {
+	switch r.Type {
+	case BSONTypeDouble:
+		if len(r.Raw) < 8 {
+			return dst, ErrInvalidLength
+		}
+		return append(dst, 0xcb, r.Raw[7], r.Raw[6], r.Raw[5], r.Raw[4], r.Raw[3], r.Raw[2], r.Raw[1], r.Raw[0]), nil
+	case BSONTypeString:
+		value, n := consumeString(r.Raw)
+		if n == 0 {
+			return dst, ErrInvalidLength
+		}
+		return appendMsgPackStr(dst, value), nil
+	case BSONTypeObject:
+		return appendMsgPackContainer(dst, r, depth, 0x80, 0xde)
+	case BSONTypeArray:
+		return appendMsgPackContainer(dst, r, depth, 0x90, 0xdc)
+	case BSONTypeBinary:
+		if len(r.Raw) < 5 {
+			return dst, ErrInvalidLength
+		}
+		if subtype, data := r.Binary(); subtype == 0 {
+			return append(appendMsgPackHeader(dst, 0, -1, 0xc4, 0xc5, len(data)), data...), nil
+		}
+	case BSONTypeBoolean:
+		if len(r.Raw) < 1 {
+			return dst, ErrInvalidLength
+		}
+		if r.Bool() {
+			return append(dst, 0xc3), nil
+		}
+		return append(dst, 0xc2), nil
+	case BSONTypeDateTime:
+		if len(r.Raw) < 8 {
+			return dst, ErrInvalidLength
+		}
+		return appendMsgPackTimestamp(dst, int64(binary.LittleEndian.Uint64(r.Raw))), nil
+	case BSONTypeNull:
+		return append(dst, 0xc0), nil
+	case BSONTypeInt32:
+		if len(r.Raw) < 4 {
+			return dst, ErrInvalidLength
+		}
+		return appendMsgPackInt(dst, int64(r.Int32())), nil
+	case BSONTypeInt64:
+		if len(r.Raw) < 8 {
+			return dst, ErrInvalidLength
+		}
+		v := uint64(r.Int64())
+		return append(dst, 0xd3, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+			byte(v>>24), byte(v>>16), byte(v>>8), byte(v)), nil
+	case BSONTypeUndefined, BSONTypeObjectID, BSONTypeRegex, BSONTypeDBPointer, BSONTypeJavaScript,
+		BSONTypeSymbol, BSONTypeJavaScriptWithScope, BSONTypeTimestamp, BSONTypeDecimal128,
+		BSONTypeMinKey, BSONTypeMaxKey:
+	default:
+		return dst, errors.Wrapf(ErrUnsupportedType, "type %v", r.Type)
+	}
+	if consumeValue(r.Type, r.Raw) != len(r.Raw) {
+		return dst, ErrInvalidLength
+	}
+	if err := validateElementValue(r.Type, r.Raw); err != nil {
+		return dst, err
+	}
+	extType := byte(r.Type)
+	if r.Type == BSONTypeMinKey {
+		extType = 0
+	}
+	return append(appendMsgPackExtHeader(dst, extType, len(r.Raw)), r.Raw...), nil
+}
+
+func appendMsgPackContainer(dst []byte, r Result, depth int, fixCode, code16 byte) ([]byte, error) {
+	if err := checkDepth(depth); err != nil {
+		return dst, err
+	}
+	dst = appendMsgPackHeader(dst, fixCode, 15, 0, code16, r.Length())
+	var err error
+	_, iterErr := r.iterFields(func(key []byte, value Result) bool {
+		if r.Type == BSONTypeObject {
+			dst = appendMsgPackStr(dst, key)
+		}
+		dst, err = appendMsgPackValue(dst, value, depth+1)
+		return err == nil
+	})
+	if err != nil {
+		return dst, err
+	}
+	return dst, iterErr
+}
+
+// appendMsgPackHeader appends the header of n items in the smallest format: the fix format if n is up to
+// fixMax, the 8 bits format code8 if it's not 0, followed by code16 and code16+1 for the 32 bits format.
+func appendMsgPackHeader(dst []byte, fixCode byte, fixMax int, code8, code16 byte, n int) []byte {
+	switch {
+	case n <= fixMax:
+		return append(dst, fixCode|byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		return append(dst, code8, byte(n))
+	case n <= math.MaxUint16:
+		return append(dst, code16, byte(n>>8), byte(n))
+	}
+	return append(dst, code16+1, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendMsgPackStr[S string | []byte](dst []byte, s S) []byte {
+	return append(appendMsgPackHeader(dst, 0xa0, 31, 0xd9, 0xda, len(s)), s...)
+}
+
+func appendMsgPackExtHeader(dst []byte, extType byte, n int) []byte {
+	switch n {
+	case 1:
+		return append(dst, 0xd4, extType)
+	case 2:
+		return append(dst, 0xd5, extType)
+	case 4:
+		return append(dst, 0xd6, extType)
+	case 8:
+		return append(dst, 0xd7, extType)
+	case 16:
+		return append(dst, 0xd8, extType)
+	}
+	return append(appendMsgPackHeader(dst, 0, -1, 0xc7, 0xc8, n), extType)
+}
+
+// appendMsgPackInt appends the integer in the smallest format.
+func appendMsgPackInt(dst []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v <= math.MaxInt8, v < 0 && v >= -32:
+		return append(dst, byte(v))
+	case v >= 0 && v <= math.MaxUint8:
+		return append(dst, 0xcc, byte(v))
+	case v >= 0 && v <= math.MaxUint16:
+		return append(dst, 0xcd, byte(v>>8), byte(v))
+	case v >= 0 && v <= math.MaxUint32:
+		return append(dst, 0xce, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	case v >= math.MinInt8:
+		return append(dst, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return append(dst, 0xd1, byte(v>>8), byte(v))
+	case v >= math.MinInt32:
+		return append(dst, 0xd2, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	return append(dst, 0xd3, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+		byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// appendMsgPackTimestamp appends the milliseconds since the epoch in the smallest timestamp format.
+func appendMsgPackTimestamp(dst []byte, ms int64) []byte {
+	sec, nsec := ms/1000, ms%1000*1e6
+	if nsec < 0 {
+		sec, nsec = sec-1, nsec+1e9
+	}
+	switch {
+	case sec >= 0 && sec <= math.MaxUint32 && nsec == 0:
+		return append(dst, 0xd6, 0xff, byte(sec>>24), byte(sec>>16), byte(sec>>8), byte(sec))
+	case sec >= 0 && sec < 1<<34:
+		v := uint64(nsec)<<34 | uint64(sec)
+		return append(dst, 0xd7, 0xff, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+			byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	return append(dst, 0xc7, 12, 0xff, byte(nsec>>24), byte(nsec>>16), byte(nsec>>8), byte(nsec),
+		byte(sec>>56), byte(sec>>48), byte(sec>>40), byte(sec>>32), byte(sec>>24), byte(sec>>16), byte(sec>>8), byte(sec))
+}
+
+// FromMsgPack converts a MessagePack map into a bson document, the reverse of ToMsgPack.
+// Keys of maps must be strings. Integers are converted into int32, or int64 if they don't fit or are
+// in the int 64 format, and floats into doubles.
+func FromMsgPack(data []byte) ([]byte, error) {
+	p := msgpackParser{data: data}
+	if len(data) == 0 || !(data[0]&0xf0 == 0x80 || data[0] == 0xde || data[0] == 0xdf) {
+		return nil, ErrNotObject
+	}
+	out, _, err := p.parseValue(nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.data) {
+		return nil, p.errorf("unexpected data after the map")
+	}
+	return out, nil
+}
+
+type msgpackParser struct {
+	data  []byte
+	pos   int
+	depth int // depth of the map or array being parsed
+}
+
+func (p *msgpackParser) errorf(format string, args ...interface{}) error {
+	return errors.Wrapf(ErrInvalidMsgPack, "offset %d: "+format, append([]interface{}{p.pos}, args...)...)
+}
+
+// read consumes n bytes.
+func (p *msgpackParser) read(n int) ([]byte, error) {
+	if n < 0 || len(p.data)-p.pos < n {
+		return nil, p.errorf("unexpected end")
+	}
+	p.pos += n
+	return p.data[p.pos-n : p.pos], nil
+}
+
+// readUint consumes a big endian unsigned integer of n bytes.
+func (p *msgpackParser) readUint(n int) (uint64, error) {
+	bs, err := p.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, b := range bs {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+// readLength consumes the length of a format of the given size in bytes, lengths of 32 bits are limited
+// by the remaining data as every item takes at least one byte.
+func (p *msgpackParser) readLength(size int) (int, error) {
+	n, err := p.readUint(size)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(p.data)-p.pos) {
+		return 0, p.errorf("length %d exceeds the data", n)
+	}
+	return int(n), nil
+}
+
+func (p *msgpackParser) parseValue(dst []byte) ([]byte, Type, error) {
+	if p.pos >= len(p.data) {
+		return dst, BSONTypeUndefined, p.errorf("unexpected end")
+	}
+	c := p.data[p.pos]
+	p.pos++
+	switch {
+	case c <= 0x7f:
+		return appendInt32(dst, int32(c)), BSONTypeInt32, nil
+	case c >= 0xe0:
+		return appendInt32(dst, int32(int8(c))), BSONTypeInt32, nil
+	case c <= 0x8f:
+		return p.parseMap(dst, int(c&0x0f))
+	case c <= 0x9f:
+		return p.parseArray(dst, int(c&0x0f))
+	case c <= 0xbf:
+		return p.parseString(dst, int(c&0x1f))
+	}
+	switch c {
+	case 0xc0:
+		return dst, BSONTypeNull, nil
+	case 0xc2, 0xc3:
+		return append(dst, c-0xc2), BSONTypeBoolean, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := p.readLength(1 << (c - 0xc4))
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		data, _ := p.read(n)
+		return appendBinary(dst, 0, data), BSONTypeBinary, nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := p.readLength(1 << (c - 0xc7))
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		return p.parseExt(dst, n)
+	case 0xca:
+		v, err := p.readUint(4)
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		return appendDouble(dst, float64(math.Float32frombits(uint32(v)))), BSONTypeDouble, nil
+	case 0xcb:
+		v, err := p.readUint(8)
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		return appendInt64(dst, int64(v)), BSONTypeDouble, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := p.readUint(1 << (c - 0xcc))
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		switch {
+		case v <= math.MaxInt32 && c != 0xcf:
+			return appendInt32(dst, int32(v)), BSONTypeInt32, nil
+		case v <= math.MaxInt64:
+			return appendInt64(dst, int64(v)), BSONTypeInt64, nil
+		}
+		return dst, BSONTypeUndefined, errors.Wrapf(ErrLossyConversion, "offset %d: %d overflows int64", p.pos, v)
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		v, err := p.readUint(size)
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		shift := 64 - 8*size
+		i := int64(v<<shift) >> shift // sign extend
+		if c == 0xd3 {
+			return appendInt64(dst, i), BSONTypeInt64, nil
+		}
+		return appendInt32(dst, int32(i)), BSONTypeInt32, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return p.parseExt(dst, 1<<(c-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := p.readLength(1 << (c - 0xd9))
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		return p.parseString(dst, n)
+	case 0xdc, 0xdd:
+		n, err := p.readLength(2 << (c - 0xdc))
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		return p.parseArray(dst, n)
+	case 0xde, 0xdf:
+		n, err := p.readLength(2 << (c - 0xde))
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		return p.parseMap(dst, n)
+	}
+	p.pos--
+	return dst, BSONTypeUndefined, p.errorf("invalid format 0x%02x", c)
+}
+
+func (p *msgpackParser) parseString(dst []byte, n int) ([]byte, Type, error) {
+	s, err := p.read(n)
+	if err != nil {
+		return dst, BSONTypeUndefined, err
+	}
+	dst = appendInt32(dst, int32(n+1))
+	return append(append(dst, s...), 0), BSONTypeString, nil
+}
+
+func (p *msgpackParser) parseMap(dst []byte, n int) ([]byte, Type, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if err := checkDepth(p.depth); err != nil {
+		return dst, BSONTypeUndefined, err
+	}
+	dst, docStart := beginDocument(dst)
+	for i := 0; i < n; i++ {
+		key, err := p.parseKey()
+		if err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		pos := len(dst)
+		if dst, err = appendElementHeader(dst, BSONTypeNull, string(key)); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		var tp Type
+		if dst, tp, err = p.parseValue(dst); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		dst[pos] = byte(tp)
+	}
+	return endDocument(dst, docStart), BSONTypeObject, nil
+}
+
+func (p *msgpackParser) parseKey() ([]byte, error) {
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end")
+	}
+	n := -1
+	switch c := p.data[p.pos]; {
+	case c >= 0xa0 && c <= 0xbf:
+		p.pos++
+		n = int(c & 0x1f)
+	case c >= 0xd9 && c <= 0xdb:
+		p.pos++
+		var err error
+		if n, err = p.readLength(1 << (c - 0xd9)); err != nil {
+			return nil, err
+		}
+	}
+	if n < 0 {
+		return nil, p.errorf("map key is not a string")
+	}
+	return p.read(n)
+}
+
+func (p *msgpackParser) parseArray(dst []byte, n int) ([]byte, Type, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if err := checkDepth(p.depth); err != nil {
+		return dst, BSONTypeUndefined, err
+	}
+	dst, docStart := beginDocument(dst)
+	for i := 0; i < n; i++ {
+		pos := len(dst)
+		dst = appendIndexHeader(dst, BSONTypeNull, i)
+		var tp Type
+		var err error
+		if dst, tp, err = p.parseValue(dst); err != nil {
+			return dst, BSONTypeUndefined, err
+		}
+		dst[pos] = byte(tp)
+	}
+	return endDocument(dst, docStart), BSONTypeArray, nil
+}
+
+// parseExt parses the extension of n bytes of data following the extension type.
+func (p *msgpackParser) parseExt(dst []byte, n int) ([]byte, Type, error) {
+	bs, err := p.read(n + 1)
+	if err != nil {
+		return dst, BSONTypeUndefined, err
+	}
+	extType, data := int8(bs[0]), bs[1:]
+	if extType == msgpackExtTimestamp {
+		var sec, nsec int64
+		switch n {
+		case 4:
+			sec = int64(binary.BigEndian.Uint32(data))
+		case 8:
+			v := binary.BigEndian.Uint64(data)
+			sec, nsec = int64(v&(1<<34-1)), int64(v>>34)
+		case 12:
+			nsec, sec = int64(binary.BigEndian.Uint32(data)), int64(binary.BigEndian.Uint64(data[4:]))
+		default:
+			return dst, BSONTypeUndefined, p.errorf("invalid timestamp of %d bytes", n)
+		}
+		return appendInt64(dst, sec*1000+nsec/1e6), BSONTypeDateTime, nil
+	}
+	tp := Type(extType)
+	if extType == 0 {
+		tp = BSONTypeMinKey
+	}
+	switch tp {
+	case BSONTypeBinary, BSONTypeUndefined, BSONTypeObjectID, BSONTypeRegex, BSONTypeDBPointer,
+		BSONTypeJavaScript, BSONTypeSymbol, BSONTypeJavaScriptWithScope, BSONTypeTimestamp,
+		BSONTypeDecimal128, BSONTypeMinKey, BSONTypeMaxKey:
+	default:
+		return dst, BSONTypeUndefined, errors.Wrapf(ErrUnsupportedType, "offset %d: extension type %d", p.pos, extType)
+	}
+	if consumeValue(tp, data) != len(data) {
+		return dst, BSONTypeUndefined, p.errorf("invalid %v of %d bytes", tp, n)
+	}
+	if err := validateElementValue(tp, data); err != nil {
+		return dst, BSONTypeUndefined, p.errorf("invalid %v: %v", tp, err)
+	}
+	return append(dst, data...), tp, nil
+}
@@ -0,0 +1,101 @@
+package gbson
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	data, err := ToMsgPack(doc)
+	require.NoError(t, err)
+	back, err := FromMsgPack(data)
+	require.NoError(t, err)
+	require.Equal(t, doc, back)
+
+	long := make([]byte, 70000)
+	doc = mustMarshal(t, bson.D{
+		{Key: "s", Value: string(long[:40])},
+		{Key: "bin", Value: long},
+		{Key: "a", Value: bson.A{int32(-33), int32(200), int32(-200), int32(70000), int32(-70000), int64(1)}},
+		{Key: "before", Value: time.Date(1960, 1, 1, 0, 0, 0, 1e6, time.UTC)},
+		{Key: "far", Value: time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Key: "uuid", Value: primitive.Binary{Subtype: 4, Data: long[:16]}},
+	})
+	data, err = ToMsgPack(doc)
+	require.NoError(t, err)
+	back, err = FromMsgPack(data)
+	require.NoError(t, err)
+	require.Equal(t, doc, back)
+}
+
+func TestToMsgPack(t *testing.T) {
+	data, err := ToMsgPack(mustMarshal(t, bson.D{
+		{Key: "a", Value: int32(1)},
+		{Key: "b", Value: bson.A{"x", nil, true}},
+		{Key: "c", Value: int64(-1)},
+		{Key: "d", Value: time.Unix(1, 0)},
+		{Key: "e", Value: primitive.MinKey{}},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []byte{
+		0x85,
+		0xa1, 'a', 0x01,
+		0xa1, 'b', 0x93, 0xa1, 'x', 0xc0, 0xc3,
+		0xa1, 'c', 0xd3, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xa1, 'd', 0xd6, 0xff, 0, 0, 0, 1,
+		0xa1, 'e', 0xc7, 0, 0,
+	}, data)
+
+	_, err = ToMsgPack([]byte{12, 0, 0, 0, 0x0b, 'r', 0, '0', '0', '0', 0, 0}) // regex without options
+	require.ErrorIs(t, err, ErrInvalidLength)
+}
+
+func TestFromMsgPack(t *testing.T) {
+	doc, err := FromMsgPack([]byte{
+		0x87,
+		0xa1, 'a', 0xca, 0x3f, 0xc0, 0, 0, // float32 1.5
+		0xd9, 1, 'b', 0xcf, 0, 0, 0, 1, 0, 0, 0, 0, // uint64
+		0xa1, 'c', 0xf0, // -16
+		0xa1, 'd', 0xde, 0, 1, 0xa1, 'x', 0xce, 0xff, 0xff, 0xff, 0xff,
+		0xa1, 'e', 0xdc, 0, 2, 0xc2, 0xc4, 1, 7,
+		0xa1, 'f', 0xd7, 0xff, 0, 0, 0, 4, 0, 0, 0, 2, // 1ns << 2 and 2s
+		0xa1, 'g', 0xd6, 0xff, 0, 0, 0, 3,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1.5, Get(doc, "a").Float64())
+	require.Equal(t, BSONTypeInt64, Get(doc, "b").Type)
+	require.Equal(t, int64(1)<<32, Get(doc, "b").Int64())
+	require.Equal(t, int32(-16), Get(doc, "c").Int32())
+	require.Equal(t, int64(1)<<32-1, Get(doc, "d", "x").Int64())
+	require.Equal(t, false, Get(doc, "e", "0").Bool())
+	_, data := Get(doc, "e", "1").Binary()
+	require.Equal(t, []byte{7}, data)
+	require.Equal(t, time.Unix(2, 0).UTC(), Get(doc, "f").TimeIn(time.UTC))
+	require.Equal(t, time.Unix(3, 0).UTC(), Get(doc, "g").TimeIn(time.UTC))
+
+	for _, data := range [][]byte{
+		{0x81, 0x01, 0x01},                  // non-string key
+		{0x81, 0xa1, 'a'},                   // missing value
+		{0x81, 0xa1, 'a', 0xd9, 5, 'x'},     // truncated string
+		{0x81, 0xa1, 'a', 0xc1},             // never used
+		{0x81, 0xa1, 'a', 0xd4, 0x07, 0x00}, // ObjectID of 1 byte
+		[]byte("\x81\xa10\xd6\v000\x00"),    // regex without options, found by fuzzing
+		{0x80, 0x00},                        // trailing data
+	} {
+		_, err = FromMsgPack(data)
+		require.ErrorIs(t, err, ErrInvalidMsgPack, "%x", data)
+	}
+	_, err = FromMsgPack([]byte{0x81, 0xa1, 'a', 0xd4, 0x40, 0x00})
+	require.ErrorIs(t, err, ErrUnsupportedType)
+	_, err = FromMsgPack([]byte{0x81, 0xa1, 'a', 0xcf, 0xff, 0, 0, 0, 0, 0, 0, 0})
+	require.ErrorIs(t, err, ErrLossyConversion)
+	_, err = FromMsgPack([]byte{0x81, 0xa2, 'a', 0, 0x01})
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = FromMsgPack([]byte{0x91, 0x01})
+	require.ErrorIs(t, err, ErrNotObject)
+}
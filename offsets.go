@@ -0,0 +1,63 @@
+package gbson
+
+// FieldOffset is an entry of the field offset table of a document, locating a value in the raw bytes.
+type FieldOffset struct {
+	Path   string // dotted path of the element as GetPath takes, with indexes as the keys of array items
+	Type   Type
+	Offset int // offset in bytes of the value in the document
+	Length int // length in bytes of the value
+}
+
+// FieldOffsets returns the offset table of all the elements of the document in order, including the ones of
+// embedded documents and arrays following their container's entry, so side indexes persisted alongside the
+// raw bytes could jump straight to values by Value. Elements of duplicate keys all have entries.
+// The error is the one of scanning a malformed document, see Result.Err.
+func FieldOffsets(doc []byte) ([]FieldOffset, error) {
+	table, err := appendFieldOffsets(nil, doc, 0, "", 1)
+	if err != nil {
+		return nil, locateError(doc, err)
+	}
+	return table, nil
+}
+
+// appendFieldOffsets appends the entries of the container raw at the offset of the document and the depth.
+func appendFieldOffsets(table []FieldOffset, raw []byte, offset int, prefix string, depth int) ([]FieldOffset, error) {
+	if err := checkDepth(depth); err != nil {
+		return table, err
+	}
+	elements, ok := containerElements(raw)
+	if !ok {
+		return table, ErrInvalidLength
+	}
+	pos := offset + 4
+	for len(elements) > 0 {
+		tp, name, value, n := consumeElement(elements)
+		if n < 0 {
+			return table, ErrInvalidLength
+		}
+		elements = elements[n:]
+		valueOffset := pos + n - len(value)
+		pos += n
+		path := string(name)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		table = append(table, FieldOffset{Path: path, Type: tp, Offset: valueOffset, Length: len(value)})
+		if tp == BSONTypeObject || tp == BSONTypeArray {
+			var err error
+			if table, err = appendFieldOffsets(table, value, valueOffset, path, depth+1); err != nil {
+				return table, err
+			}
+		}
+	}
+	return table, nil
+}
+
+// Value returns the value located by the entry in the document the table is of, without scanning it.
+// It returns a missing result if the entry is beyond the document.
+func (f FieldOffset) Value(doc []byte) Result {
+	if f.Offset < 0 || f.Length < 0 || f.Offset > len(doc)-f.Length {
+		return Result{Type: BSONTypeUndefined}
+	}
+	return Result{Type: f.Type, Raw: doc[f.Offset : f.Offset+f.Length : f.Offset+f.Length]}
+}
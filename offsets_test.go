@@ -0,0 +1,39 @@
+package gbson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFieldOffsets(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	table, err := FieldOffsets(doc)
+	require.NoError(t, err)
+	var paths []string
+	for _, f := range table {
+		paths = append(paths, f.Path)
+		expected := GetPath(doc, f.Path)
+		actual := f.Value(doc)
+		require.Equal(t, expected.Type, actual.Type, f.Path)
+		require.Equal(t, string(expected.Raw), string(actual.Raw), f.Path)
+	}
+	require.Contains(t, paths, "doc.b.2")
+	require.Len(t, table, Get(doc).Length()+5) // doc has a, b, and b has 3 items
+
+	dup := mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}, {Key: "a", Value: int32(2)}})
+	table, err = FieldOffsets(dup)
+	require.NoError(t, err)
+	require.Equal(t, []FieldOffset{
+		{Path: "a", Type: BSONTypeInt32, Offset: 7, Length: 4},
+		{Path: "a", Type: BSONTypeInt32, Offset: 14, Length: 4},
+	}, table)
+	require.Equal(t, int32(2), table[1].Value(dup).Int32())
+	require.False(t, table[1].Value(dup[:17]).Exist())
+
+	_, err = FieldOffsets(doc[:len(doc)-1])
+	require.ErrorIs(t, err, ErrInvalidLength)
+	_, err = FieldOffsets(nestedDocument(DefaultMaxDepth + 1))
+	require.ErrorIs(t, err, ErrMaxDepth)
+}
@@ -0,0 +1,44 @@
+package gbson
+
+// GetOptions are the options of a Get call, e.g. the limits of a request handler, which apply to the call only.
+// Zero fields are the defaults of Get, like the ones of Validator.
+type GetOptions struct {
+	// MaxDepth limits the length of the path, longer ones fail with ErrMaxDepth. If zero, DefaultMaxDepth is used.
+	MaxDepth int
+	// MaxElements limits the elements the call visits, including the ones compared and skipped in every
+	// document on the path, beyond which the call fails with ErrBudgetExceeded. It protects request handlers
+	// from documents engineered to have enormous flat lists of elements. Zero means no limit.
+	MaxElements int
+	// UnknownTypes is the policy for unknown types, which fail the call if nil.
+	UnknownTypes *UnknownTypePolicy
+	// StrictStrings makes String, StringBytes and StringE of the returned value verify the text is valid UTF-8
+	// without zero bytes, for texts forwarded to systems failing hard on invalid encodings. Invalid texts are
+	// "" for String, nil for StringBytes and ErrInvalidValue for StringE.
+	StrictStrings bool
+}
+
+// GetLimited is Get with the options of the call, e.g. the limits of a request handler:
+//
+//	r := gbson.GetLimited(body, gbson.GetOptions{MaxElements: 1000}, "user", "name")
+//	if err := r.Err(); err != nil {
+//		...
+//	}
+func GetLimited(doc []byte, opts GetOptions, path ...string) Result {
+	return resultFromBytes(doc).GetLimited(opts, path...)
+}
+
+// GetLimited is Result.Get with the options of the call, see GetLimited.
+func (r Result) GetLimited(opts GetOptions, path ...string) Result {
+	if len(path) > 0 {
+		limits := getLimits{maxDepth: opts.MaxDepth, budget: opts.MaxElements, unknownTypes: opts.UnknownTypes}
+		if limits.maxDepth == 0 {
+			limits.maxDepth = DefaultMaxDepth
+		}
+		if limits.unknownTypes == nil {
+			limits.unknownTypes = &defaultUnknownTypes
+		}
+		r = r.get(limits, path)
+	}
+	r.strict = r.strict || opts.StrictStrings
+	return r
+}
@@ -0,0 +1,59 @@
+package gbson
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGetLimited(t *testing.T) {
+	var d bson.D
+	for i := 0; i < 10; i++ {
+		d = append(d, bson.E{Key: fmt.Sprint("k", i), Value: bson.D{{Key: "a", Value: int32(i)}, {Key: "b", Value: int32(i)}}})
+	}
+	doc := mustMarshal(t, d)
+	limited := GetOptions{MaxElements: 7} // k0 to k4, a and b
+
+	// calls with their own limits run concurrently without affecting each other
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if GetLimited(doc, limited, "k4", "b").Int32() != 4 || GetLimited(doc, limited, "k5", "b").Exist() ||
+					Get(doc, "k9", "b").Int32() != 9 {
+					t.Error("limits leaked across calls")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	require.ErrorIs(t, GetLimited(doc, limited, "k5", "b").Err(), ErrBudgetExceeded)
+	require.ErrorIs(t, Get(doc).GetLimited(GetOptions{MaxDepth: 1}, "k1", "a").Err(), ErrMaxDepth)
+	require.Equal(t, int32(1), Get(doc).GetLimited(GetOptions{MaxDepth: 2}, "k1", "a").Int32())
+	require.Equal(t, Get(doc), GetLimited(doc, limited))
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		GetLimited(doc, limited, "k4", "b")
+	}))
+
+	unknown := mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}, {Key: "x", Value: "future"}, {Key: "b", Value: int32(2)}})
+	unknown[11] = 0x20 // x is of an unknown type laid out like strings
+	skip := &UnknownTypePolicy{Mode: UnknownTypeSkip, Length: func(tp Type, value []byte) int {
+		return int(consumeInt32(value)) + 4
+	}}
+	require.Equal(t, int32(2), GetLimited(unknown, GetOptions{UnknownTypes: skip}, "b").Int32())
+	require.False(t, Get(unknown, "b").Exist(), "the package level policy is kept")
+
+	bad := mustMarshal(t, bson.D{{Key: "s", Value: "bad\xff"}})
+	require.Equal(t, "bad\xff", Get(bad, "s").String())
+	strict := GetLimited(bad, GetOptions{StrictStrings: true}, "s")
+	require.Equal(t, "", strict.String())
+	require.Nil(t, strict.StringBytes())
+	_, err := strict.StringE()
+	require.ErrorIs(t, err, ErrInvalidValue)
+}
@@ -0,0 +1,58 @@
+package gbson
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// IterArrayParallel calls fn with the indexes and the items of the array across goroutines, after a single
+// pass finding the boundaries of the items, for arrays of many items which are costly to process, e.g. to
+// decode. Items are taken by the goroutines one by one in order, so fn must be safe for concurrent use.
+// workers is the number of goroutines, GOMAXPROCS if not positive.
+//
+// It returns the error of a malformed array before calling fn like IterArrayE, or the first error returned
+// by fn with the index of the item, after which no more items are taken.
+func (r Result) IterArrayParallel(workers int, fn func(i int, r Result) error) error {
+	var items []Result
+	if err := r.IterArrayE(func(item Result) bool {
+		items = append(items, item)
+		return true
+	}); err != nil {
+		return err
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+	var next int64 = -1
+	var failed int32
+	var once sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&failed) == 0 {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(items) {
+					return
+				}
+				if err := fn(i, items[i]); err != nil {
+					once.Do(func() {
+						firstErr = errors.WithMessagef(err, "item %d", i)
+						atomic.StoreInt32(&failed, 1)
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,50 @@
+package gbson
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestIterArrayParallel(t *testing.T) {
+	a := make(bson.A, 1000)
+	for i := range a {
+		a[i] = bson.D{{Key: "n", Value: int64(i)}}
+	}
+	doc := mustMarshal(t, bson.D{{Key: "a", Value: a}})
+	for _, workers := range []int{0, 1, 4, 2000} {
+		var sum, calls int64
+		require.NoError(t, Get(doc, "a").IterArrayParallel(workers, func(i int, r Result) error {
+			if n := r.Get("n").Int64(); n != int64(i) {
+				return errors.Errorf("n is %d", n)
+			}
+			atomic.AddInt64(&sum, r.Get("n").Int64())
+			atomic.AddInt64(&calls, 1)
+			return nil
+		}))
+		require.Equal(t, int64(1000), calls)
+		require.Equal(t, int64(999*1000/2), sum)
+	}
+
+	errBad := errors.New("bad")
+	var calls int64
+	err := Get(doc, "a").IterArrayParallel(4, func(i int, r Result) error {
+		atomic.AddInt64(&calls, 1)
+		if i == 10 {
+			return errBad
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, errBad)
+	require.Contains(t, err.Error(), "item 10")
+	require.Less(t, calls, int64(1000))
+
+	require.NoError(t, Get(mustMarshal(t, bson.D{{Key: "a", Value: bson.A{}}}), "a").IterArrayParallel(4, nil))
+	require.ErrorIs(t, Get(doc).IterArrayParallel(4, nil), ErrTypeMismatch)
+	bad := Get(doc, "a")
+	bad.Raw = bad.Raw[:len(bad.Raw)-1]
+	require.ErrorIs(t, bad.IterArrayParallel(4, nil), ErrInvalidLength)
+}
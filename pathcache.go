@@ -0,0 +1,104 @@
+package gbson
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// PathCache is an LRU cache of the offsets of values by documents and paths, for workloads querying the same
+// few paths against the same immutable buffers repeatedly. It's safe for concurrent use, so it could be
+// global as well as per document.
+//
+// Documents are identified by their buffers, which are kept alive while cached. Cached offsets are checked
+// against the headers of the elements on hits, and looked up again on mismatches, but the check is not
+// exhaustive, so buffers must not be modified while cached: Purge clears the cache before reusing them.
+// Missing values are not cached.
+type PathCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[pathCacheKey]*list.Element
+	lru     *list.List // of *pathCacheEntry, the most recently used first
+}
+
+type pathCacheKey struct {
+	doc  *byte // the first byte of the document
+	n    int   // length of the document
+	path string
+}
+
+type pathCacheEntry struct {
+	key            pathCacheKey
+	tp             Type
+	offset, length int // of the value in the document
+}
+
+// NewPathCache returns a cache of up to size paths.
+func NewPathCache(size int) *PathCache {
+	return &PathCache{size: size, entries: make(map[pathCacheKey]*list.Element), lru: list.New()}
+}
+
+// Get gets the first value by the given path like Get, from the cache if the path of the document is cached.
+func (c *PathCache) Get(doc []byte, path ...string) Result {
+	if len(doc) == 0 || len(path) == 0 {
+		return Get(doc, path...)
+	}
+	key := pathCacheKey{doc: &doc[0], n: len(doc), path: path[0]}
+	if len(path) > 1 {
+		key.path = strings.Join(path, "\x00") // keys never have zero bytes
+	}
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*pathCacheEntry)
+		if e.valid(doc, path[len(path)-1]) {
+			c.lru.MoveToFront(elem)
+			c.mu.Unlock()
+			return Result{Type: e.tp, Raw: doc[e.offset : e.offset+e.length]}
+		}
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+
+	r := Get(doc, path...)
+	if !r.Exist() || c.size <= 0 {
+		return r
+	}
+	// the value is a part of the document, whose offset is told by the capacities
+	e := &pathCacheEntry{key: key, tp: r.Type, offset: cap(doc) - cap(r.Raw), length: len(r.Raw)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return r // cached concurrently
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*pathCacheEntry).key)
+	}
+	return r
+}
+
+// valid checks the cached value against the header of its element with the key.
+func (e *pathCacheEntry) valid(doc []byte, key string) bool {
+	start := e.offset - len(key) - 2
+	return start >= 0 && e.offset+e.length <= len(doc) &&
+		Type(doc[start]) == e.tp && bytesEqualToString(doc[start+1:e.offset-1], key) && doc[e.offset-1] == 0 &&
+		declaredValueLen(e.tp, doc[e.offset:]) == e.length
+}
+
+// Len returns the number of cached paths.
+func (c *PathCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Purge removes all the cached paths, releasing the documents.
+func (c *PathCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[pathCacheKey]*list.Element)
+	c.lru.Init()
+}
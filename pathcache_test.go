@@ -0,0 +1,32 @@
+package gbson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPathCache(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	c := NewPathCache(2)
+	for _, path := range [][]string{{"int32"}, {"doc", "b", "1"}, {"int32"}, {"missing"}, {"null"}} {
+		for i := 0; i < 2; i++ {
+			require.Equal(t, Get(doc, path...), c.Get(doc, path...), "%v", path)
+		}
+	}
+	require.Equal(t, 2, c.Len(), "int32 is evicted by null")
+	require.Equal(t, Get(doc, "int32"), c.Get(doc, "int32"))
+	// the same path of another document
+	other := mustMarshal(t, bson.D{{Key: "int32", Value: int32(3)}})
+	require.Equal(t, int32(3), c.Get(other, "int32").Int32())
+
+	// cached offsets are checked on hits
+	buf := mustMarshal(t, bson.D{{Key: "a", Value: "x"}, {Key: "b", Value: int32(1)}})
+	require.Equal(t, int32(1), c.Get(buf, "b").Int32())
+	copy(buf, mustMarshal(t, bson.D{{Key: "b", Value: int32(2)}, {Key: "a", Value: "x"}}))
+	require.Equal(t, int32(2), c.Get(buf, "b").Int32())
+	c.Purge()
+	require.Zero(t, c.Len())
+	require.Equal(t, int32(2), c.Get(buf, "b").Int32())
+}
@@ -0,0 +1,9 @@
+//go:build purego || appengine
+
+package gbson
+
+// bytesEqualToString is the implementation without unsafe for the purego or appengine build tags,
+// the compiler compares the conversion without allocating, so it's as fast.
+func bytesEqualToString(left []byte, right string) bool {
+	return string(left) == right
+}
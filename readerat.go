@@ -0,0 +1,164 @@
+package gbson
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// GetReaderAt gets the first value by the given path from the document at the offset of r, such as a huge
+// document in a file, reading only the headers of the elements up to the value and the value itself.
+// Siblings are skipped by their lengths without reading them. The value is copied from r.
+// A missing value is not an error, as Get. Errors are the ones of reading r, or ErrInvalidLength wrapped
+// with the offset for malformed documents.
+func GetReaderAt(r io.ReaderAt, offset int64, path ...string) (Result, error) {
+	if err := checkDepth(len(path)); err != nil {
+		return Result{Type: BSONTypeUndefined}, err
+	}
+	w := readerAtWalker{r: r}
+	tp := BSONTypeObject
+	for i := 0; ; i++ {
+		n, err := w.int32At(offset)
+		if err != nil {
+			return Result{Type: BSONTypeUndefined}, err
+		}
+		if i == len(path) {
+			raw, err := w.read(offset, n)
+			return Result{Type: tp, Raw: raw}, err
+		}
+		if n < 5 {
+			return Result{Type: BSONTypeUndefined}, errors.Wrapf(ErrInvalidLength, "document at %d declares %d bytes", offset, n)
+		}
+		var found bool
+		if tp, offset, n, found, err = w.find(offset+4, offset+int64(n)-1, path[i]); err != nil || !found {
+			return Result{Type: BSONTypeUndefined}, err
+		}
+		if i == len(path)-1 {
+			raw, err := w.read(offset, n)
+			return Result{Type: tp, Raw: raw}, err
+		}
+		if tp != BSONTypeObject && tp != BSONTypeArray {
+			return Result{Type: BSONTypeUndefined}, nil
+		}
+	}
+}
+
+// readerAtWalker reads the elements of a document through an io.ReaderAt.
+type readerAtWalker struct {
+	r   io.ReaderAt
+	buf [64]byte
+}
+
+// find finds the first element of the key between the offsets, and returns the type, the offset and the length
+// of its value.
+func (w *readerAtWalker) find(pos, end int64, key string) (tp Type, offset int64, n int, found bool, err error) {
+	for pos < end {
+		header, err := w.cstringAt(pos, end) // the type and the key
+		if err != nil {
+			return tp, 0, 0, false, err
+		}
+		if len(header) == 0 {
+			return tp, 0, 0, false, errors.Wrapf(ErrInvalidLength, "element at %d has no type", pos)
+		}
+		tp, found = Type(header[0]), bytesEqualToString(header[1:], key)
+		offset = pos + int64(len(header)) + 1
+		if n, err = w.valueLen(tp, offset, end); err != nil {
+			return tp, 0, 0, false, err
+		}
+		if found {
+			return tp, offset, n, true, nil
+		}
+		pos = offset + int64(n)
+	}
+	return tp, 0, 0, false, nil
+}
+
+// valueLen returns the length of the value of the type at the offset, which ends before end.
+func (w *readerAtWalker) valueLen(tp Type, offset, end int64) (int, error) {
+	var n int
+	switch tp {
+	case BSONTypeRegex:
+		pattern, err := w.cstringAt(offset, end)
+		if err != nil {
+			return 0, err
+		}
+		options, err := w.cstringAt(offset+int64(len(pattern))+1, end)
+		if err != nil {
+			return 0, err
+		}
+		n = len(pattern) + len(options) + 2
+	case BSONTypeString, BSONTypeJavaScript, BSONTypeSymbol, BSONTypeObject, BSONTypeArray,
+		BSONTypeBinary, BSONTypeDBPointer, BSONTypeJavaScriptWithScope:
+		prefix := w.buf[:4]
+		if _, err := w.readFull(prefix, offset); err != nil {
+			return 0, err
+		}
+		n = declaredValueLen(tp, prefix)
+	default:
+		if n = declaredValueLen(tp, nil); n < 0 {
+			return 0, errors.Wrapf(ErrUnsupportedType, "type %v at %d", tp, offset-1)
+		}
+	}
+	if n < 0 || offset+int64(n) > end {
+		return 0, errors.Wrapf(ErrInvalidLength, "%v value at %d exceeds the document", tp, offset)
+	}
+	return n, nil
+}
+
+// cstringAt reads the zero terminated string at the offset, which ends before end.
+// The string may refer to the buffer of w until the next read.
+func (w *readerAtWalker) cstringAt(offset, end int64) ([]byte, error) {
+	var s []byte
+	for pos := offset; pos < end; {
+		chunk := w.buf[:]
+		if rest := end - pos; rest < int64(len(chunk)) {
+			chunk = chunk[:rest]
+		}
+		if _, err := w.readFull(chunk, pos); err != nil {
+			return nil, err
+		}
+		if i := bytes.IndexByte(chunk, 0); i >= 0 {
+			return append(s, chunk[:i]...), nil
+		}
+		s = append(s, chunk...)
+		pos += int64(len(chunk))
+	}
+	return nil, errors.Wrapf(ErrInvalidLength, "key at %d is not terminated", offset)
+}
+
+// int32At reads the length prefix at the offset.
+func (w *readerAtWalker) int32At(offset int64) (int, error) {
+	if _, err := w.readFull(w.buf[:4], offset); err != nil {
+		return 0, err
+	}
+	return int(consumeInt32(w.buf[:4])), nil
+}
+
+// read reads n bytes at the offset into a new slice. The last byte is read first, so a corrupt length beyond
+// the end of r fails before allocating.
+func (w *readerAtWalker) read(offset int64, n int) ([]byte, error) {
+	if n < 0 {
+		return nil, errors.Wrapf(ErrInvalidLength, "value at %d declares %d bytes", offset, n)
+	}
+	if n > 0 {
+		if _, err := w.readFull(w.buf[:1], offset+int64(n)-1); err != nil {
+			return nil, err
+		}
+	}
+	b := make([]byte, n)
+	_, err := w.readFull(b, offset)
+	return b, err
+}
+
+// readFull reads len(b) bytes at the offset, the end of r is ErrInvalidLength as the document is cut short.
+func (w *readerAtWalker) readFull(b []byte, offset int64) (int, error) {
+	n, err := w.r.ReadAt(b, offset)
+	if n == len(b) {
+		return n, nil
+	}
+	if err == io.EOF {
+		err = errors.Wrapf(ErrInvalidLength, "%d bytes at %d, %d available", len(b), offset, n)
+	}
+	return n, err
+}
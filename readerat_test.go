@@ -0,0 +1,71 @@
+package gbson
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// countingReaderAt counts the bytes read through it.
+type countingReaderAt struct {
+	r     *bytes.Reader
+	bytes int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.bytes += n
+	return n, err
+}
+
+func TestGetReaderAt(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	paths := [][]string{
+		{}, {"int32"}, {"doc", "b", "1"}, {"doc", "b", "2"}, {"doc", "missing"}, {"int32", "x"}, {"missing"}, {"empty", "0"},
+	}
+	for _, path := range paths {
+		r, err := GetReaderAt(bytes.NewReader(doc), 0, path...)
+		require.NoError(t, err, "%v", path)
+		expected := Get(doc, path...)
+		require.Equal(t, expected.Type, r.Type, "%v", path)
+		require.Equal(t, string(expected.Raw), string(r.Raw), "%v", path)
+	}
+
+	// at an offset of the reader
+	r, err := GetReaderAt(bytes.NewReader(append([]byte("header"), doc...)), 6, "doc", "b", "1")
+	require.NoError(t, err)
+	require.Equal(t, Get(doc, "doc", "b", "1").Str(), r.Str())
+
+	// siblings are skipped without reading them
+	huge := mustMarshal(t, bson.D{
+		{Key: "blob", Value: primitive.Binary{Data: make([]byte, 1<<20)}},
+		{Key: "a", Value: bson.D{{Key: "large", Value: make([]byte, 1<<20)}, {Key: "b", Value: "x"}}},
+	})
+	c := &countingReaderAt{r: bytes.NewReader(huge)}
+	r, err = GetReaderAt(c, 0, "a", "b")
+	require.NoError(t, err)
+	require.Equal(t, "x", r.Str())
+	require.Less(t, c.bytes, 1024)
+
+	// corrupt lengths beyond the end of the reader fail before allocating
+	overDeclared := []byte{0xff, 0xff, 0xff, 0x7f, byte(BSONTypeString), 'a', 0, 0, 0, 0, 0x40, 'x'}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = GetReaderAt(bytes.NewReader(overDeclared), 0, "a")
+	runtime.ReadMemStats(&after)
+	require.ErrorIs(t, err, ErrInvalidLength)
+	require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+	_, err = GetReaderAt(bytes.NewReader(overDeclared), 0)
+	require.ErrorIs(t, err, ErrInvalidLength)
+
+	_, err = GetReaderAt(bytes.NewReader(doc[:len(doc)/2]), 0, "missing")
+	require.ErrorIs(t, err, ErrInvalidLength)
+	_, err = GetReaderAt(bytes.NewReader(doc[:3]), 0, "int32")
+	require.ErrorIs(t, err, ErrInvalidLength)
+	_, err = GetReaderAt(bytes.NewReader(doc), 0, make([]string, DefaultMaxDepth+1)...)
+	require.ErrorIs(t, err, ErrMaxDepth)
+}
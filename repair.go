@@ -0,0 +1,90 @@
+package gbson
+
+// Repair salvages a damaged document, such as a truncated one from a partially corrupted dump, into a new
+// well-formed document of all the elements which parse cleanly, and reports what is dropped.
+//
+// Elements with invalid values are dropped individually. Once the boundary of the next element is lost,
+// e.g. by an unknown type or a length beyond the available bytes or negative, the rest of the document is dropped,
+// but the elements before it are kept. Embedded documents and arrays are repaired recursively, and the
+// items of repaired arrays are renumbered. The findings have the offsets in the damaged document.
+func Repair(doc []byte) ([]byte, []Finding) {
+	v := validator{Validator: Validator{MaxDepth: DefaultMaxDepth}}
+	out := v.repairContainer(nil, doc, BSONTypeObject, 0)
+	return out, v.findings
+}
+
+// repairContainer appends the repaired document or array raw at the offset of the damaged document.
+func (v *validator) repairContainer(dst []byte, raw []byte, containerType Type, offset int) []byte {
+	dst, docStart := beginDocument(dst)
+	if len(raw) < 5 {
+		v.report(LengthError{Expected: 5, Actual: len(raw)}, offset, "%d bytes are too short for a document, dropped", len(raw))
+		return endDocument(dst, docStart)
+	}
+	n := int(consumeInt32(raw))
+	end := n - 1
+	switch {
+	case n < 5 || n > len(raw):
+		v.report(LengthError{Expected: n, Actual: len(raw)}, offset, "declares %d bytes, %d available, salvaging the available", n, len(raw))
+		end = len(raw)
+		if raw[end-1] == 0 {
+			end--
+		}
+	case raw[end] != 0:
+		v.report(ErrInvalidLength, offset+end, "document is not terminated by a zero byte, salvaging the elements")
+		end = n
+	}
+	if v.depth == v.MaxDepth {
+		v.report(ErrMaxDepth, offset, "nested deeper than %d, dropped", v.MaxDepth)
+		return endDocument(dst, docStart)
+	}
+	v.depth++
+	depth := len(v.path)
+	defer func() { v.path, v.depth = v.path[:depth], v.depth-1 }()
+	index := 0
+	for pos := 4; pos < end; {
+		tp := Type(raw[pos])
+		name, nameLen := consumeCString(raw[pos+1 : end])
+		if nameLen == 0 {
+			v.report(ErrInvalidLength, offset+pos, "key is not terminated, dropped with the rest")
+			break
+		}
+		v.path = append(v.path[:depth], name)
+		if _, ok := typeNames[tp]; !ok {
+			v.report(ErrUnsupportedType, offset+pos, "type %v, dropped with the rest", tp)
+			break
+		}
+		start := pos + 1 + nameLen
+		value := raw[start:end]
+		valueLen := consumeValue(tp, value)
+		if valueLen < 0 && tp != BSONTypeObject && tp != BSONTypeArray {
+			v.report(ErrInvalidLength, offset+start, "%v value exceeds the document, dropped with the rest", tp)
+			break
+		}
+		if valueLen >= 0 {
+			value = value[:valueLen]
+		}
+		if tp != BSONTypeObject && tp != BSONTypeArray {
+			findings := len(v.findings)
+			if !v.validateValue(tp, value, offset+start) {
+				break // the rest is dropped as the length is not reliable
+			}
+			if len(v.findings) > findings {
+				pos = start + valueLen
+				continue
+			}
+		}
+		if containerType == BSONTypeArray {
+			dst = appendIndexHeader(dst, tp, index)
+		} else {
+			dst = append(append(append(dst, byte(tp)), name...), 0)
+		}
+		index++
+		if tp != BSONTypeObject && tp != BSONTypeArray {
+			dst = append(dst, value...)
+		} else if dst = v.repairContainer(dst, value, tp, offset+start); valueLen < 0 {
+			break // the truncated container takes the rest
+		}
+		pos = start + valueLen
+	}
+	return endDocument(dst, docStart)
+}
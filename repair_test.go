@@ -0,0 +1,64 @@
+package gbson
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRepair(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "a", Value: int32(1)},
+		{Key: "b", Value: bson.D{{Key: "x", Value: "s"}, {Key: "y", Value: int32(2)}}},
+		{Key: "c", Value: bson.A{int32(1), "z", int32(3)}},
+	})
+	out, findings := Repair(doc)
+	require.Equal(t, doc, out)
+	require.Empty(t, findings)
+
+	// truncated in the middle of b.y: a and b.x are kept
+	// a at 4, b at 11, its document at 14, "x" at 18, "y" at 27 and its value at 30
+	out, findings = Repair(doc[:31])
+	require.Equal(t, mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}, {Key: "b", Value: bson.D{{Key: "x", Value: "s"}}}}), out)
+	require.Equal(t, []Finding{
+		{Offset: 0, Path: "", Reason: "declares 67 bytes, 31 available, salvaging the available", Cause: LengthError{Expected: 67, Actual: 31}},
+		{Offset: 14, Path: "b", Reason: "declares 21 bytes, 17 available, salvaging the available", Cause: LengthError{Expected: 21, Actual: 17}},
+		{Offset: 30, Path: "b.y", Reason: "int value exceeds the document, dropped with the rest", Cause: ErrInvalidLength},
+	}, findings)
+
+	// a negative length loses the boundary of the next element, so the rest is dropped
+	// c at 35, its array at 38, "1" at 49 and its value at 52
+	bad := append([]byte(nil), doc...)
+	binary.LittleEndian.PutUint32(bad[52:], 0xffffffff)
+	out, findings = Repair(bad)
+	require.Equal(t, mustMarshal(t, bson.D{
+		{Key: "a", Value: int32(1)},
+		{Key: "b", Value: bson.D{{Key: "x", Value: "s"}, {Key: "y", Value: int32(2)}}},
+		{Key: "c", Value: bson.A{int32(1)}},
+	}), out)
+	require.Equal(t, []Finding{{Offset: 52, Path: "c.1", Reason: "string declares -1 bytes", Cause: ErrInvalidLength}}, findings)
+	require.NoError(t, Validate(out))
+
+	// invalid values of reliable lengths are dropped individually and items are renumbered
+	// c at 4, its array at 7, "1" at 18, its value at 21 and the code at 25
+	bad = mustMarshal(t, bson.D{{Key: "c", Value: bson.A{int32(1), primitive.CodeWithScope{Code: "x", Scope: bson.D{}}, int32(3)}}})
+	binary.LittleEndian.PutUint32(bad[25:], 0)
+	out, findings = Repair(bad)
+	require.Equal(t, mustMarshal(t, bson.D{{Key: "c", Value: bson.A{int32(1), int32(3)}}}), out)
+	require.Equal(t, []Finding{{Offset: 25, Path: "c.1", Reason: "code declares 0 bytes", Cause: ErrInvalidLength}}, findings)
+
+	// unknown types drop the rest
+	bad = append([]byte(nil), doc...)
+	bad[11] = 0x20
+	out, findings = Repair(bad)
+	require.Equal(t, mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}}), out)
+	require.Len(t, findings, 1)
+	require.ErrorIs(t, findings[0], ErrUnsupportedType)
+
+	out, findings = Repair(nil)
+	require.Equal(t, []byte{5, 0, 0, 0, 0}, out)
+	require.Len(t, findings, 1)
+}
@@ -0,0 +1,29 @@
+package gbson
+
+import "github.com/pkg/errors"
+
+// Safe calls f, which traverses documents or calls accessors, and converts a panic in it into an error
+// wrapping ErrPanic, e.g. of a bug on unforeseen malformed input or a panicking callback of IterDocument
+// or Walk. The accessors are not supposed to panic, Safe is the last line of defense for untrusted ingest
+// paths which prefer availability to the small cost of the deferred recovery.
+//
+//	name, err := gbson.Safe(func() (string, error) {
+//		return gbson.Get(doc, "user", "name").StringE()
+//	})
+func Safe[T any](f func() (T, error)) (v T, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			var zero T
+			v, err = zero, errors.Wrapf(ErrPanic, "%v", p)
+		}
+	}()
+	return f()
+}
+
+// SafeGet is Get in Safe, the error is the one carried by the value, see Result.Err, or of a panic.
+func SafeGet(doc []byte, path ...string) (Result, error) {
+	return Safe(func() (Result, error) {
+		r := Get(doc, path...)
+		return r, r.err
+	})
+}
@@ -0,0 +1,47 @@
+package gbson
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSafe(t *testing.T) {
+	doc := mustMarshal(t, bson.D{{Key: "a", Value: bson.A{"x", "y"}}})
+	s, err := Safe(func() (string, error) {
+		return Get(doc, "a", "1").StringE()
+	})
+	require.NoError(t, err)
+	require.Equal(t, "y", s)
+
+	_, err = Safe(func() (string, error) {
+		return Get(doc, "b").StringE()
+	})
+	require.ErrorIs(t, err, ErrNotExist)
+
+	n, err := Safe(func() (int, error) {
+		var items []Result
+		Get(doc, "a").IterArray(func(r Result) bool {
+			items = append(items, r)
+			return true
+		})
+		return len(items[:3]), nil
+	})
+	require.ErrorIs(t, err, ErrPanic)
+	require.Zero(t, n)
+
+	_, err = Safe(func() (struct{}, error) {
+		panic(errors.New("boom"))
+	})
+	require.ErrorIs(t, err, ErrPanic)
+	require.Contains(t, err.Error(), "boom")
+
+	r, err := SafeGet(doc, "a", "0")
+	require.NoError(t, err)
+	require.Equal(t, "x", r.String())
+	r, err = SafeGet(doc[:len(doc)-1], "a")
+	require.ErrorIs(t, err, ErrInvalidLength)
+	require.False(t, r.Exist())
+}
@@ -0,0 +1,314 @@
+package gbson
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Decoder reads bson documents one after another from a stream, such as the output of mongodump.
+type Decoder struct {
+	r          io.Reader
+	count      int // number of documents read
+	maxSize    int // max declared length of documents if positive
+	quarantine func(start, end int64, err error)
+	buf        []byte // bytes read but not consumed in the quarantine mode
+	offset     int64  // offset of buf in the stream
+	readErr    error  // error of reading r in the quarantine mode, io.EOF at the end
+	header     [4]byte
+	transient  bool
+	doc        []byte // the last document in the transient mode, its buffer is reused
+}
+
+// decoderBuffers pools the buffers released by transient decoders, see Decoder.Release.
+var decoderBuffers sync.Pool // *[]byte
+
+// NewDecoder returns a decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// SetMaxDocumentSize limits the declared length of documents, larger ones are rejected with ErrTooLarge
+// before allocating their buffers. Zero means no limit, which is the default, where the buffers grow as
+// the bytes arrive, so a truncated stream declaring a huge document doesn't allocate its declared length.
+func (d *Decoder) SetMaxDocumentSize(n int) {
+	d.maxSize = n
+}
+
+// quarantineMaxSize is the max document size of the quarantine mode if not set, which is the one of MongoDB.
+const quarantineMaxSize = 16 << 20
+
+// SetQuarantine makes the decoder skip corrupt documents rather than failing, so one bad record doesn't lose
+// the rest of a dump. A document failing Validate or declaring an implausible length is skipped by scanning
+// forward for the next offset where a valid document starts, and the skipped range [start, end) of the stream
+// is passed to fn with the error of the corrupt document. Trailing bytes with no documents are skipped
+// the same before io.EOF. Errors of reading the stream are still returned.
+//
+// Documents are limited by the max document size, 16 MiB if not set, which bounds the memory used for
+// scanning. It must be called before reading.
+func (d *Decoder) SetQuarantine(fn func(start, end int64, err error)) {
+	d.quarantine = fn
+}
+
+// SetTransient makes the decoder read every document into the same buffer, so reading doesn't allocate
+// in the steady state, but a document returned by Next is only valid until the following Next.
+// Detach takes a document for retaining it. It must be called before reading.
+func (d *Decoder) SetTransient(transient bool) {
+	d.transient = transient
+}
+
+// Next reads the next document, it returns io.EOF at the end of the stream,
+// and io.ErrUnexpectedEOF if the stream ends in the middle of a document.
+// In the transient mode, the document is overwritten by the following Next.
+func (d *Decoder) Next() ([]byte, error) {
+	if !d.transient {
+		return d.readDocument(nil)
+	}
+	if d.doc == nil {
+		if buf, ok := decoderBuffers.Get().(*[]byte); ok {
+			d.doc = *buf
+		}
+	}
+	doc, err := d.readDocument(d.doc)
+	if err != nil {
+		d.doc = d.doc[:0]
+		return nil, err
+	}
+	d.doc = doc
+	return doc, nil
+}
+
+// Detach takes the document last returned by Next in the transient mode, which stays valid as the decoder
+// reads the following documents into a new buffer. It returns nil if there's no document to take.
+func (d *Decoder) Detach() []byte {
+	doc := d.doc
+	if len(doc) == 0 {
+		return nil
+	}
+	d.doc = nil
+	return doc[:len(doc):len(doc)]
+}
+
+// Release puts the buffer of the transient mode into a pool shared by decoders, for consumers decoding
+// many streams one after another. The document last returned by Next is invalid after it.
+func (d *Decoder) Release() {
+	if cap(d.doc) > 0 {
+		buf := d.doc[:0]
+		decoderBuffers.Put(&buf)
+	}
+	d.doc = nil
+}
+
+// readDocument reads the next document into dst, which is reused if large enough.
+func (d *Decoder) readDocument(dst []byte) ([]byte, error) {
+	if d.quarantine != nil {
+		return d.readQuarantined(dst)
+	}
+	if _, err := io.ReadFull(d.r, d.header[:]); err != nil {
+		return nil, err
+	}
+	n := int(consumeInt32(d.header[:]))
+	if n < 5 {
+		return nil, errors.Wrapf(ErrInvalidLength, "document %d declares %d bytes", d.count, n)
+	}
+	if d.maxSize > 0 && n > d.maxSize {
+		return nil, errors.Wrapf(ErrTooLarge, "document %d declares %d bytes, limit %d", d.count, n, d.maxSize)
+	}
+	if cap(dst) < n && cap(dst) < decoderInitialSize {
+		size := decoderInitialSize
+		if n < size {
+			size = n
+		}
+		dst = make([]byte, 0, size)
+	}
+	dst, err := readGrowing(d.r, append(dst[:0], d.header[:]...), n)
+	if err != nil {
+		return nil, err
+	}
+	if dst[n-1] != 0 {
+		return nil, errors.Wrapf(ErrInvalidLength, "document %d is not terminated", d.count)
+	}
+	d.count++
+	return dst, nil
+}
+
+// decoderInitialSize is the initial capacity of the buffers of documents larger than it, which grow as
+// the bytes arrive.
+const decoderInitialSize = 64 << 10
+
+// readGrowing reads the rest of the value of n bytes into raw, growing it as the bytes arrive rather than
+// allocating the declared length upfront, so a stream declaring a huge value but ending early doesn't
+// allocate more than twice what it has. raw holds the bytes read on errors.
+func readGrowing(r io.Reader, raw []byte, n int) ([]byte, error) {
+	for len(raw) < n {
+		if len(raw) == cap(raw) {
+			raw = append(raw, 0)[:len(raw)]
+		}
+		chunk := raw[len(raw):cap(raw)]
+		if rest := n - len(raw); len(chunk) > rest {
+			chunk = chunk[:rest]
+		}
+		m, err := io.ReadFull(r, chunk)
+		raw = raw[:len(raw)+m]
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return raw, err
+		}
+	}
+	return raw, nil
+}
+
+// readQuarantined reads the next valid document into dst in the quarantine mode, skipping the corrupt bytes.
+func (d *Decoder) readQuarantined(dst []byte) ([]byte, error) {
+	var badStart int64
+	var badErr error
+	for {
+		n, err := d.candidate()
+		if d.readErr != nil && d.readErr != io.EOF {
+			return nil, d.readErr
+		}
+		if err == nil || len(d.buf) == 0 {
+			if badErr != nil {
+				d.quarantine(badStart, d.offset, badErr)
+			}
+			if err != nil {
+				return nil, io.EOF
+			}
+			dst = append(dst[:0], d.buf[:n]...)
+			d.consume(n)
+			d.count++
+			return dst, nil
+		}
+		if badErr == nil {
+			badStart, badErr = d.offset, err
+		}
+		d.consume(1)
+	}
+}
+
+// candidate returns the length of the valid document at the beginning of the unconsumed bytes,
+// or the error why there's none.
+func (d *Decoder) candidate() (int, error) {
+	if !d.fill(4) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := int(consumeInt32(d.buf))
+	maxSize := d.maxSize
+	if maxSize <= 0 {
+		maxSize = quarantineMaxSize
+	}
+	switch {
+	case n < 5:
+		return 0, errors.Wrapf(ErrInvalidLength, "document declares %d bytes", n)
+	case n > maxSize:
+		return 0, errors.Wrapf(ErrTooLarge, "document declares %d bytes, limit %d", n, maxSize)
+	case !d.fill(n):
+		return 0, io.ErrUnexpectedEOF
+	}
+	if err := Validate(d.buf[:n]); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// fill reads until there are n unconsumed bytes, it returns false if the stream ends before.
+func (d *Decoder) fill(n int) bool {
+	for len(d.buf) < n && d.readErr == nil {
+		if cap(d.buf)-len(d.buf) < 4096 {
+			size := 2*len(d.buf) + 32<<10
+			if size < n {
+				size = n
+			}
+			d.buf = append(make([]byte, 0, size), d.buf...)
+		}
+		m, err := d.r.Read(d.buf[len(d.buf):cap(d.buf)])
+		d.buf, d.readErr = d.buf[:len(d.buf)+m], err
+	}
+	return len(d.buf) >= n
+}
+
+// consume drops the first n unconsumed bytes.
+func (d *Decoder) consume(n int) {
+	d.buf = d.buf[n:]
+	d.offset += int64(n)
+}
+
+// StreamOptions configures ConvertStream.
+type StreamOptions struct {
+	// Canonical writes canonical rather than relaxed Extended JSON.
+	Canonical bool
+	// Quarantine skips corrupt documents and passes their ranges to it, see Decoder.SetQuarantine.
+	Quarantine func(start, end int64, err error)
+}
+
+// ConvertStream converts a stream of bson documents into newline delimited Extended JSON,
+// one line per document. Buffers are reused between documents, so the memory used is bounded by
+// the largest document. A nil opts writes relaxed Extended JSON.
+func ConvertStream(r io.Reader, w io.Writer, opts *StreamOptions) error {
+	if opts == nil {
+		opts = &StreamOptions{}
+	}
+	jw := jsonWriter{canonical: opts.Canonical}
+	bw := bufio.NewWriter(w)
+	dec := NewDecoder(r)
+	dec.SetQuarantine(opts.Quarantine)
+	var doc, line []byte
+	for {
+		var err error
+		if doc, err = dec.readDocument(doc); err != nil {
+			if err == io.EOF {
+				return bw.Flush()
+			}
+			return err
+		}
+		if line, err = jw.appendDocument(line[:0], resultFromBytes(doc)); err != nil {
+			return errors.WithMessagef(err, "document %d", dec.count-1)
+		}
+		if _, err = bw.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+}
+
+// ConvertJSONStream converts newline delimited Extended JSON, one document per line, into a stream of
+// bson documents, the reverse of ConvertStream. Blank lines are skipped.
+func ConvertJSONStream(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	var line, doc []byte
+	for n := 1; ; n++ {
+		var err error
+		line, err = readLine(br, line[:0])
+		if len(bytes.TrimSpace(line)) > 0 {
+			var parseErr error
+			if doc, parseErr = appendFromJSON(doc[:0], line); parseErr != nil {
+				return errors.WithMessagef(parseErr, "line %d", n)
+			}
+			if _, err := bw.Write(doc); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return bw.Flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readLine appends the next line without the line break to dst, lines longer than the buffer are supported.
+func readLine(br *bufio.Reader, dst []byte) ([]byte, error) {
+	for {
+		chunk, err := br.ReadSlice('\n')
+		dst = append(dst, chunk...)
+		if err != bufio.ErrBufferFull {
+			return bytes.TrimSuffix(dst, []byte{'\n'}), err
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package gbson
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func getTestStream(t testing.TB) ([][]byte, []byte) {
+	docs := [][]byte{
+		mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}}),
+		getTestJSONDocument(t),
+		mustMarshal(t, bson.D{}),
+	}
+	return docs, bytes.Join(docs, nil)
+}
+
+func TestDecoder(t *testing.T) {
+	docs, stream := getTestStream(t)
+	dec := NewDecoder(bytes.NewReader(stream))
+	for _, doc := range docs {
+		actual, err := dec.Next()
+		require.NoError(t, err)
+		require.Equal(t, doc, actual)
+	}
+	_, err := dec.Next()
+	require.Equal(t, io.EOF, err)
+
+	_, err = NewDecoder(bytes.NewReader(stream[:len(stream)-1])).Next()
+	require.NoError(t, err)
+	dec = NewDecoder(bytes.NewReader(stream[:len(docs[0])+10]))
+	_, _ = dec.Next()
+	_, err = dec.Next()
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+	_, err = NewDecoder(bytes.NewReader([]byte{1, 0, 0, 0})).Next()
+	require.ErrorIs(t, err, ErrInvalidLength)
+
+	dec = NewDecoder(bytes.NewReader(stream))
+	dec.SetMaxDocumentSize(len(docs[0]))
+	_, err = dec.Next()
+	require.NoError(t, err)
+	_, err = dec.Next()
+	require.ErrorIs(t, err, ErrTooLarge)
+	dec = NewDecoder(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0x7f}))
+	dec.SetMaxDocumentSize(16 << 20)
+	_, err = dec.Next()
+	require.ErrorIs(t, err, ErrTooLarge)
+
+	// a truncated stream declaring a 2 GiB document doesn't allocate it without a limit
+	for _, transient := range []bool{false, true} {
+		dec = NewDecoder(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0x7f, byte(BSONTypeInt32), 'a', 0, 1, 0, 0, 0}))
+		dec.SetTransient(transient)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err = dec.Next()
+		runtime.ReadMemStats(&after)
+		require.Equal(t, io.ErrUnexpectedEOF, err)
+		require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+	}
+}
+
+func TestDecoderTransient(t *testing.T) {
+	docs, stream := getTestStream(t)
+	dec := NewDecoder(bytes.NewReader(stream))
+	dec.SetTransient(true)
+	require.Nil(t, dec.Detach())
+	first, err := dec.Next()
+	require.NoError(t, err)
+	require.Equal(t, docs[0], first)
+	detached := dec.Detach()
+	second, err := dec.Next()
+	require.NoError(t, err)
+	require.Equal(t, docs[1], second)
+	require.Equal(t, docs[0], detached)
+	third, err := dec.Next()
+	require.NoError(t, err)
+	require.Equal(t, docs[2], third)
+	require.Equal(t, &second[0], &third[0]) // the buffer is reused
+	_, err = dec.Next()
+	require.Equal(t, io.EOF, err)
+	require.Nil(t, dec.Detach())
+	dec.Release()
+
+	// the steady state doesn't allocate
+	stream = bytes.Repeat(stream, 50)
+	dec = NewDecoder(bytes.NewReader(stream))
+	dec.SetTransient(true)
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		_, err = dec.Next()
+	}))
+	require.NoError(t, err)
+	dec.Release()
+
+	dec = NewDecoder(bytes.NewReader(stream))
+	dec.SetTransient(true)
+	dec.SetQuarantine(func(start, end int64, err error) {
+		t.Fatalf("quarantined [%d, %d): %v", start, end, err)
+	})
+	for i := 0; i < len(docs)*50; i++ {
+		doc, err := dec.Next()
+		require.NoError(t, err)
+		require.Equal(t, docs[i%len(docs)], doc)
+	}
+	_, err = dec.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestDecoderQuarantine(t *testing.T) {
+	docs := [][]byte{
+		mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}}),
+		mustMarshal(t, bson.D{{Key: "s", Value: "hello"}}),
+		mustMarshal(t, bson.D{{Key: "b", Value: int32(2)}}),
+	}
+	docs[1][4] = 0x77
+	stream := append(bytes.Join(docs, nil), 1, 2, 3)
+	type quarantined struct {
+		start, end int64
+		err        error
+	}
+	var bad []quarantined
+	dec := NewDecoder(iotest.OneByteReader(bytes.NewReader(stream)))
+	dec.SetQuarantine(func(start, end int64, err error) {
+		bad = append(bad, quarantined{start, end, err})
+	})
+	for _, i := range []int{0, 2} {
+		doc, err := dec.Next()
+		require.NoError(t, err)
+		require.Equal(t, docs[i], doc)
+	}
+	_, err := dec.Next()
+	require.Equal(t, io.EOF, err)
+	require.Len(t, bad, 2)
+	require.Equal(t, []int64{12, 30}, []int64{bad[0].start, bad[0].end})
+	require.ErrorIs(t, bad[0].err, ErrUnsupportedType)
+	require.Equal(t, []int64{42, 45}, []int64{bad[1].start, bad[1].end})
+	require.Equal(t, io.ErrUnexpectedEOF, bad[1].err)
+
+	var out bytes.Buffer
+	bad = nil
+	require.NoError(t, ConvertStream(bytes.NewReader(stream), &out, &StreamOptions{Quarantine: func(start, end int64, err error) {
+		bad = append(bad, quarantined{start, end, err})
+	}}))
+	require.Equal(t, "{\"a\":1}\n{\"b\":2}\n", out.String())
+	require.Len(t, bad, 2)
+}
+
+func TestConvertStream(t *testing.T) {
+	docs, stream := getTestStream(t)
+	for _, canonical := range []bool{false, true} {
+		var out bytes.Buffer
+		require.NoError(t, ConvertStream(bytes.NewReader(stream), &out, &StreamOptions{Canonical: canonical}))
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		require.Len(t, lines, len(docs))
+		for i, doc := range docs {
+			expected, err := bson.MarshalExtJSON(bson.Raw(doc), canonical, false)
+			require.NoError(t, err)
+			require.Equal(t, string(expected), lines[i])
+		}
+	}
+	require.Error(t, ConvertStream(bytes.NewReader(stream[:len(stream)-2]), io.Discard, nil))
+}
+
+func TestConvertJSONStream(t *testing.T) {
+	_, stream := getTestStream(t)
+	var lines bytes.Buffer
+	require.NoError(t, ConvertStream(bytes.NewReader(stream), &lines, &StreamOptions{Canonical: true}))
+	input := "\n" + strings.ReplaceAll(lines.String(), "\n", "\r\n\n")
+	var out bytes.Buffer
+	require.NoError(t, ConvertJSONStream(strings.NewReader(input), &out))
+	dec := NewDecoder(&out)
+	for _, line := range strings.Split(strings.TrimSuffix(lines.String(), "\n"), "\n") {
+		var expected bson.Raw
+		require.NoError(t, bson.UnmarshalExtJSON([]byte(line), true, &expected))
+		actual, err := dec.Next()
+		require.NoError(t, err)
+		require.Equal(t, []byte(expected), actual)
+	}
+	_, err := dec.Next()
+	require.Equal(t, io.EOF, err)
+
+	// long lines without a trailing line break
+	long := `{"s":"` + strings.Repeat("x", 10000) + `"}`
+	out.Reset()
+	require.NoError(t, ConvertJSONStream(strings.NewReader(long), &out))
+	require.Equal(t, 10000, len(Get(out.Bytes(), "s").String()))
+
+	err = ConvertJSONStream(strings.NewReader("{}\n{\"a\":}\n"), io.Discard)
+	require.ErrorIs(t, err, ErrInvalidJSON)
+	require.Contains(t, err.Error(), "line 2")
+}
@@ -0,0 +1,215 @@
+package gbson
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// structField describes a struct field stored in bson.
+type structField struct {
+	name      string
+	index     []int // index path, longer than one for inlined fields
+	typ       reflect.Type
+	omitEmpty bool
+	minSize   bool
+	truncate  bool // decode doubles with fractions into integers, only read by Unmarshal
+}
+
+// structInfo describes how a struct type maps to a bson document.
+type structInfo struct {
+	fields    []structField  // in declaration order
+	names     map[string]int // field name to index in fields
+	inlineMap int            // index of the inlined map field, -1 if none
+}
+
+var structInfoCache sync.Map // map[reflect.Type]*structInfo
+
+// zeroer is checked by omitempty, the same as mongo-driver's bsoncodec.Zeroer.
+type zeroer interface {
+	IsZero() bool
+}
+
+var zeroerType = reflect.TypeOf((*zeroer)(nil)).Elem()
+
+// structTag is the parsed `bson` struct tag, compatible with mongo-driver.
+type structTag struct {
+	name      string
+	skip      bool
+	omitEmpty bool
+	minSize   bool
+	truncate  bool
+	inline    bool
+}
+
+func parseStructTag(sf reflect.StructField) (st structTag) {
+	st.name = strings.ToLower(sf.Name)
+	tag, ok := sf.Tag.Lookup("bson")
+	if !ok && !strings.Contains(string(sf.Tag), ":") && len(sf.Tag) > 0 {
+		// the whole tag is used when it's not in the key:"value" form
+		tag = string(sf.Tag)
+	}
+	if tag == "-" {
+		st.skip = true
+		return
+	}
+	for idx, s := range strings.Split(tag, ",") {
+		if idx == 0 && s != "" {
+			st.name = s
+		}
+		switch s {
+		case "omitempty":
+			st.omitEmpty = true
+		case "minsize":
+			st.minSize = true
+		case "truncate":
+			st.truncate = true
+		case "inline":
+			st.inline = true
+		}
+	}
+	return
+}
+
+// cachedStructInfo returns the description of the struct type, which is cached by type.
+func cachedStructInfo(t reflect.Type) (*structInfo, error) {
+	if info, ok := structInfoCache.Load(t); ok {
+		return info.(*structInfo), nil
+	}
+	info, err := newStructInfo(t)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := structInfoCache.LoadOrStore(t, info)
+	return actual.(*structInfo), nil
+}
+
+func newStructInfo(t reflect.Type) (*structInfo, error) {
+	info := &structInfo{inlineMap: -1}
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+		tag := parseStructTag(sf)
+		if tag.skip {
+			continue
+		}
+		if tag.inline {
+			ft := sf.Type
+			switch ft.Kind() {
+			case reflect.Map:
+				if info.inlineMap >= 0 {
+					return nil, errors.Errorf("struct %s has multiple inline maps", t)
+				}
+				if ft.Key().Kind() != reflect.String {
+					return nil, errors.Errorf("struct %s has inline map without string keys", t)
+				}
+				info.inlineMap = i
+				continue
+			case reflect.Ptr:
+				ft = ft.Elem()
+				if ft.Kind() != reflect.Struct {
+					return nil, errors.Errorf("struct %s has inline field not a struct, a struct pointer or a map", t)
+				}
+			case reflect.Struct:
+			default:
+				return nil, errors.Errorf("struct %s has inline field not a struct, a struct pointer or a map", t)
+			}
+			inner, err := cachedStructInfo(ft)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range inner.fields {
+				f.index = append([]int{i}, f.index...)
+				fields = append(fields, f)
+			}
+			continue
+		}
+		fields = append(fields, structField{
+			name:      tag.name,
+			index:     []int{i},
+			typ:       sf.Type,
+			omitEmpty: tag.omitEmpty,
+			minSize:   tag.minSize,
+			truncate:  tag.truncate,
+		})
+	}
+
+	// shallower fields dominate inlined fields with the same name
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].name != fields[j].name {
+			return fields[i].name < fields[j].name
+		}
+		return len(fields[i].index) < len(fields[j].index)
+	})
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		if j-i > 1 && len(fields[i].index) == len(fields[i+1].index) {
+			return nil, errors.Errorf("struct %s has duplicated key %s", t, fields[i].name)
+		}
+		info.fields = append(info.fields, fields[i])
+		i = j
+	}
+	sort.Slice(info.fields, func(i, j int) bool {
+		return indexLess(info.fields[i].index, info.fields[j].index)
+	})
+
+	info.names = make(map[string]int, len(info.fields))
+	for i, f := range info.fields {
+		info.names[f.name] = i
+	}
+	return info, nil
+}
+
+func indexLess(a, b []int) bool {
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// fieldByIndex returns the nested field, ok is false when walking through a nil pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmptyValue reports whether the value is omitted by omitempty, the same as mongo-driver.
+func isEmptyValue(v reflect.Value) bool {
+	if v.Type().Implements(zeroerType) && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+		return v.Interface().(zeroer).IsZero()
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
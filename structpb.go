@@ -0,0 +1,142 @@
+package gbson
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// maxExactFloat is the largest magnitude of integers which are exact in float64.
+const maxExactFloat = 1 << 53
+
+// ToStruct converts the bson document into the map accepted by structpb.NewStruct, so it could be attached
+// to gRPC APIs as a google.protobuf.Struct without a JSON detour. Values are converted by StructValue.
+func ToStruct(doc []byte) (map[string]interface{}, error) {
+	v, err := Result{Type: BSONTypeObject, Raw: doc}.StructValue()
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]interface{}), nil
+}
+
+// StructValue converts the value into one of the Go types accepted by structpb.NewValue, the same types
+// (*structpb.Value).AsInterface returns:
+//
+//	Double, Int32, Int64          float64, integers beyond ±2^53 are rejected with ErrLossyConversion
+//	String, Symbol, JavaScript    string
+//	Object                        map[string]interface{}
+//	Array                         []interface{}
+//	Boolean                       bool
+//	Null, Undefined               nil
+//
+// The other types are converted into strings by Str, e.g. ObjectIDs in hex and datetimes in RFC 3339,
+// except DBPointer, JavaScriptWithScope, MinKey and MaxKey which are converted into maps of
+// their relaxed Extended JSON.
+func (r Result) StructValue() (interface{}, error) {
+	v, err := r.structValue(1)
+	if err != nil && r.IsContainer() {
+		return nil, locateError(r.Raw, err)
+	}
+	return v, err
+}
+
+// structValue is StructValue of the value at the depth if it's a container.
+func (r Result) structValue(depth int) (interface{}, error) {
+	switch r.Type {
+	case BSONTypeDouble:
+		if len(r.Raw) < 8 {
+			return nil, ErrInvalidLength
+		}
+		return r.Float64(), nil
+	case BSONTypeInt32, BSONTypeInt64:
+		i, err := r.Int64E()
+		if err != nil {
+			return nil, err
+		}
+		if i > maxExactFloat || i < -maxExactFloat {
+			return nil, errors.Wrapf(ErrLossyConversion, "%d to float64", i)
+		}
+		return float64(i), nil
+	case BSONTypeString, BSONTypeSymbol, BSONTypeJavaScript:
+		return r.StringE()
+	case BSONTypeObject:
+		if err := checkDepth(depth); err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{})
+		var err error
+		_, iterErr := r.iterFields(func(key []byte, value Result) bool {
+			if m[string(key)], err = value.structValue(depth + 1); err != nil {
+				err = errors.WithMessagef(err, "%q", key)
+			}
+			return err == nil
+		})
+		if err == nil {
+			err = iterErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	case BSONTypeArray:
+		if err := checkDepth(depth); err != nil {
+			return nil, err
+		}
+		a := make([]interface{}, 0)
+		var err error
+		_, iterErr := r.iterFields(func(_ []byte, value Result) bool {
+			var v interface{}
+			v, err = value.structValue(depth + 1)
+			a = append(a, v)
+			return err == nil
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "%d", len(a)-1)
+		}
+		if iterErr != nil {
+			return nil, iterErr
+		}
+		return a, nil
+	case BSONTypeBoolean:
+		return r.BoolE()
+	case BSONTypeNull, BSONTypeUndefined:
+		return nil, nil
+	case BSONTypeDBPointer, BSONTypeJavaScriptWithScope, BSONTypeMinKey, BSONTypeMaxKey:
+		js, err := r.JSON()
+		if err != nil {
+			return nil, err
+		}
+		var v interface{}
+		if err = json.Unmarshal(js, &v); err != nil {
+			return nil, errors.Wrap(ErrInvalidJSON, err.Error())
+		}
+		return v, nil
+	case BSONTypeObjectID, BSONTypeBinary, BSONTypeDateTime, BSONTypeRegex, BSONTypeTimestamp, BSONTypeDecimal128:
+		if consumeValue(r.Type, r.Raw) != len(r.Raw) {
+			return nil, ErrInvalidLength
+		}
+		return r.Str(), nil
+	}
+	return nil, errors.Wrapf(ErrUnsupportedType, "type %v", r.Type)
+}
+
+// FromStructValue converts a scalar of the Go types (*structpb.Value).AsInterface returns into a bson value:
+// nil into Null, bool into Boolean, float64 into Double and string into String. Structs and lists
+// could be converted by FromMap.
+func FromStructValue(v interface{}) (Result, error) {
+	switch v := v.(type) {
+	case nil:
+		return Result{Type: BSONTypeNull}, nil
+	case bool:
+		b := byte(0)
+		if v {
+			b = 1
+		}
+		return Result{Type: BSONTypeBoolean, Raw: []byte{b}}, nil
+	case float64:
+		return Result{Type: BSONTypeDouble, Raw: appendDouble(nil, v)}, nil
+	case string:
+		return Result{Type: BSONTypeString, Raw: appendString(nil, v)}, nil
+	}
+	return Result{Type: BSONTypeUndefined}, errors.Wrapf(ErrUnsupportedType, "%T", v)
+}
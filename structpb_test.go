@@ -0,0 +1,56 @@
+package gbson
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestToStruct(t *testing.T) {
+	id := primitive.NewObjectID()
+	m, err := ToStruct(mustMarshal(t, bson.D{
+		{Key: "_id", Value: id},
+		{Key: "n", Value: int32(1)},
+		{Key: "l", Value: int64(1) << 53},
+		{Key: "f", Value: 1.5},
+		{Key: "s", Value: "x"},
+		{Key: "b", Value: true},
+		{Key: "null", Value: nil},
+		{Key: "date", Value: time.Date(2022, 11, 10, 1, 2, 3, 0, time.UTC)},
+		{Key: "doc", Value: bson.D{{Key: "a", Value: bson.A{int32(1), "y", bson.D{}}}}},
+		{Key: "min", Value: primitive.MinKey{}},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"_id":  id.Hex(),
+		"n":    1.0,
+		"l":    float64(1 << 53),
+		"f":    1.5,
+		"s":    "x",
+		"b":    true,
+		"null": nil,
+		"date": "2022-11-10T01:02:03Z",
+		"doc":  map[string]interface{}{"a": []interface{}{1.0, "y", map[string]interface{}{}}},
+		"min":  map[string]interface{}{"$minKey": 1.0},
+	}, m)
+
+	_, err = ToStruct(mustMarshal(t, bson.D{{Key: "doc", Value: bson.D{{Key: "big", Value: int64(1)<<53 + 1}}}}))
+	require.ErrorIs(t, err, ErrLossyConversion)
+	require.Contains(t, err.Error(), `"doc": "big"`)
+}
+
+func TestFromStructValue(t *testing.T) {
+	for _, v := range []interface{}{nil, true, false, 1.5, math.Inf(1), "x"} {
+		r, err := FromStructValue(v)
+		require.NoError(t, err)
+		back, err := r.StructValue()
+		require.NoError(t, err)
+		require.Equal(t, v, back)
+	}
+	_, err := FromStructValue(1)
+	require.ErrorIs(t, err, ErrUnsupportedType)
+}
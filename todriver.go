@@ -0,0 +1,180 @@
+package gbson
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// FromRaw returns the result of a whole document held by bson.Raw, which already includes the length header,
+// so paths could be looked up by Get of the result. The bytes are referred to without copying.
+func FromRaw(doc bson.Raw) Result {
+	return resultFromBytes(doc)
+}
+
+// FromCore is like FromRaw, for documents of mongo-driver's bsoncore package.
+func FromCore(doc bsoncore.Document) Result {
+	return resultFromBytes(doc)
+}
+
+// RawValue returns the value as mongo-driver's bson.RawValue referring to the same bytes without copying,
+// so it could be used in filters or compared by mongo-driver directly. A missing result returns the zero RawValue.
+func (r Result) RawValue() bson.RawValue {
+	if !r.Exist() {
+		return bson.RawValue{}
+	}
+	return bson.RawValue{Type: bsontype.Type(r.Type), Value: r.Raw}
+}
+
+// ToBSOND converts a document into a bson.D keeping the order of elements, nil if the value is not a document.
+// Values are converted into the same types mongo-driver decodes into interface{}, e.g. primitive.DateTime for
+// datetimes and primitive.A for arrays, with embedded documents converted into bson.D as well.
+// It's nil for malformed documents and the ones nested deeper than DefaultMaxDepth, see ToBSONDE.
+func (r Result) ToBSOND() primitive.D {
+	d, _ := r.ToBSONDE()
+	return d
+}
+
+// ToBSONDE is ToBSOND returning the error of the malformed document, or ErrMaxDepth for documents nested
+// deeper than DefaultMaxDepth.
+func (r Result) ToBSONDE() (primitive.D, error) {
+	if r.Type != BSONTypeObject {
+		return nil, nil
+	}
+	d, err := toBSOND(r, 1)
+	if err != nil {
+		return nil, locateError(r.Raw, err)
+	}
+	return d.(primitive.D), nil
+}
+
+// ToBSONM converts a document into a bson.M, nil if the value is not a document.
+// Values are converted the same as ToBSOND, except embedded documents are converted into bson.M.
+func (r Result) ToBSONM() primitive.M {
+	m, _ := r.ToBSONME()
+	return m
+}
+
+// ToBSONME is ToBSONM returning the errors of ToBSONDE.
+func (r Result) ToBSONME() (primitive.M, error) {
+	if r.Type != BSONTypeObject {
+		return nil, nil
+	}
+	m, err := toBSONM(r, 1)
+	if err != nil {
+		return nil, locateError(r.Raw, err)
+	}
+	return m.(primitive.M), nil
+}
+
+func toBSOND(r Result, depth int) (interface{}, error) {
+	if err := checkDepth(depth); err != nil {
+		return nil, err
+	}
+	d := primitive.D{}
+	var err error
+	_, iterErr := r.iterFields(func(key []byte, value Result) bool {
+		var v interface{}
+		if v, err = driverValue(value, depth+1, toBSOND); err != nil {
+			return false
+		}
+		d = append(d, primitive.E{Key: string(key), Value: v})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, iterErr
+}
+
+func toBSONM(r Result, depth int) (interface{}, error) {
+	if err := checkDepth(depth); err != nil {
+		return nil, err
+	}
+	m := primitive.M{}
+	var err error
+	_, iterErr := r.iterFields(func(key []byte, value Result) bool {
+		var v interface{}
+		if v, err = driverValue(value, depth+1, toBSONM); err != nil {
+			return false
+		}
+		m[string(key)] = v
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, iterErr
+}
+
+// driverValue converts the value at the depth into the type of mongo-driver, embedded documents are converted
+// by toDocument.
+func driverValue(r Result, depth int, toDocument func(Result, int) (interface{}, error)) (interface{}, error) {
+	switch r.Type {
+	case BSONTypeObject:
+		return toDocument(r, depth)
+	case BSONTypeArray:
+		if err := checkDepth(depth); err != nil {
+			return nil, err
+		}
+		a := primitive.A{}
+		var err error
+		_, iterErr := r.iterFields(func(_ []byte, item Result) bool {
+			var v interface{}
+			if v, err = driverValue(item, depth+1, toDocument); err != nil {
+				return false
+			}
+			a = append(a, v)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		return a, iterErr
+	case BSONTypeJavaScriptWithScope:
+		code, scope := r.JavaScriptWithScope()
+		d, err := toBSOND(scope, depth)
+		if err != nil {
+			return nil, err
+		}
+		return primitive.CodeWithScope{Code: primitive.JavaScript(code), Scope: d}, nil
+	}
+	return driverScalar(r), nil
+}
+
+// driverScalar converts the value other than documents and arrays into the type of mongo-driver.
+func driverScalar(r Result) interface{} {
+	switch r.Type {
+	case BSONTypeBinary:
+		subtype, data := r.BinaryCopy()
+		return primitive.Binary{Subtype: subtype, Data: data}
+	case BSONTypeUndefined:
+		return primitive.Undefined{}
+	case BSONTypeObjectID:
+		return primitive.ObjectID(r.ObjectID())
+	case BSONTypeDateTime:
+		return primitive.DateTime(r.UnixMilli())
+	case BSONTypeRegex:
+		pattern, options := r.Regex()
+		return primitive.Regex{Pattern: pattern, Options: options}
+	case BSONTypeDBPointer:
+		ns, id, _ := r.DBPointer()
+		return primitive.DBPointer{DB: ns, Pointer: id}
+	case BSONTypeJavaScript:
+		return primitive.JavaScript(r.String())
+	case BSONTypeSymbol:
+		return primitive.Symbol(r.String())
+	case BSONTypeTimestamp:
+		t, i := r.Timestamp()
+		return primitive.Timestamp{T: t, I: i}
+	case BSONTypeDecimal128:
+		d := r.Decimal128()
+		return primitive.NewDecimal128(d.High, d.Low)
+	case BSONTypeMinKey:
+		return primitive.MinKey{}
+	case BSONTypeMaxKey:
+		return primitive.MaxKey{}
+	}
+	return r.Value()
+}
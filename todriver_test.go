@@ -0,0 +1,68 @@
+package gbson
+
+import (
+	"math"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestToBSOND(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	var d bson.D
+	require.NoError(t, bson.Unmarshal(doc, &d))
+	actual := Get(doc).ToBSOND()
+	// NaN never equals itself
+	require.Equal(t, "nan", actual[5].Key)
+	d[5].Value, actual[5].Value = nil, nil
+	require.Equal(t, d, actual)
+	require.Nil(t, Get(doc, "int32").ToBSOND())
+
+	var m bson.M
+	require.NoError(t, bson.Unmarshal(doc, &m))
+	actualM := Get(doc).ToBSONM()
+	delete(m, "nan")
+	delete(actualM, "nan")
+	require.Equal(t, m, actualM)
+	require.Nil(t, Get(doc, "missing").ToBSONM())
+
+	_, err := Get(doc[:len(doc)-1]).ToBSONDE()
+	require.ErrorIs(t, err, ErrInvalidLength)
+	_, err = Get(doc[:len(doc)-1]).ToBSONME()
+	require.ErrorIs(t, err, ErrInvalidLength)
+	d2, err := Get(doc).ToBSONDE()
+	require.NoError(t, err)
+	require.Equal(t, Get(doc).ToBSOND()[0], d2[0])
+
+	// datetimes are converted from the milliseconds, round-tripping the extremes exactly
+	for _, ms := range []int64{math.MaxInt64, math.MinInt64, -1} {
+		d = bson.D{{Key: "t", Value: primitive.DateTime(ms)}}
+		require.Equal(t, d, Get(mustMarshal(t, d)).ToBSOND())
+	}
+}
+
+func TestRawValue(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	raw := bson.Raw(doc)
+	for _, key := range []string{"_id", "string", "doc", "int32", "decimal", "min"} {
+		rv := Get(doc, key).RawValue()
+		require.True(t, raw.Lookup(key).Equal(rv), key)
+	}
+	rv := Get(doc, "string").RawValue()
+	require.Equal(t, unsafe.Pointer(&Get(doc, "string").Raw[0]), unsafe.Pointer(&rv.Value[0]))
+	filter, err := bson.Marshal(bson.D{{Key: "string", Value: rv}})
+	require.NoError(t, err)
+	require.Equal(t, Get(doc, "string").String(), Get(filter, "string").String())
+	require.Equal(t, bson.RawValue{}, Get(doc, "missing").RawValue())
+}
+
+func TestFromRaw(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	require.Equal(t, Get(doc, "doc", "a"), FromRaw(bson.Raw(doc)).Get("doc", "a"))
+	require.Equal(t, "x", FromCore(bsoncore.Document(doc)).Get("doc", "b", "1").String())
+	require.Equal(t, Get(doc).Length(), FromCore(bsoncore.Document(doc)).Length())
+}
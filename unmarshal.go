@@ -0,0 +1,512 @@
+package gbson
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// valueDecoder decodes the bson value into v, which is settable.
+type valueDecoder func(r Result, v reflect.Value) error
+
+var decoderCache sync.Map // map[reflect.Type]valueDecoder
+
+// Unmarshal decodes the bson document into v, see Result.Unmarshal.
+func Unmarshal(doc []byte, v interface{}) error {
+	return resultFromBytes(doc).Unmarshal(v)
+}
+
+// Unmarshal decodes the value into v, which must be a non-nil pointer.
+//
+// Documents are decoded into structs compatibly with mongo-driver: fields are matched by the names
+// Marshal writes, then by lowercased element names, and the tag options inline and truncate are honored.
+// Elements without a matching field are ignored unless the struct has an inlined map.
+// Fields missing from the document are left untouched. Integers are range checked and
+// doubles with fractions are only decoded into integers with the truncate option.
+//
+// Fields of type Result refer to the raw bytes of the element without copying, interface{} values
+// are decoded by Value, and types of mongo-driver's primitive package are decoded by mongo-driver.
+// Containers nested deeper than DefaultMaxDepth are rejected with ErrMaxDepth before decoding.
+func (r Result) Unmarshal(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.Errorf("unmarshal into non-pointer or nil %T", v)
+	}
+	if !r.Exist() {
+		return ErrNotExist
+	}
+	if r.IsContainer() {
+		if err := checkNesting(r.Raw, 1); err != nil {
+			return err
+		}
+	}
+	err := decoderOf(rv.Type().Elem())(r, rv.Elem())
+	if err != nil && r.IsContainer() {
+		return locateError(r.Raw, err)
+	}
+	return err
+}
+
+// decoderOf returns the cached decoder of the type, building it when missing.
+func decoderOf(t reflect.Type) valueDecoder {
+	if dec, ok := decoderCache.Load(t); ok {
+		return dec.(valueDecoder)
+	}
+	// store an indirect decoder first to support recursive types,
+	// it waits until the real decoder is built.
+	var (
+		wg  sync.WaitGroup
+		dec valueDecoder
+	)
+	wg.Add(1)
+	indirect, loaded := decoderCache.LoadOrStore(t, valueDecoder(func(r Result, v reflect.Value) error {
+		wg.Wait()
+		return dec(r, v)
+	}))
+	if loaded {
+		return indirect.(valueDecoder)
+	}
+	dec = newDecoder(t, false)
+	wg.Done()
+	decoderCache.Store(t, dec)
+	return dec
+}
+
+// mismatch returns the error of decoding a bson value into an incompatible Go type.
+func mismatch(r Result, t reflect.Type) error {
+	return errors.Wrapf(ErrTypeMismatch, "cannot decode %v into %s", r.Type, t)
+}
+
+func newDecoder(t reflect.Type, truncate bool) valueDecoder {
+	switch t {
+	case timeType:
+		return decodeTime
+	case resultType:
+		return decodeResult
+	}
+	if driverTypes[t] {
+		return decodeDriverValue
+	}
+	var dec valueDecoder
+	switch t.Kind() {
+	case reflect.Bool:
+		dec = decodeBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		dec = newIntDecoder(truncate)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		dec = newUintDecoder(truncate)
+	case reflect.Float32, reflect.Float64:
+		dec = decodeFloat
+	case reflect.String:
+		dec = decodeString
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			dec = decodeByteSlice
+		} else {
+			dec = newSliceDecoder(t)
+		}
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			dec = decodeByteArray
+		} else {
+			dec = newArrayDecoder(t)
+		}
+	case reflect.Map:
+		dec = newMapDecoder(t)
+	case reflect.Struct:
+		dec = newStructDecoder(t)
+	case reflect.Ptr:
+		return newPtrDecoder(t)
+	case reflect.Interface:
+		return decodeInterface
+	default:
+		return func(Result, reflect.Value) error {
+			return errors.Wrapf(ErrUnsupportedType, "%s", t)
+		}
+	}
+	// null and undefined reset the value
+	return func(r Result, v reflect.Value) error {
+		if r.Type == BSONTypeNull || r.Type == BSONTypeUndefined {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		return dec(r, v)
+	}
+}
+
+func decodeTime(r Result, v reflect.Value) error {
+	switch r.Type {
+	case BSONTypeNull, BSONTypeUndefined:
+		v.Set(reflect.Zero(timeType))
+		return nil
+	case BSONTypeDateTime, BSONTypeTimestamp:
+		if err := r.checkLength(); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(r.TimeIn(time.UTC)))
+		return nil
+	}
+	return mismatch(r, timeType)
+}
+
+func decodeResult(r Result, v reflect.Value) error {
+	v.Set(reflect.ValueOf(r))
+	return nil
+}
+
+func decodeDriverValue(r Result, v reflect.Value) error {
+	return r.RawValue().Unmarshal(v.Addr().Interface())
+}
+
+func decodeBool(r Result, v reflect.Value) error {
+	b, err := r.BoolE()
+	if err != nil {
+		return mismatch(r, v.Type())
+	}
+	v.SetBool(b)
+	return nil
+}
+
+// decodeInteger reads an integer value, doubles with fractions are rejected unless truncate is set.
+func decodeInteger(r Result, t reflect.Type, truncate bool) (int64, error) {
+	switch r.Type {
+	case BSONTypeInt32, BSONTypeInt64:
+		return r.Int64E()
+	case BSONTypeDouble:
+		f, err := r.Float64E()
+		if err != nil {
+			return 0, err
+		}
+		if !truncate && f != math.Trunc(f) {
+			return 0, errors.Wrapf(ErrLossyConversion, "%v into %s", f, t)
+		}
+		if f < math.MinInt64 || f >= math.MaxInt64 || math.IsNaN(f) {
+			return 0, errors.Wrapf(ErrLossyConversion, "%v overflows %s", f, t)
+		}
+		return int64(f), nil
+	}
+	return 0, mismatch(r, t)
+}
+
+func newIntDecoder(truncate bool) valueDecoder {
+	return func(r Result, v reflect.Value) error {
+		i, err := decodeInteger(r, v.Type(), truncate)
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(i) {
+			return errors.Wrapf(ErrLossyConversion, "%d overflows %s", i, v.Type())
+		}
+		v.SetInt(i)
+		return nil
+	}
+}
+
+func newUintDecoder(truncate bool) valueDecoder {
+	return func(r Result, v reflect.Value) error {
+		i, err := decodeInteger(r, v.Type(), truncate)
+		if err != nil {
+			return err
+		}
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return errors.Wrapf(ErrLossyConversion, "%d overflows %s", i, v.Type())
+		}
+		v.SetUint(uint64(i))
+		return nil
+	}
+}
+
+func decodeFloat(r Result, v reflect.Value) error {
+	f, err := r.Float64E()
+	if err != nil {
+		return mismatch(r, v.Type())
+	}
+	v.SetFloat(f)
+	return nil
+}
+
+func decodeString(r Result, v reflect.Value) error {
+	if r.Type == BSONTypeObjectID {
+		if err := r.checkLength(); err != nil {
+			return err
+		}
+		v.SetString(r.ObjectIDHex())
+		return nil
+	}
+	if err := r.checkType(BSONTypeString, BSONTypeSymbol, BSONTypeJavaScript); err != nil {
+		return mismatch(r, v.Type())
+	}
+	s, err := r.StringE()
+	if err != nil {
+		return err
+	}
+	v.SetString(s)
+	return nil
+}
+
+func decodeByteSlice(r Result, v reflect.Value) error {
+	if r.Type != BSONTypeBinary {
+		return mismatch(r, v.Type())
+	}
+	if len(r.Raw) < 5 {
+		return ErrInvalidLength
+	}
+	_, data := r.BinaryCopy()
+	v.SetBytes(data)
+	return nil
+}
+
+func decodeByteArray(r Result, v reflect.Value) error {
+	var data []byte
+	switch r.Type {
+	case BSONTypeBinary:
+		_, data = r.Binary()
+	case BSONTypeObjectID:
+		if err := r.checkLength(); err != nil {
+			return err
+		}
+		data = r.Raw[:12]
+	default:
+		return mismatch(r, v.Type())
+	}
+	if len(data) != v.Len() {
+		return errors.Wrapf(ErrInvalidLength, "%d bytes into %s", len(data), v.Type())
+	}
+	reflect.Copy(v, reflect.ValueOf(data))
+	return nil
+}
+
+func decodeInterface(r Result, v reflect.Value) error {
+	if v.NumMethod() == 0 {
+		if value := r.Value(); value != nil {
+			v.Set(reflect.ValueOf(value))
+		} else {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+	if r.Type == BSONTypeNull || r.Type == BSONTypeUndefined {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	// only decodes into the pointer held by a non-empty interface
+	if elem := v.Elem(); elem.Kind() == reflect.Ptr && !elem.IsNil() {
+		return decoderOf(elem.Type().Elem())(r, elem.Elem())
+	}
+	return errors.Wrapf(ErrUnsupportedType, "%s", v.Type())
+}
+
+func newPtrDecoder(t reflect.Type) valueDecoder {
+	elem := decoderOf(t.Elem())
+	return func(r Result, v reflect.Value) error {
+		if r.Type == BSONTypeNull || r.Type == BSONTypeUndefined {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return elem(r, v.Elem())
+	}
+}
+
+func newSliceDecoder(t reflect.Type) valueDecoder {
+	elem := decoderOf(t.Elem())
+	return func(r Result, v reflect.Value) error {
+		if r.Type != BSONTypeArray {
+			return mismatch(r, t)
+		}
+		s := reflect.MakeSlice(t, 0, 0)
+		var err error
+		if _, iterErr := r.iterFields(func(_ []byte, item Result) bool {
+			s = reflect.Append(s, reflect.Zero(t.Elem()))
+			err = elem(item, s.Index(s.Len()-1))
+			return err == nil
+		}); iterErr != nil {
+			return iterErr
+		}
+		if err != nil {
+			return err
+		}
+		v.Set(s)
+		return nil
+	}
+}
+
+func newArrayDecoder(t reflect.Type) valueDecoder {
+	elem := decoderOf(t.Elem())
+	return func(r Result, v reflect.Value) error {
+		if r.Type != BSONTypeArray {
+			return mismatch(r, t)
+		}
+		var i int
+		var err error
+		if _, iterErr := r.iterFields(func(_ []byte, item Result) bool {
+			if i >= t.Len() {
+				err = errors.Wrapf(ErrInvalidLength, "more than %d elements into %s", t.Len(), t)
+				return false
+			}
+			err = elem(item, v.Index(i))
+			i++
+			return err == nil
+		}); iterErr != nil {
+			return iterErr
+		}
+		return err
+	}
+}
+
+// mapKeyParser returns the function parsing map keys into the type, nil if not supported.
+func mapKeyParser(t reflect.Type) func(key string, v reflect.Value) error {
+	switch t.Kind() {
+	case reflect.String:
+		return func(key string, v reflect.Value) error {
+			v.SetString(key)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(key string, v reflect.Value) error {
+			i, err := strconv.ParseInt(key, 10, t.Bits())
+			v.SetInt(i)
+			return errors.Wrapf(err, "map key %q", key)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(key string, v reflect.Value) error {
+			u, err := strconv.ParseUint(key, 10, t.Bits())
+			v.SetUint(u)
+			return errors.Wrapf(err, "map key %q", key)
+		}
+	}
+	return nil
+}
+
+func newMapDecoder(t reflect.Type) valueDecoder {
+	parseKey := mapKeyParser(t.Key())
+	if parseKey == nil {
+		return func(Result, reflect.Value) error {
+			return errors.Wrapf(ErrUnsupportedType, "%s", t)
+		}
+	}
+	elem := decoderOf(t.Elem())
+	return func(r Result, v reflect.Value) error {
+		if r.Type != BSONTypeObject {
+			return mismatch(r, t)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(t))
+		}
+		return decodeMapElements(r, v, parseKey, elem, nil)
+	}
+}
+
+// decodeMapElements sets all elements of the document into the map, except the ones accepted by skip.
+func decodeMapElements(r Result, v reflect.Value, parseKey func(string, reflect.Value) error,
+	elem valueDecoder, skip func(string) bool) error {
+	t := v.Type()
+	key := reflect.New(t.Key()).Elem()
+	value := reflect.New(t.Elem()).Elem()
+	var err error
+	if _, iterErr := r.iterFields(func(name []byte, item Result) bool {
+		if skip != nil && skip(string(name)) {
+			return true
+		}
+		if err = parseKey(string(name), key); err != nil {
+			return false
+		}
+		value.Set(reflect.Zero(t.Elem()))
+		if err = elem(item, value); err != nil {
+			err = errors.WithMessagef(err, "key %s", name)
+			return false
+		}
+		v.SetMapIndex(key, value)
+		return true
+	}); iterErr != nil {
+		return iterErr
+	}
+	return err
+}
+
+type fieldDecoder struct {
+	structField
+	dec valueDecoder
+}
+
+func newStructDecoder(t reflect.Type) valueDecoder {
+	info, err := cachedStructInfo(t)
+	if err != nil {
+		return func(Result, reflect.Value) error {
+			return err
+		}
+	}
+	fields := make([]fieldDecoder, len(info.fields))
+	for i, f := range info.fields {
+		dec := decoderOf(f.typ)
+		if f.truncate {
+			dec = newDecoder(f.typ, true)
+		}
+		fields[i] = fieldDecoder{structField: f, dec: dec}
+	}
+	lookup := func(name string) (*fieldDecoder, bool) {
+		idx, ok := info.names[name]
+		if !ok {
+			idx, ok = info.names[strings.ToLower(name)]
+		}
+		if !ok {
+			return nil, false
+		}
+		return &fields[idx], true
+	}
+	var inlineDecoder valueDecoder
+	if info.inlineMap >= 0 {
+		inlineDecoder = decoderOf(t.Field(info.inlineMap).Type.Elem())
+	}
+	return func(r Result, v reflect.Value) error {
+		if r.Type != BSONTypeObject {
+			return mismatch(r, t)
+		}
+		var err error
+		if _, iterErr := r.iterFields(func(name []byte, item Result) bool {
+			f, ok := lookup(string(name))
+			if !ok {
+				return true
+			}
+			if err = f.dec(item, allocFieldByIndex(v, f.index)); err != nil {
+				err = errors.WithMessagef(err, "field %s", f.name)
+				return false
+			}
+			return true
+		}); iterErr != nil {
+			return iterErr
+		}
+		if err != nil || inlineDecoder == nil {
+			return err
+		}
+		mv := v.Field(info.inlineMap)
+		if mv.IsNil() {
+			mv.Set(reflect.MakeMap(mv.Type()))
+		}
+		return decodeMapElements(r, mv, mapKeyParser(mv.Type().Key()), inlineDecoder, func(name string) bool {
+			_, ok := lookup(name)
+			return ok
+		})
+	}
+}
+
+// allocFieldByIndex returns the nested field, allocating nil pointers of inlined structs.
+func allocFieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
@@ -0,0 +1,120 @@
+package gbson
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUnmarshal(t *testing.T) {
+	v := testMarshalStruct{
+		ID:       42,
+		Count:    7,
+		Big:      1 << 40,
+		Small:    -3,
+		Unsigned: 9,
+		Flag:     true,
+		Text:     "hello",
+		Data:     []byte{1, 2, 3},
+		Hash:     [4]byte{4, 5, 6, 7},
+		When:     time.UnixMilli(1668000000123).UTC(),
+		Tags:     []string{"a", "b"},
+		Child:    testMarshalChild{Name: "child", Score: 1.5},
+		ChildPtr: &testMarshalChild{Name: "ptr"},
+	}
+	doc := mustMarshal(t, v)
+	var expected, actual testMarshalStruct
+	require.NoError(t, bson.Unmarshal(doc, &expected))
+	require.NoError(t, Unmarshal(doc, &actual))
+	require.Equal(t, expected, actual)
+	require.Equal(t, v, actual)
+
+	var tagged, expectedTagged testMarshalTagged
+	doc = mustMarshal(t, testMarshalTagged{
+		Base:    testMarshalBase{ID: "id", Version: 3},
+		Meta:    &testMarshalChild{Name: "meta", Score: 2},
+		Name:    "name",
+		Version: "v1",
+		Extra:   map[string]interface{}{"extra": "x"},
+		Nested:  []testMarshalChild{{Name: "n"}},
+		Table:   map[string]*testMarshalChild{"k": nil, "j": {Score: 1}},
+	})
+	require.NoError(t, bson.Unmarshal(doc, &expectedTagged))
+	require.NoError(t, Unmarshal(doc, &tagged))
+	require.Equal(t, expectedTagged, tagged)
+
+	var recursive testMarshalRecursive
+	doc = mustMarshal(t, testMarshalRecursive{Value: 1, Next: &testMarshalRecursive{Value: 2}})
+	require.NoError(t, Get(doc).Unmarshal(&recursive))
+	require.Equal(t, int32(2), recursive.Next.Value)
+}
+
+func TestUnmarshalSubtree(t *testing.T) {
+	oid := primitive.NewObjectID()
+	doc := mustMarshal(t, bson.D{
+		{Key: "outer", Value: bson.D{
+			{Key: "Name", Value: "child"},
+			{Key: "score", Value: int32(3)},
+			{Key: "unknown", Value: true},
+		}},
+		{Key: "numbers", Value: bson.A{int32(1), int64(2), 3.0}},
+		{Key: "map", Value: bson.D{{Key: "1", Value: "a"}, {Key: "2", Value: nil}}},
+		{Key: "id", Value: oid},
+		{Key: "fraction", Value: 1.5},
+		{Key: "large", Value: int64(1) << 40},
+		{Key: "negative", Value: int32(-1)},
+	})
+	child := testMarshalChild{Name: "old", Score: 9}
+	require.NoError(t, Get(doc, "outer").Unmarshal(&child))
+	require.Equal(t, testMarshalChild{Name: "child", Score: 3}, child)
+
+	var numbers []int8
+	require.NoError(t, Get(doc, "numbers").Unmarshal(&numbers))
+	require.Equal(t, []int8{1, 2, 3}, numbers)
+	var fixed [2]int
+	require.ErrorIs(t, Get(doc, "numbers").Unmarshal(&fixed), ErrInvalidLength)
+
+	var m map[int]*string
+	require.NoError(t, Get(doc, "map").Unmarshal(&m))
+	require.Equal(t, "a", *m[1])
+	require.Nil(t, m[2])
+
+	var id primitive.ObjectID
+	require.NoError(t, Get(doc, "id").Unmarshal(&id))
+	require.Equal(t, oid, id)
+	var hex string
+	require.NoError(t, Get(doc, "id").Unmarshal(&hex))
+	require.Equal(t, oid.Hex(), hex)
+
+	var value interface{}
+	require.NoError(t, Get(doc, "outer").Unmarshal(&value))
+	require.Equal(t, map[string]interface{}{"Name": "child", "score": int32(3), "unknown": true}, value)
+	var r struct {
+		Outer Result
+		Large uint64
+	}
+	require.NoError(t, Unmarshal(doc, &r))
+	require.Equal(t, "child", r.Outer.Get("Name").String())
+	require.Equal(t, uint64(1)<<40, r.Large)
+
+	var truncated struct {
+		Fraction int `bson:",truncate"`
+	}
+	require.NoError(t, Unmarshal(doc, &truncated))
+	require.Equal(t, 1, truncated.Fraction)
+
+	var i int
+	require.ErrorIs(t, Get(doc, "fraction").Unmarshal(&i), ErrLossyConversion)
+	var i32 int32
+	require.ErrorIs(t, Get(doc, "large").Unmarshal(&i32), ErrLossyConversion)
+	var u uint
+	require.ErrorIs(t, Get(doc, "negative").Unmarshal(&u), ErrLossyConversion)
+	require.ErrorIs(t, Get(doc, "numbers").Unmarshal(&child), ErrTypeMismatch)
+	require.ErrorIs(t, Get(doc, "missing").Unmarshal(&child), ErrNotExist)
+	var ch chan int
+	require.ErrorIs(t, Get(doc, "id").Unmarshal(&ch), ErrUnsupportedType)
+	require.Error(t, Get(doc).Unmarshal(child))
+}
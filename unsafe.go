@@ -0,0 +1,9 @@
+//go:build !purego && !appengine
+
+package gbson
+
+import "unsafe"
+
+func bytesEqualToString(left []byte, right string) bool {
+	return *(*string)(unsafe.Pointer(&left)) == right
+}
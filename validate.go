@@ -0,0 +1,477 @@
+package gbson
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Valid reports whether the document is structurally valid, see Validate.
+func Valid(doc []byte) bool {
+	return Validate(doc) == nil
+}
+
+// Validate checks the structure of the whole document as a cheap pre-flight check before lazy access of
+// documents from untrusted peers: the declared lengths of the document, embedded documents and every
+// element fit in the bytes, documents end with the zero byte, keys are terminated, types are known
+// and length prefixes of strings and binaries are not negative. Bytes after the document are ignored,
+// Validator.NoTrailingBytes rejects them. An embedded document declaring a length shorter than its elements
+// ends at the type of an element rather than a zero byte, so it's found rather than losing the elements.
+//
+// The error is a Finding wrapping ErrInvalidLength or ErrUnsupportedType, with the offset and the path
+// of the invalid element.
+func Validate(doc []byte) error {
+	return Validator{}.Validate(doc)
+}
+
+// HasDuplicateKeys reports whether a key appears more than once in the document, see FindDuplicateKey.
+func HasDuplicateKeys(doc []byte, recursive bool) bool {
+	_, found := FindDuplicateKey(doc, recursive)
+	return found
+}
+
+// FindDuplicateKey finds the first key appearing more than once in a document and returns its dotted path.
+// If recursive is true, embedded documents, including the ones in arrays, are checked too.
+// The document is not validated, invalid parts are ignored.
+func FindDuplicateKey(doc []byte, recursive bool) (path string, found bool) {
+	var keys []string
+	if findDuplicateKey(Result{Type: BSONTypeObject, Raw: doc}, recursive, 1, &keys) {
+		return strings.Join(keys, "."), true
+	}
+	return "", false
+}
+
+// findDuplicateKey finds the duplicate key in the container at the depth, keys holds the path to the container
+// and is left with the path to the duplicate key if found.
+func findDuplicateKey(r Result, recursive bool, depth int, keys *[]string) (found bool) {
+	if checkDepth(depth) != nil {
+		return false
+	}
+	var seen map[string]struct{}
+	if r.Type == BSONTypeObject {
+		seen = make(map[string]struct{})
+	}
+	n := len(*keys)
+	_, _ = r.iterFields(func(key []byte, value Result) bool {
+		*keys = append((*keys)[:n], string(key))
+		if seen != nil {
+			if _, found = seen[string(key)]; found {
+				return false
+			}
+			seen[string(key)] = struct{}{}
+		}
+		if recursive && value.IsContainer() {
+			found = findDuplicateKey(value, recursive, depth+1, keys)
+		}
+		return !found
+	})
+	if !found {
+		*keys = (*keys)[:n]
+	}
+	return found
+}
+
+// Validator validates documents with options, the zero value validates the same as Validate.
+type Validator struct {
+	// Strict additionally checks what MongoDB's own validators enforce: keys and strings are valid UTF-8,
+	// strings end with the zero byte, booleans are 0 or 1, binary subtypes are not reserved, and binaries
+	// of the old subtype 0x02, UUIDs and MD5s have consistent lengths.
+	// Violations other than lengths wrap ErrInvalidValue.
+	Strict bool
+	// MaxDepth limits the nesting depth of documents and arrays, deeper ones are reported with ErrMaxDepth.
+	// If zero, DefaultMaxDepth is used.
+	MaxDepth int
+	// MaxDocumentSize limits the declared length of the document, MaxElementSize the declared length of
+	// any element value, including embedded documents, strings and binaries. Larger ones are reported with
+	// ErrTooLarge, so absurd sizes are rejected before any downstream allocation. Zero means no limit.
+	MaxDocumentSize int
+	MaxElementSize  int
+	// MaxElements limits the number of elements in the document, including the ones of embedded documents
+	// and arrays, so flat lists of tiny elements are rejected before each costs a downstream allocation.
+	// The first element over the limit is reported with ErrTooLarge. Zero means no limit.
+	MaxElements int
+	// NoDuplicateKeys reports keys appearing more than once in a document with ErrDuplicateKey.
+	// They are legal in bson, but lost or rejected by most JSON consumers.
+	NoDuplicateKeys bool
+	// NoTrailingBytes requires the document to take the whole buffer, bytes after it are reported with
+	// ErrInvalidLength, which catches framing bugs hidden by ignoring them.
+	NoTrailingBytes bool
+	// NoDollarKeys and NoDottedKeys report keys of documents starting with '$' or containing '.' with
+	// ErrInvalidKey, following the rules of MongoDB on insertion, so synthesized documents fail fast before
+	// the server rejects them. The keys $ref, $id and $db of DBRefs are allowed.
+	NoDollarKeys bool
+	NoDottedKeys bool
+	// CanonicalArrayKeys requires the keys of arrays to be "0", "1", "2" and so on, reporting the ones of sparse
+	// arrays with gaps, duplicates or non-numeric keys with ErrInvalidKey. The accessors read arrays as lists
+	// of their items regardless of the keys, which consumers indexing by the keys may not expect.
+	CanonicalArrayKeys bool
+	// CanonicalDecimals reports Decimal128 values which are not canonical, see Decimal128.IsCanonical,
+	// or are signaling NaNs, with ErrInvalidValue. Such bit patterns are never produced by encoders.
+	CanonicalDecimals bool
+}
+
+// UntrustedValidator returns the Validator of ParseUntrusted, bundling the limits and checks for documents
+// from untrusted peers of internet-facing services. The limits follow MongoDB: 16 MiB documents nested up to
+// 100 levels. The returned copy could be tuned for a caller, e.g. lowering MaxDocumentSize for small requests.
+func UntrustedValidator() Validator {
+	return Validator{
+		Strict:          true,
+		MaxDepth:        100,
+		MaxDocumentSize: 16 << 20,
+		MaxElements:     1 << 20,
+		NoDuplicateKeys: true,
+		NoTrailingBytes: true,
+	}
+}
+
+// ParseUntrusted validates the document from an untrusted peer with UntrustedValidator before it's
+// accessed lazily, and returns it as a Result. The error is the first Finding.
+//
+// The worst-case cost is linear in the input: validation reads every byte once for the structure and once
+// more for UTF-8 of keys and strings, and hashes every key once into a set of the keys of its document,
+// which is allocated for every document and embedded document. It's bounded by MaxDocumentSize before
+// the elements are read, and recursion is bounded by MaxDepth. Declared lengths are checked against
+// the available bytes before use, so they never cause reads or allocations beyond the input.
+func ParseUntrusted(doc []byte) (Result, error) {
+	if err := UntrustedValidator().Validate(doc); err != nil {
+		return Result{Type: BSONTypeUndefined}, err
+	}
+	return resultFromBytes(doc), nil
+}
+
+// Validate validates the document, see the package level Validate for the structural checks.
+// The error is the first Finding.
+func (opts Validator) Validate(doc []byte) error {
+	if findings := opts.validate(doc, 1); len(findings) > 0 {
+		return findings[0]
+	}
+	return nil
+}
+
+// Report validates the document and returns all the findings, empty if the document is valid.
+// Validation goes on after invalid elements as long as the lengths of the enclosing documents are reliable,
+// e.g. after an invalid string in an embedded document, the rest of the outer document is validated still.
+func (opts Validator) Report(doc []byte) []Finding {
+	return opts.validate(doc, 0)
+}
+
+// validate validates the document and returns up to limit findings, all of them if limit is zero.
+func (opts Validator) validate(doc []byte, limit int) []Finding {
+	v := validator{Validator: opts, limit: limit}
+	if v.MaxDepth == 0 {
+		v.MaxDepth = DefaultMaxDepth
+	}
+	v.validateContainer(doc, BSONTypeObject, 0)
+	if n := int(consumeInt32(doc)); v.NoTrailingBytes && !v.done() && n >= 5 && n < len(doc) {
+		v.report(ErrInvalidLength, n, "%d bytes after the document", len(doc)-n)
+	}
+	return v.findings
+}
+
+// locateError replaces an error of invalid lengths from processing the document with the first Finding
+// of validating it, which tells the offset and the path of the invalid part. Validation only runs on errors,
+// so it costs nothing on the success path.
+func locateError(doc []byte, err error) error {
+	if err == nil || !errors.Is(err, ErrInvalidLength) {
+		return err
+	}
+	if _, ok := err.(Finding); ok {
+		return err
+	}
+	if f := Validate(doc); errors.Is(f, ErrInvalidLength) {
+		return f
+	}
+	return err
+}
+
+// locate locates the error of processing the document or array by locateError, passing the output through.
+func (r Result) locate(out []byte, err error) ([]byte, error) {
+	if r.IsContainer() {
+		err = locateError(r.Raw, err)
+	}
+	return out, err
+}
+
+// LengthError is the cause of findings of mismatched lengths, it wraps ErrInvalidLength.
+type LengthError struct {
+	Expected int // the declared or required length in bytes
+	Actual   int // the available or actual length in bytes
+}
+
+func (e LengthError) Error() string {
+	return ErrInvalidLength.Error()
+}
+
+// Unwrap returns ErrInvalidLength.
+func (e LengthError) Unwrap() error {
+	return ErrInvalidLength
+}
+
+// Finding is a violation found by validation, it's an error wrapping the cause.
+type Finding struct {
+	Offset int    // offset in bytes of the invalid part in the document
+	Path   string // dotted path of the invalid element, "" for the document itself
+	Reason string
+	Cause  error // one of the sentinel errors, e.g. ErrInvalidLength
+}
+
+func (f Finding) Error() string {
+	return fmt.Sprintf("offset %d, path %q: %s: %v", f.Offset, f.Path, f.Reason, f.Cause)
+}
+
+// Unwrap returns the cause, so errors.Is works on findings.
+func (f Finding) Unwrap() error {
+	return f.Cause
+}
+
+// validator validates documents without allocations unless invalid.
+type validator struct {
+	Validator
+	path     [][]byte // keys of the containers and the element being validated
+	depth    int      // depth of the container being validated
+	elements int      // number of elements validated
+	findings []Finding
+	limit    int // stop after the number of findings if positive
+}
+
+// validateElementValue validates the value of the type, whose length is checked to fit, as Validate does
+// the value of an element, e.g. both cstrings of a regex are terminated. The error is the first Finding,
+// whose offset is relative to the value.
+func validateElementValue(tp Type, value []byte) error {
+	v := validator{Validator: Validator{MaxDepth: DefaultMaxDepth}, limit: 1}
+	if v.validateValue(tp, value, 0); len(v.findings) > 0 {
+		return v.findings[0]
+	}
+	return nil
+}
+
+// report adds the finding at the offset of the document with the path of the current element.
+func (v *validator) report(cause error, offset int, format string, args ...interface{}) {
+	path := make([]string, len(v.path))
+	for i, key := range v.path {
+		path[i] = string(key)
+	}
+	v.findings = append(v.findings, Finding{
+		Offset: offset,
+		Path:   strings.Join(path, "."),
+		Reason: fmt.Sprintf(format, args...),
+		Cause:  cause,
+	})
+}
+
+// done reports whether enough findings are found.
+func (v *validator) done() bool {
+	return v.limit > 0 && len(v.findings) >= v.limit
+}
+
+// validateContainer validates the document or array raw at the offset of the whole document.
+func (v *validator) validateContainer(raw []byte, containerType Type, offset int) {
+	if len(raw) < 5 {
+		v.report(LengthError{Expected: 5, Actual: len(raw)}, offset, "%d bytes are too short for a document", len(raw))
+		return
+	}
+	n := int(consumeInt32(raw))
+	if v.MaxDocumentSize > 0 && v.depth == 0 && n > v.MaxDocumentSize {
+		v.report(ErrTooLarge, offset, "document declares %d bytes, limit %d", n, v.MaxDocumentSize)
+		return
+	}
+	if n < 5 || n > len(raw) {
+		v.report(LengthError{Expected: n, Actual: len(raw)}, offset, "declares %d bytes, %d available", n, len(raw))
+		return
+	}
+	if raw[n-1] != 0 {
+		v.report(ErrInvalidLength, offset+n-1, "document is not terminated by a zero byte")
+		return
+	}
+	if v.depth == v.MaxDepth {
+		v.report(ErrMaxDepth, offset, "nested deeper than %d", v.MaxDepth)
+		return
+	}
+	v.depth++
+	depth := len(v.path)
+	defer func() { v.path, v.depth = v.path[:depth], v.depth-1 }()
+	var keys map[string]struct{}
+	if v.NoDuplicateKeys && containerType == BSONTypeObject {
+		keys = make(map[string]struct{})
+	}
+	index := 0
+	for pos := 4; pos < n-1 && !v.done(); index++ {
+		tp := Type(raw[pos])
+		name, nameLen := consumeCString(raw[pos+1 : n-1])
+		if nameLen == 0 {
+			v.report(ErrInvalidLength, offset+pos, "key is not terminated")
+			return
+		}
+		v.path = append(v.path[:depth], name)
+		if v.elements++; v.MaxElements > 0 && v.elements > v.MaxElements {
+			if v.elements == v.MaxElements+1 { // reported once, the enclosing containers stop silently
+				v.report(ErrTooLarge, offset+pos, "more than %d elements", v.MaxElements)
+			}
+			return
+		}
+		if _, ok := typeNames[tp]; !ok {
+			v.report(ErrUnsupportedType, offset+pos, "type %v", tp)
+			return
+		}
+		if v.Strict && !utf8.Valid(name) {
+			v.report(ErrInvalidValue, offset+pos+1, "key is not valid UTF-8")
+		}
+		if containerType == BSONTypeObject {
+			v.validateKey(name, offset+pos+1)
+		} else if v.CanonicalArrayKeys && !bytesEqualToString(name, strconv.Itoa(index)) {
+			v.report(ErrInvalidKey, offset+pos+1, "array key %q, expected %q", name, strconv.Itoa(index))
+		}
+		if keys != nil {
+			if _, ok := keys[string(name)]; ok {
+				v.report(ErrDuplicateKey, offset+pos+1, "key %q appears again", name)
+			}
+			keys[string(name)] = struct{}{}
+		}
+		start := pos + 1 + nameLen
+		value := raw[start : n-1]
+		valueLen := consumeValue(tp, value)
+		if valueLen < 0 {
+			var cause error = ErrInvalidLength
+			if n := declaredValueLen(tp, value); n > len(value) {
+				cause = LengthError{Expected: n, Actual: len(value)}
+			}
+			v.report(cause, offset+start, "%v value exceeds the document", tp)
+			return
+		}
+		if v.MaxElementSize > 0 && valueLen > v.MaxElementSize {
+			v.report(ErrTooLarge, offset+start, "%v value of %d bytes, limit %d", tp, valueLen, v.MaxElementSize)
+			pos = start + valueLen
+			continue
+		}
+		if !v.validateValue(tp, value[:valueLen], offset+start) {
+			return
+		}
+		if v.Strict && !v.done() {
+			v.validateStrict(tp, value[:valueLen], offset+start)
+		}
+		if v.CanonicalDecimals && tp == BSONTypeDecimal128 {
+			if reason := (Result{Type: tp, Raw: value}).Decimal128().abnormality(); reason != "" {
+				v.report(ErrInvalidValue, offset+start, "decimal128 %s", reason)
+			}
+		}
+		pos = start + valueLen
+	}
+}
+
+// validateKey validates the key of a document at the offset against the key name policies.
+func (v *validator) validateKey(key []byte, offset int) {
+	if v.NoDollarKeys && len(key) > 0 && key[0] == '$' {
+		switch string(key) {
+		case "$ref", "$id", "$db":
+		default:
+			v.report(ErrInvalidKey, offset, "key %q starts with '$'", key)
+		}
+	}
+	if v.NoDottedKeys {
+		if i := bytes.IndexByte(key, '.'); i >= 0 {
+			v.report(ErrInvalidKey, offset+i, "key %q contains '.'", key)
+		}
+	}
+}
+
+// validateValue validates the layout of the value at the offset, whose length is already checked.
+// It returns false if the length of the value is not reliable, so the rest of the document can't be validated.
+func (v *validator) validateValue(tp Type, value []byte, offset int) bool {
+	switch tp {
+	case BSONTypeObject, BSONTypeArray:
+		v.validateContainer(value, tp, offset)
+	case BSONTypeString, BSONTypeJavaScript, BSONTypeSymbol:
+		if n := len(value) - 4; n < 1 { // the value is cut by the declared length, which may be negative
+			v.report(ErrInvalidLength, offset, "string declares %d bytes", n)
+			return false
+		}
+	case BSONTypeBinary:
+		if n := len(value) - 5; n < 0 {
+			v.report(ErrInvalidLength, offset, "binary declares %d bytes", n)
+			return false
+		}
+	case BSONTypeDBPointer:
+		if n := len(value) - 16; n < 1 {
+			v.report(ErrInvalidLength, offset, "namespace declares %d bytes", n)
+			return false
+		}
+	case BSONTypeRegex:
+		_, patternLen := consumeCString(value)
+		if patternLen == 0 || patternLen == len(value) {
+			v.report(ErrInvalidLength, offset, "regex is not terminated")
+			return false
+		}
+	case BSONTypeJavaScriptWithScope:
+		return v.validateCodeWithScope(value, offset)
+	}
+	return true
+}
+
+// validateCodeWithScope validates the code and the scope, whose total length is already checked to fit,
+// it returns false if the total length is too short to be reliable.
+func (v *validator) validateCodeWithScope(value []byte, offset int) bool {
+	if len(value) < 4+5+5 {
+		v.report(ErrInvalidLength, offset, "code with scope of %d bytes is too short", len(value))
+		return false
+	}
+	if consumeInt32(value[4:]) < 1 {
+		v.report(ErrInvalidLength, offset+4, "code declares %d bytes", consumeInt32(value[4:]))
+		return true
+	}
+	_, codeLen := consumeString(value[4:])
+	if codeLen == 0 {
+		v.report(ErrInvalidLength, offset+4, "code exceeds the code with scope")
+		return true
+	}
+	scope := value[4+codeLen:]
+	if n := int(consumeInt32(scope)); n != len(scope) {
+		v.report(LengthError{Expected: n, Actual: len(scope)}, offset+4+codeLen, "scope declares %d bytes, %d available", n, len(scope))
+		return true
+	}
+	v.validateContainer(scope, BSONTypeObject, offset+4+codeLen)
+	return true
+}
+
+// validateStrict validates the value of the valid layout in the strict mode.
+func (v *validator) validateStrict(tp Type, value []byte, offset int) {
+	switch tp {
+	case BSONTypeString, BSONTypeJavaScript, BSONTypeSymbol, BSONTypeDBPointer:
+		v.validateString(value, offset)
+	case BSONTypeJavaScriptWithScope:
+		if _, n := consumeString(value[4:]); n > 0 { // the length of value is checked by validateCodeWithScope
+			v.validateString(value[4:], offset+4)
+		}
+	case BSONTypeRegex:
+		if !utf8.Valid(value) {
+			v.report(ErrInvalidValue, offset, "regex is not valid UTF-8")
+		}
+	case BSONTypeBoolean:
+		if value[0] > 1 {
+			v.report(ErrInvalidValue, offset, "boolean of byte %d", value[0])
+		}
+	case BSONTypeBinary:
+		n, subtype := int(consumeInt32(value)), value[4]
+		switch {
+		case subtype > 0x09 && subtype < 0x80:
+			v.report(ErrInvalidValue, offset+4, "binary subtype 0x%02x is reserved", subtype)
+		case subtype == 0x02 && (n < 4 || int(consumeInt32(value[5:])) != n-4):
+			v.report(ErrInvalidLength, offset, "old binary of %d bytes declares %d bytes", n, consumeInt32(value[5:]))
+		case (subtype == 0x03 || subtype == 0x04 || subtype == 0x05) && n != 16:
+			v.report(LengthError{Expected: 16, Actual: n}, offset, "binary of subtype 0x%02x has %d bytes, expects 16", subtype, n)
+		}
+	}
+}
+
+// validateString validates a length prefixed string ends with the zero byte and is valid UTF-8.
+func (v *validator) validateString(value []byte, offset int) {
+	s, n := consumeString(value)
+	if value[n-1] != 0 {
+		v.report(ErrInvalidLength, offset+n-1, "string is not terminated by a zero byte")
+	} else if !utf8.Valid(s) {
+		v.report(ErrInvalidValue, offset+4, "string is not valid UTF-8")
+	}
+}
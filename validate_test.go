@@ -0,0 +1,269 @@
+package gbson
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidate(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	require.NoError(t, Validate(doc))
+	require.True(t, Valid(doc))
+	require.True(t, Valid(append(doc, 1, 2, 3)))
+
+	for _, bad := range [][]byte{nil, {5, 0, 0, 0}, doc[:len(doc)-1], append(doc[:len(doc)-1:len(doc)-1], 1)} {
+		require.ErrorIs(t, Validate(bad), ErrInvalidLength)
+		require.False(t, Valid(bad))
+	}
+
+	doc = mustMarshal(t, bson.D{
+		{Key: "a", Value: bson.D{{Key: "b", Value: bson.A{"x"}}}},
+		{Key: "c", Value: primitive.CodeWithScope{Code: "x", Scope: bson.D{}}},
+	})
+	corrupt := func(offset int, value int32) []byte {
+		bad := append([]byte(nil), doc...)
+		binary.LittleEndian.PutUint32(bad[offset:], uint32(value))
+		return bad
+	}
+	// doc: 4 length, 0x03 "a\0" at 4, embedded document at 7, 0x04 "b\0" at 11, array at 14,
+	// 0x02 "0\0" at 18, string at 21, code with scope at 32
+	err := Validate(corrupt(21, 0))
+	require.ErrorIs(t, err, ErrInvalidLength)
+	require.Contains(t, err.Error(), `offset 21, path "a.b.0": string declares 0 bytes`)
+	err = Validate(corrupt(14, 100))
+	require.ErrorIs(t, err, ErrInvalidLength)
+	require.Contains(t, err.Error(), `path "a.b"`)
+	err = Validate(corrupt(7, 4))
+	require.Contains(t, err.Error(), `offset 7, path "a"`)
+	err = Validate(corrupt(36, 10))
+	require.Contains(t, err.Error(), `offset 36, path "c": code exceeds the code with scope`)
+	require.NoError(t, Validate(doc))
+
+	bad := append([]byte(nil), doc...)
+	bad[18] = 0x14
+	err = Validate(bad)
+	require.ErrorIs(t, err, ErrUnsupportedType)
+	require.Contains(t, err.Error(), `offset 18, path "a.b.0": type Type(0x14)`)
+}
+
+func TestValidateStrict(t *testing.T) {
+	strict := Validator{Strict: true}
+	doc := mustMarshal(t, bson.D{
+		{Key: "s", Value: "中文"},
+		{Key: "b", Value: true},
+		{Key: "old", Value: primitive.Binary{Subtype: 0x02, Data: []byte{1, 2}}},
+		{Key: "uuid", Value: primitive.Binary{Subtype: 0x04, Data: make([]byte, 16)}},
+		{Key: "user", Value: primitive.Binary{Subtype: 0x80, Data: []byte{1}}},
+		{Key: "scope", Value: primitive.CodeWithScope{Code: "x", Scope: bson.D{}}},
+	})
+	require.NoError(t, strict.Validate(doc))
+	err := strict.Validate(getTestJSONDocument(t))
+	require.ErrorIs(t, err, ErrInvalidValue)
+	require.Contains(t, err.Error(), `path "string": string is not valid UTF-8`)
+
+	for _, c := range []struct {
+		doc   bson.D
+		patch func([]byte)
+		cause error
+		msg   string
+	}{
+		{bson.D{{Key: "b", Value: true}}, func(bs []byte) { bs[7] = 2 }, ErrInvalidValue, "boolean of byte 2"},
+		{bson.D{{Key: "s", Value: "ab"}}, func(bs []byte) { bs[13] = 'c' }, ErrInvalidLength, "string is not terminated"},
+		{bson.D{{Key: "\xff", Value: 1}}, nil, ErrInvalidValue, "key is not valid UTF-8"},
+		{bson.D{{Key: "x", Value: primitive.Binary{Subtype: 0x10}}}, nil, ErrInvalidValue, "binary subtype 0x10 is reserved"},
+		{bson.D{{Key: "x", Value: primitive.Binary{Subtype: 0x04, Data: make([]byte, 15)}}}, nil, ErrInvalidLength, "has 15 bytes, expects 16"},
+		{bson.D{{Key: "x", Value: primitive.Binary{Subtype: 0x02, Data: []byte{1}}}}, func(bs []byte) { bs[12] = 9 }, ErrInvalidLength, "old binary"},
+		{bson.D{{Key: "x", Value: primitive.Regex{Pattern: "\xff"}}}, nil, ErrInvalidValue, "regex is not valid UTF-8"},
+	} {
+		bad := mustMarshal(t, c.doc)
+		if c.patch != nil {
+			c.patch(bad)
+		}
+		require.NoError(t, Validate(bad), c.msg)
+		err := strict.Validate(bad)
+		require.ErrorIs(t, err, c.cause, c.msg)
+		require.Contains(t, err.Error(), c.msg)
+	}
+}
+
+func TestValidateReport(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "a", Value: bson.D{{Key: "s", Value: "x"}, {Key: "t", Value: "y"}}},
+		{Key: "b", Value: bson.A{"z"}},
+		{Key: "c", Value: true},
+	})
+	require.Empty(t, Validator{}.Report(doc))
+	// a: document at 7, "s" string at 14, "t" string at 23; b: array at 33, "0" string at 40; c: boolean at 50
+	bad := append([]byte(nil), doc...)
+	binary.LittleEndian.PutUint32(bad[14:], 0)
+	binary.LittleEndian.PutUint32(bad[40:], 0)
+	bad[50] = 2
+	findings := Validator{Strict: true}.Report(bad)
+	require.Equal(t, []Finding{
+		{Offset: 14, Path: "a.s", Reason: "string declares 0 bytes", Cause: ErrInvalidLength},
+		{Offset: 40, Path: "b.0", Reason: "string declares 0 bytes", Cause: ErrInvalidLength},
+		{Offset: 50, Path: "c", Reason: "boolean of byte 2", Cause: ErrInvalidValue},
+	}, findings)
+	err := Validator{Strict: true}.Validate(bad)
+	require.Equal(t, findings[0], err)
+	require.ErrorIs(t, err, ErrInvalidLength)
+	require.EqualError(t, err, `offset 14, path "a.s": string declares 0 bytes: invalid length`)
+
+	findings = Validator{}.Report(bad[:len(bad)-1])
+	require.Equal(t, []Finding{{Offset: 0, Path: "", Reason: "declares 52 bytes, 51 available", Cause: LengthError{Expected: 52, Actual: 51}}}, findings)
+}
+
+func TestValidateSizeLimits(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "a", Value: bson.D{{Key: "s", Value: "x"}, {Key: "t", Value: "y"}}},
+		{Key: "b", Value: bson.A{"z"}},
+		{Key: "c", Value: true},
+	})
+	require.NoError(t, Validator{MaxDocumentSize: 52, MaxElementSize: 23}.Validate(doc))
+	require.Equal(t, []Finding{
+		{Offset: 0, Path: "", Reason: "document declares 52 bytes, limit 51", Cause: ErrTooLarge},
+	}, Validator{MaxDocumentSize: 51}.Report(doc))
+	require.Equal(t, []Finding{
+		{Offset: 7, Path: "a", Reason: "object value of 23 bytes, limit 10", Cause: ErrTooLarge},
+		{Offset: 33, Path: "b", Reason: "array value of 14 bytes, limit 10", Cause: ErrTooLarge},
+	}, Validator{MaxElementSize: 10}.Report(doc))
+
+	// absurd declared lengths are too large before being out of the buffer
+	huge := append([]byte(nil), doc...)
+	binary.LittleEndian.PutUint32(huge, 1<<31-1)
+	require.ErrorIs(t, Validator{MaxDocumentSize: 16 << 20}.Validate(huge), ErrTooLarge)
+
+	// elements of embedded documents and arrays are counted too
+	require.NoError(t, Validator{MaxElements: 6}.Validate(doc))
+	require.Equal(t, []Finding{
+		{Offset: 37, Path: "b.0", Reason: "more than 4 elements", Cause: ErrTooLarge},
+	}, Validator{MaxElements: 4}.Report(doc))
+}
+
+func TestParseUntrusted(t *testing.T) {
+	doc := mustMarshal(t, bson.D{{Key: "a", Value: bson.A{"x"}}})
+	r, err := ParseUntrusted(doc)
+	require.NoError(t, err)
+	require.Equal(t, "x", r.Get("a", "0").String())
+
+	for _, bad := range [][]byte{
+		append(append([]byte(nil), doc...), 0),
+		mustMarshal(t, bson.D{{Key: "a", Value: 1}, {Key: "a", Value: 2}}),
+		mustMarshal(t, bson.D{{Key: "a", Value: "\xff"}}),
+		nestedDocument(101),
+	} {
+		r, err = ParseUntrusted(bad)
+		require.Error(t, err)
+		require.False(t, r.Exist())
+	}
+	huge := []byte{0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(huge, 16<<20+1)
+	_, err = ParseUntrusted(huge)
+	require.ErrorIs(t, err, ErrTooLarge)
+
+	small := UntrustedValidator()
+	small.MaxDocumentSize = len(doc) - 1
+	require.ErrorIs(t, small.Validate(doc), ErrTooLarge)
+	_, err = ParseUntrusted(doc)
+	require.NoError(t, err, "tuning a validator doesn't affect the other callers")
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "a", Value: int32(1)},
+		{Key: "b", Value: bson.A{bson.D{{Key: "x", Value: 1}, {Key: "y", Value: 2}, {Key: "x", Value: 3}}}},
+		{Key: "c", Value: "z"},
+	})
+	require.False(t, HasDuplicateKeys(doc, false))
+	require.True(t, HasDuplicateKeys(doc, true))
+	path, found := FindDuplicateKey(doc, true)
+	require.True(t, found)
+	require.Equal(t, "b.0.x", path)
+	require.NoError(t, Validate(doc))
+	// b: array at 14, "0": document at 21, whose second "x" element is at 39
+	require.Equal(t, []Finding{
+		{Offset: 40, Path: "b.0.x", Reason: `key "x" appears again`, Cause: ErrDuplicateKey},
+	}, Validator{NoDuplicateKeys: true}.Report(doc))
+
+	doc = mustMarshal(t, bson.D{{Key: "a", Value: 1}, {Key: "a", Value: bson.A{1, 1}}})
+	path, found = FindDuplicateKey(doc, false)
+	require.True(t, found)
+	require.Equal(t, "a", path)
+	require.False(t, HasDuplicateKeys(mustMarshal(t, bson.D{{Key: "a", Value: bson.D{{Key: "a", Value: 1}}}}), true))
+}
+
+func TestValidateTrailingBytes(t *testing.T) {
+	doc := append(mustMarshal(t, bson.D{{Key: "a", Value: int32(1)}}), 0, 0)
+	require.NoError(t, Validate(doc))
+	require.NoError(t, Validator{NoTrailingBytes: true}.Validate(doc[:len(doc)-2]))
+	require.Equal(t, []Finding{
+		{Offset: 12, Path: "", Reason: "2 bytes after the document", Cause: ErrInvalidLength},
+	}, Validator{NoTrailingBytes: true}.Report(doc))
+}
+
+func TestValidateKeyNames(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "a.b", Value: 1}}},
+		{Key: "ref", Value: bson.D{{Key: "$ref", Value: "c"}, {Key: "$id", Value: 1}}},
+		{Key: "a", Value: bson.A{bson.D{{Key: "$x.y", Value: 1}}}},
+	})
+	require.NoError(t, Validate(doc))
+	findings := Validator{NoDollarKeys: true}.Report(doc)
+	require.Len(t, findings, 2)
+	require.Equal(t, Finding{Offset: 5, Path: "$set", Reason: `key "$set" starts with '$'`, Cause: ErrInvalidKey}, findings[0])
+	require.Equal(t, "a.0.$x.y", findings[1].Path)
+	findings = Validator{NoDottedKeys: true}.Report(doc)
+	require.Len(t, findings, 2)
+	// $set at 4, its document at 10 and a.b at 14
+	require.Equal(t, Finding{Offset: 16, Path: "$set.a.b", Reason: `key "a.b" contains '.'`, Cause: ErrInvalidKey}, findings[0])
+	require.ErrorIs(t, findings[1], ErrInvalidKey)
+	require.Len(t, Validator{NoDollarKeys: true, NoDottedKeys: true}.Report(doc), 4)
+}
+
+func TestCanonicalArrayKeys(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "a", Value: bson.D{{Key: "0", Value: int32(1)}, {Key: "2", Value: int32(2)}, {Key: "x", Value: int32(3)}}},
+		{Key: "b", Value: bson.A{1, bson.A{"x"}}},
+	})
+	doc[4] = byte(BSONTypeArray)
+	require.NoError(t, Validate(doc))
+	require.NoError(t, Validator{CanonicalArrayKeys: true}.Validate(mustMarshal(t, bson.D{{Key: "b", Value: bson.A{1, bson.A{"x"}}}})))
+	// a at 4, its array at 7 and the items of 7 bytes at 11
+	require.Equal(t, []Finding{
+		{Offset: 19, Path: "a.2", Reason: `array key "2", expected "1"`, Cause: ErrInvalidKey},
+		{Offset: 26, Path: "a.x", Reason: `array key "x", expected "2"`, Cause: ErrInvalidKey},
+	}, Validator{CanonicalArrayKeys: true}.Report(doc))
+}
+
+func TestLocateError(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "a", Value: bson.D{{Key: "s", Value: "x"}, {Key: "t", Value: "y"}}},
+		{Key: "b", Value: bson.A{"z"}},
+	})
+	// a: document at 7, its length is increased beyond the 40 bytes before the terminating zero
+	bad := append([]byte(nil), doc...)
+	binary.LittleEndian.PutUint32(bad[7:], 100)
+	for _, err := range []error{
+		func() error { _, err := ToJSON(bad); return err }(),
+		func() error { _, err := ToYAML(bad); return err }(),
+		func() error { _, err := ToMsgPack(bad); return err }(),
+		func() error { _, err := ToStruct(bad); return err }(),
+		Walk(bad, &recordingHandler{}),
+		Unmarshal(bad, &map[string]interface{}{}),
+		Get(bad).GetIter(func(Result) bool { return true }, "b"),
+	} {
+		require.ErrorIs(t, err, ErrInvalidLength)
+		var f Finding
+		require.True(t, errors.As(err, &f), "%v", err)
+		require.Equal(t, 7, f.Offset)
+		require.Equal(t, "a", f.Path)
+		var lengthErr LengthError
+		require.True(t, errors.As(err, &lengthErr))
+		require.Equal(t, LengthError{Expected: 100, Actual: 40}, lengthErr)
+	}
+}
@@ -0,0 +1,230 @@
+package gbson
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ValueReader returns mongo-driver's bsonrw.ValueReader reading the value, so the value could be decoded by
+// any decoder of mongo-driver, e.g. bson.NewDecoder, without copying the bytes.
+func (r Result) ValueReader() bsonrw.ValueReader {
+	return resultReader{r: r}
+}
+
+// resultReader implements bsonrw.ValueReader and bsonrw.BytesReader.
+type resultReader struct {
+	r Result
+}
+
+// resultDocumentReader implements bsonrw.DocumentReader and bsonrw.ArrayReader.
+type resultDocumentReader struct {
+	bs []byte // the remaining elements
+}
+
+var (
+	_ bsonrw.ValueReader    = resultReader{}
+	_ bsonrw.BytesReader    = resultReader{}
+	_ bsonrw.DocumentReader = (*resultDocumentReader)(nil)
+	_ bsonrw.ArrayReader    = (*resultDocumentReader)(nil)
+)
+
+// check returns an error if the value is not of the type or malformed.
+func (vr resultReader) check(tp Type) error {
+	if vr.r.Type != tp {
+		return errors.Wrapf(ErrTypeMismatch, "read %v from %v", tp, vr.r.Type)
+	}
+	return vr.r.checkLength()
+}
+
+func (vr resultReader) Type() bsontype.Type {
+	return bsontype.Type(vr.r.Type)
+}
+
+func (vr resultReader) Skip() error {
+	return nil
+}
+
+func (vr resultReader) ReadValueBytes(dst []byte) (bsontype.Type, []byte, error) {
+	return bsontype.Type(vr.r.Type), append(dst, vr.r.Raw...), nil
+}
+
+func newDocumentReader(raw []byte) (*resultDocumentReader, error) {
+	elements, ok := containerElements(raw)
+	if !ok {
+		return nil, ErrInvalidLength
+	}
+	return &resultDocumentReader{bs: elements}, nil
+}
+
+func (vr resultReader) ReadArray() (bsonrw.ArrayReader, error) {
+	if err := vr.check(BSONTypeArray); err != nil {
+		return nil, err
+	}
+	return newDocumentReader(vr.r.Raw)
+}
+
+func (vr resultReader) ReadDocument() (bsonrw.DocumentReader, error) {
+	if err := vr.check(BSONTypeObject); err != nil {
+		return nil, err
+	}
+	return newDocumentReader(vr.r.Raw)
+}
+
+func (vr resultReader) ReadBinary() ([]byte, byte, error) {
+	if err := vr.check(BSONTypeBinary); err != nil {
+		return nil, 0, err
+	}
+	if len(vr.r.Raw) < 5 {
+		return nil, 0, ErrInvalidLength
+	}
+	subtype, data := vr.r.Binary()
+	return data, subtype, nil
+}
+
+func (vr resultReader) ReadBoolean() (bool, error) {
+	if err := vr.check(BSONTypeBoolean); err != nil {
+		return false, err
+	}
+	return vr.r.Bool(), nil
+}
+
+func (vr resultReader) ReadCodeWithScope() (string, bsonrw.DocumentReader, error) {
+	if err := vr.check(BSONTypeJavaScriptWithScope); err != nil {
+		return "", nil, err
+	}
+	code, scope := vr.r.JavaScriptWithScope()
+	if !scope.Exist() {
+		return "", nil, ErrInvalidLength
+	}
+	dr, err := newDocumentReader(scope.Raw)
+	return code, dr, err
+}
+
+func (vr resultReader) ReadDBPointer() (string, primitive.ObjectID, error) {
+	if err := vr.check(BSONTypeDBPointer); err != nil {
+		return "", primitive.ObjectID{}, err
+	}
+	ns, id, ok := vr.r.DBPointer()
+	if !ok {
+		return "", primitive.ObjectID{}, ErrInvalidLength
+	}
+	return ns, id, nil
+}
+
+func (vr resultReader) ReadDateTime() (int64, error) {
+	if err := vr.check(BSONTypeDateTime); err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(vr.r.Raw)), nil
+}
+
+func (vr resultReader) ReadDecimal128() (primitive.Decimal128, error) {
+	if err := vr.check(BSONTypeDecimal128); err != nil {
+		return primitive.Decimal128{}, err
+	}
+	d := vr.r.Decimal128()
+	return primitive.NewDecimal128(d.High, d.Low), nil
+}
+
+func (vr resultReader) ReadDouble() (float64, error) {
+	if err := vr.check(BSONTypeDouble); err != nil {
+		return 0, err
+	}
+	return vr.r.Float64(), nil
+}
+
+func (vr resultReader) ReadInt32() (int32, error) {
+	if err := vr.check(BSONTypeInt32); err != nil {
+		return 0, err
+	}
+	return vr.r.Int32(), nil
+}
+
+func (vr resultReader) ReadInt64() (int64, error) {
+	if err := vr.check(BSONTypeInt64); err != nil {
+		return 0, err
+	}
+	return vr.r.Int64(), nil
+}
+
+func (vr resultReader) readString(tp Type) (string, error) {
+	if err := vr.check(tp); err != nil {
+		return "", err
+	}
+	return vr.r.StringE()
+}
+
+func (vr resultReader) ReadJavascript() (string, error) {
+	return vr.readString(BSONTypeJavaScript)
+}
+
+func (vr resultReader) ReadString() (string, error) {
+	return vr.readString(BSONTypeString)
+}
+
+func (vr resultReader) ReadSymbol() (string, error) {
+	return vr.readString(BSONTypeSymbol)
+}
+
+func (vr resultReader) ReadMaxKey() error {
+	return vr.check(BSONTypeMaxKey)
+}
+
+func (vr resultReader) ReadMinKey() error {
+	return vr.check(BSONTypeMinKey)
+}
+
+func (vr resultReader) ReadNull() error {
+	return vr.check(BSONTypeNull)
+}
+
+func (vr resultReader) ReadUndefined() error {
+	return vr.check(BSONTypeUndefined)
+}
+
+func (vr resultReader) ReadObjectID() (primitive.ObjectID, error) {
+	if err := vr.check(BSONTypeObjectID); err != nil {
+		return primitive.ObjectID{}, err
+	}
+	return vr.r.ObjectID(), nil
+}
+
+func (vr resultReader) ReadRegex() (string, string, error) {
+	if err := vr.check(BSONTypeRegex); err != nil {
+		return "", "", err
+	}
+	pattern, options := vr.r.Regex()
+	return pattern, options, nil
+}
+
+func (vr resultReader) ReadTimestamp() (uint32, uint32, error) {
+	if err := vr.check(BSONTypeTimestamp); err != nil {
+		return 0, 0, err
+	}
+	t, i := vr.r.Timestamp()
+	return t, i, nil
+}
+
+func (dr *resultDocumentReader) ReadElement() (string, bsonrw.ValueReader, error) {
+	if len(dr.bs) == 0 {
+		return "", nil, bsonrw.ErrEOD
+	}
+	tp, name, value, n := consumeElement(dr.bs)
+	if n < 0 {
+		return "", nil, ErrInvalidLength
+	}
+	dr.bs = dr.bs[n:]
+	return string(name), resultReader{r: Result{Type: tp, Raw: value}}, nil
+}
+
+func (dr *resultDocumentReader) ReadValue() (bsonrw.ValueReader, error) {
+	_, vr, err := dr.ReadElement()
+	if err == bsonrw.ErrEOD {
+		err = bsonrw.ErrEOA
+	}
+	return vr, err
+}
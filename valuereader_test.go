@@ -0,0 +1,34 @@
+package gbson
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+)
+
+func TestValueReader(t *testing.T) {
+	doc := getTestJSONDocument(t)
+	var buf bytes.Buffer
+	vw, err := bsonrw.NewBSONValueWriter(&buf)
+	require.NoError(t, err)
+	require.NoError(t, bsonrw.Copier{}.CopyDocument(vw, Get(doc).ValueReader()))
+	require.Equal(t, doc, buf.Bytes())
+
+	dec, err := bson.NewDecoder(Get(doc, "doc").ValueReader())
+	require.NoError(t, err)
+	var v struct {
+		A int
+		B []interface{}
+	}
+	require.NoError(t, dec.Decode(&v))
+	require.Equal(t, 1, v.A)
+	require.Equal(t, []interface{}{int64(2), "x", bson.D{}}, v.B)
+
+	_, err = Get(doc, "string").ValueReader().ReadInt32()
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	_, err = Result{Type: BSONTypeObject, Raw: doc[:10]}.ValueReader().ReadDocument()
+	require.ErrorIs(t, err, ErrInvalidLength)
+}
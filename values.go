@@ -0,0 +1,259 @@
+package gbson
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Accessors of the bson specific types.
+
+// ObjectID returns the 12 bytes of an ObjectID value, all zeros for the other types.
+func (r Result) ObjectID() (id [12]byte) {
+	if r.Type == BSONTypeObjectID && len(r.Raw) >= 12 {
+		copy(id[:], r.Raw)
+	}
+	return
+}
+
+// ObjectIDHex returns the 24 characters hex string of an ObjectID value, "" for the other types.
+func (r Result) ObjectIDHex() string {
+	if r.Type == BSONTypeObjectID && len(r.Raw) >= 12 {
+		return hex.EncodeToString(r.Raw[:12])
+	}
+	return ""
+}
+
+// ObjectIDTime returns the creation time embedded in an ObjectID value, which is in seconds.
+func (r Result) ObjectIDTime() time.Time {
+	if r.Type == BSONTypeObjectID && len(r.Raw) >= 12 {
+		return time.Unix(int64(binary.BigEndian.Uint32(r.Raw)), 0)
+	}
+	return time.Time{}
+}
+
+// Binary returns the subtype and the payload of a Binary value, data is nil for the other types.
+// The data aliases the source buffer, use BinaryCopy if it's retained after the buffer is reused.
+// For the deprecated subtype 0x02, the extra length prefix is stripped from the data.
+func (r Result) Binary() (subtype byte, data []byte) {
+	if r.Type != BSONTypeBinary || len(r.Raw) < 5 {
+		return 0, nil
+	}
+	n := int(consumeInt32(r.Raw))
+	subtype, data = r.Raw[4], r.Raw[5:]
+	if n < 0 || n > len(data) {
+		return 0, nil
+	}
+	data = data[:n]
+	if subtype == 0x02 && len(data) >= 4 {
+		if inner := int(consumeInt32(data)); inner >= 0 && inner <= len(data)-4 {
+			data = data[4 : 4+inner]
+		}
+	}
+	return subtype, data
+}
+
+// BinaryCopy is like Binary, but returns a copy of the payload.
+func (r Result) BinaryCopy() (subtype byte, data []byte) {
+	subtype, data = r.Binary()
+	if data == nil {
+		return subtype, nil
+	}
+	return subtype, append(make([]byte, 0, len(data)), data...)
+}
+
+// UUIDRepresentation is the byte order of UUIDs stored in the legacy binary subtype 0x03,
+// which was decided by the drivers writing them.
+type UUIDRepresentation uint8
+
+const (
+	// UUIDPythonLegacy stores the bytes in the standard order, which is the same as subtype 0x04.
+	UUIDPythonLegacy UUIDRepresentation = iota
+	// UUIDJavaLegacy stores each 8 bytes half in reversed order.
+	UUIDJavaLegacy
+	// UUIDCSharpLegacy stores the first 3 groups in little endian.
+	UUIDCSharpLegacy
+)
+
+// UUID returns the UUID stored as a binary of subtype 0x04, or 0x03 in the standard byte order.
+// ok is false for the other values.
+func (r Result) UUID() (uuid [16]byte, ok bool) {
+	return r.UUIDLegacy(UUIDPythonLegacy)
+}
+
+// UUIDLegacy is like UUID, but decodes the legacy subtype 0x03 in the given byte order.
+func (r Result) UUIDLegacy(rep UUIDRepresentation) (uuid [16]byte, ok bool) {
+	subtype, data := r.Binary()
+	if (subtype != 0x03 && subtype != 0x04) || len(data) != 16 {
+		return uuid, false
+	}
+	copy(uuid[:], data)
+	if subtype == 0x04 {
+		return uuid, true
+	}
+	switch rep {
+	case UUIDJavaLegacy:
+		reverseBytes(uuid[0:8])
+		reverseBytes(uuid[8:16])
+	case UUIDCSharpLegacy:
+		reverseBytes(uuid[0:4])
+		reverseBytes(uuid[4:6])
+		reverseBytes(uuid[6:8])
+	}
+	return uuid, true
+}
+
+// UUIDString returns the UUID in the canonical "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx" form, "" if it's not a UUID.
+func (r Result) UUIDString() string {
+	uuid, ok := r.UUID()
+	if !ok {
+		return ""
+	}
+	var buf [36]byte
+	return string(appendUUID(buf[:0], uuid))
+}
+
+// appendUUID appends the canonical form of the UUID, e.g. 01234567-89ab-cdef-0123-456789abcdef.
+func appendUUID(dst []byte, uuid [16]byte) []byte {
+	var buf [36]byte
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+	return append(dst, buf[:]...)
+}
+
+func reverseBytes(bs []byte) {
+	for i, j := 0, len(bs)-1; i < j; i, j = i+1, j-1 {
+		bs[i], bs[j] = bs[j], bs[i]
+	}
+}
+
+// Regex returns the pattern and the options of a Regex value, both are "" for the other types.
+func (r Result) Regex() (pattern, options string) {
+	p, o := r.regexBytes()
+	return string(p), string(o)
+}
+
+// regexBytes is Regex without copying.
+func (r Result) regexBytes() (pattern, options []byte) {
+	if r.Type != BSONTypeRegex {
+		return nil, nil
+	}
+	p, n := consumeCString(r.Raw)
+	if n == 0 {
+		return nil, nil
+	}
+	o, m := consumeCString(r.Raw[n:])
+	if m == 0 {
+		return nil, nil
+	}
+	return p, o
+}
+
+// Regexp compiles a Regex value into a Go regular expression.
+// The options i, m and s are translated into flags, l and u are ignored,
+// the extended option x is not supported by Go and results an error.
+func (r Result) Regexp() (*regexp.Regexp, error) {
+	if r.Type != BSONTypeRegex {
+		return nil, errors.Wrapf(ErrTypeMismatch, "type %v", r.Type)
+	}
+	pattern, options := r.Regex()
+	var flags []byte
+	for i := 0; i < len(options); i++ {
+		switch c := options[i]; c {
+		case 'i', 'm', 's':
+			if bytes.IndexByte(flags, c) < 0 {
+				flags = append(flags, c)
+			}
+		case 'l', 'u':
+		default:
+			return nil, errors.Errorf("unsupported regex option %q", c)
+		}
+	}
+	if len(flags) > 0 {
+		pattern = "(?" + string(flags) + ")" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// Timestamp returns the seconds t and the increment i of a Timestamp value, zeros for the other types.
+func (r Result) Timestamp() (t uint32, i uint32) {
+	if r.Type != BSONTypeTimestamp || len(r.Raw) < 8 {
+		return 0, 0
+	}
+	return binary.LittleEndian.Uint32(r.Raw[4:8]), binary.LittleEndian.Uint32(r.Raw[0:4])
+}
+
+// JavaScript returns the code of a JavaScript value, "" for the other types.
+func (r Result) JavaScript() string {
+	if r.Type != BSONTypeJavaScript {
+		return ""
+	}
+	code, _ := consumeString(r.Raw)
+	return string(code)
+}
+
+// JavaScriptWithScope returns the code and the scope document of a JavaScriptWithScope value,
+// the scope doesn't exist for the other types.
+func (r Result) JavaScriptWithScope() (code string, scope Result) {
+	scope.Type = BSONTypeUndefined
+	if r.Type != BSONTypeJavaScriptWithScope || len(r.Raw) < 4 {
+		return "", scope
+	}
+	c, n := consumeString(r.Raw[4:])
+	if n == 0 {
+		return "", scope
+	}
+	return string(c), resultFromBytes(r.Raw[4+n:])
+}
+
+// DBPointer returns the namespace and the ObjectID of a deprecated DBPointer value, ok is false for the other types.
+func (r Result) DBPointer() (namespace string, id [12]byte, ok bool) {
+	if r.Type != BSONTypeDBPointer {
+		return "", id, false
+	}
+	ns, n := consumeString(r.Raw)
+	if n == 0 || len(r.Raw)-n < 12 {
+		return "", id, false
+	}
+	copy(id[:], r.Raw[n:])
+	return string(ns), id, true
+}
+
+// DBRef returns the collection, the id and the optional database of a DBRef, which is a document with
+// a string "$ref", an "$id" of any type and an optional string "$db", in any order and possibly followed
+// by other fields. ok is false for the other values.
+// See https://www.mongodb.com/docs/manual/reference/database-references/#dbrefs
+func (r Result) DBRef() (collection string, id Result, db string, ok bool) {
+	missing := Result{Type: BSONTypeUndefined}
+	if r.Type != BSONTypeObject {
+		return "", missing, "", false
+	}
+	var ref, dbField Result
+	_, err := r.iterFields(func(key []byte, value Result) bool {
+		switch string(key) {
+		case "$ref":
+			ref = value
+		case "$id":
+			id = value
+		case "$db":
+			dbField = value
+		}
+		return true
+	})
+	if err != nil || ref.Type != BSONTypeString || id.Type == 0 ||
+		(dbField.Type != 0 && dbField.Type != BSONTypeString) {
+		return "", missing, "", false
+	}
+	return ref.String(), id, dbField.String(), true
+}
@@ -0,0 +1,188 @@
+package gbson
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mustMarshal(t testing.TB, v interface{}) []byte {
+	bs, err := bson.Marshal(v)
+	require.NoError(t, err)
+	return bs
+}
+
+func TestObjectID(t *testing.T) {
+	oid := primitive.NewObjectIDFromTimestamp(time.Unix(1668000000, 0))
+	doc := mustMarshal(t, bson.D{{Key: "_id", Value: oid}, {Key: "s", Value: "x"}})
+
+	r := Get(doc, "_id")
+	require.Equal(t, [12]byte(oid), r.ObjectID())
+	require.Equal(t, oid.Hex(), r.ObjectIDHex())
+	require.Equal(t, time.Unix(1668000000, 0), r.ObjectIDTime())
+
+	r = Get(doc, "s")
+	require.Equal(t, [12]byte{}, r.ObjectID())
+	require.Equal(t, "", r.ObjectIDHex())
+	require.True(t, r.ObjectIDTime().IsZero())
+}
+
+func TestBinary(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "generic", Value: []byte{1, 2, 3}},
+		{Key: "user", Value: primitive.Binary{Subtype: 0x80, Data: []byte{4, 5}}},
+		{Key: "old", Value: primitive.Binary{Subtype: 0x02, Data: []byte{6}}},
+		{Key: "empty", Value: []byte{}},
+		{Key: "s", Value: "x"},
+	})
+	subtype, data := Get(doc, "generic").Binary()
+	require.Equal(t, byte(0), subtype)
+	require.Equal(t, []byte{1, 2, 3}, data)
+	subtype, data = Get(doc, "user").Binary()
+	require.Equal(t, byte(0x80), subtype)
+	require.Equal(t, []byte{4, 5}, data)
+	subtype, data = Get(doc, "old").Binary()
+	require.Equal(t, byte(0x02), subtype)
+	require.Equal(t, []byte{6}, data)
+	_, data = Get(doc, "empty").Binary()
+	require.Equal(t, []byte{}, data)
+	_, data = Get(doc, "s").Binary()
+	require.Nil(t, data)
+
+	_, data = Get(doc, "generic").BinaryCopy()
+	data[0] = 9
+	_, origin := Get(doc, "generic").Binary()
+	require.Equal(t, byte(1), origin[0])
+}
+
+func TestUUID(t *testing.T) {
+	uuid := [16]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+	java := [16]byte{0x77, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x00, 0xff, 0xee, 0xdd, 0xcc, 0xbb, 0xaa, 0x99, 0x88}
+	csharp := [16]byte{0x33, 0x22, 0x11, 0x00, 0x55, 0x44, 0x77, 0x66, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+	doc := mustMarshal(t, bson.D{
+		{Key: "standard", Value: primitive.Binary{Subtype: 0x04, Data: uuid[:]}},
+		{Key: "java", Value: primitive.Binary{Subtype: 0x03, Data: java[:]}},
+		{Key: "csharp", Value: primitive.Binary{Subtype: 0x03, Data: csharp[:]}},
+		{Key: "short", Value: primitive.Binary{Subtype: 0x04, Data: uuid[:8]}},
+	})
+
+	actual, ok := Get(doc, "standard").UUID()
+	require.True(t, ok)
+	require.Equal(t, uuid, actual)
+	require.Equal(t, "00112233-4455-6677-8899-aabbccddeeff", Get(doc, "standard").UUIDString())
+	actual, ok = Get(doc, "java").UUIDLegacy(UUIDJavaLegacy)
+	require.True(t, ok)
+	require.Equal(t, uuid, actual)
+	actual, ok = Get(doc, "csharp").UUIDLegacy(UUIDCSharpLegacy)
+	require.True(t, ok)
+	require.Equal(t, uuid, actual)
+	actual, ok = Get(doc, "java").UUID()
+	require.True(t, ok)
+	require.Equal(t, java, actual)
+
+	_, ok = Get(doc, "short").UUID()
+	require.False(t, ok)
+	require.Equal(t, "", Get(doc, "missing").UUIDString())
+}
+
+func TestRegex(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "re", Value: primitive.Regex{Pattern: "^ab+c$", Options: "im"}},
+		{Key: "x", Value: primitive.Regex{Pattern: "a b", Options: "x"}},
+		{Key: "s", Value: "x"},
+	})
+	pattern, options := Get(doc, "re").Regex()
+	require.Equal(t, "^ab+c$", pattern)
+	require.Equal(t, "im", options)
+	re, err := Get(doc, "re").Regexp()
+	require.NoError(t, err)
+	require.True(t, re.MatchString("xyz\nABBC"))
+
+	_, err = Get(doc, "x").Regexp()
+	require.Error(t, err)
+	_, err = Get(doc, "s").Regexp()
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	pattern, options = Get(doc, "s").Regex()
+	require.Equal(t, "", pattern)
+	require.Equal(t, "", options)
+}
+
+func TestTimestamp(t *testing.T) {
+	doc := mustMarshal(t, bson.D{{Key: "ts", Value: primitive.Timestamp{T: 1668000000, I: 7}}})
+	ts, inc := Get(doc, "ts").Timestamp()
+	require.Equal(t, uint32(1668000000), ts)
+	require.Equal(t, uint32(7), inc)
+	require.Equal(t, time.Unix(1668000000, 0), Get(doc, "ts").Time())
+	ts, inc = Get(doc, "missing").Timestamp()
+	require.Zero(t, ts)
+	require.Zero(t, inc)
+}
+
+func TestJavaScript(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "js", Value: primitive.JavaScript("function() { return 1; }")},
+		{Key: "scoped", Value: primitive.CodeWithScope{Code: "x + 1", Scope: bson.D{{Key: "x", Value: int32(41)}}}},
+	})
+	require.Equal(t, "function() { return 1; }", Get(doc, "js").JavaScript())
+	code, scope := Get(doc, "scoped").JavaScriptWithScope()
+	require.Equal(t, "x + 1", code)
+	require.Equal(t, int32(41), scope.Get("x").Int32())
+
+	require.Equal(t, "", Get(doc, "scoped").JavaScript())
+	code, scope = Get(doc, "js").JavaScriptWithScope()
+	require.Equal(t, "", code)
+	require.False(t, scope.Exist())
+}
+
+func TestDBPointer(t *testing.T) {
+	oid := primitive.NewObjectID()
+	doc := mustMarshal(t, bson.D{{Key: "p", Value: primitive.DBPointer{DB: "db.coll", Pointer: oid}}})
+	ns, id, ok := Get(doc, "p").DBPointer()
+	require.True(t, ok)
+	require.Equal(t, "db.coll", ns)
+	require.Equal(t, [12]byte(oid), id)
+	_, _, ok = Get(doc, "missing").DBPointer()
+	require.False(t, ok)
+}
+
+func TestDBRef(t *testing.T) {
+	oid := primitive.NewObjectID()
+	doc := mustMarshal(t, bson.D{
+		{Key: "ref", Value: bson.D{{Key: "$ref", Value: "users"}, {Key: "$id", Value: oid}}},
+		{Key: "full", Value: bson.D{{Key: "$id", Value: int32(1)}, {Key: "$ref", Value: "users"}, {Key: "$db", Value: "app"}, {Key: "x", Value: 1}}},
+		{Key: "noID", Value: bson.D{{Key: "$ref", Value: "users"}}},
+		{Key: "badDB", Value: bson.D{{Key: "$ref", Value: "users"}, {Key: "$id", Value: 1}, {Key: "$db", Value: 1}}},
+		{Key: "badRef", Value: bson.D{{Key: "$ref", Value: 1}, {Key: "$id", Value: 1}}},
+	})
+	coll, id, db, ok := Get(doc, "ref").DBRef()
+	require.True(t, ok)
+	require.Equal(t, "users", coll)
+	require.Equal(t, oid.Hex(), id.ObjectIDHex())
+	require.Equal(t, "", db)
+	coll, id, db, ok = Get(doc, "full").DBRef()
+	require.True(t, ok)
+	require.Equal(t, "users", coll)
+	require.Equal(t, int32(1), id.Int32())
+	require.Equal(t, "app", db)
+	for _, key := range []string{"noID", "badDB", "badRef", "missing"} {
+		_, id, _, ok = Get(doc, key).DBRef()
+		require.False(t, ok, key)
+		require.False(t, id.Exist(), key)
+	}
+}
+
+func TestStringLayoutTypes(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "string", Value: "text"},
+		{Key: "symbol", Value: primitive.Symbol("ENUM_VALUE")},
+		{Key: "js", Value: primitive.JavaScript("return 1")},
+		{Key: "int", Value: 1},
+	})
+	require.Equal(t, "text", Get(doc, "string").String())
+	require.Equal(t, "ENUM_VALUE", Get(doc, "symbol").String())
+	require.Equal(t, "return 1", Get(doc, "js").String())
+	require.Equal(t, "", Get(doc, "int").String())
+}
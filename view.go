@@ -0,0 +1,62 @@
+package gbson
+
+// View is a lazy map view of a document, an alternative to Map when only a few keys are looked up:
+// the document is scanned on demand up to the looked up key, and the keys scanned so far are memoized,
+// so each element is scanned at most once. Values are the first ones of duplicate keys, the same as Get.
+// A View is not safe for concurrent use.
+type View struct {
+	cursor Cursor
+	seen   map[string]Result // elements scanned so far
+}
+
+// View returns a lazy map view of the document, which is empty for the other values.
+func (r Result) View() *View {
+	v := &View{}
+	if r.Type == BSONTypeObject {
+		v.cursor = r.Cursor()
+	}
+	return v
+}
+
+// Get gets the value of the key, scanning the document up to it if it's not scanned yet.
+func (v *View) Get(key string) Result {
+	if value, ok := v.seen[key]; ok {
+		return value
+	}
+	for v.cursor.Next() {
+		if v.remember() && bytesEqualToString(v.cursor.Key(), key) {
+			return v.cursor.Value()
+		}
+	}
+	return Result{Type: BSONTypeUndefined}
+}
+
+// remember memoizes the current element of the cursor, it returns false for duplicate keys.
+func (v *View) remember() bool {
+	if v.seen == nil {
+		v.seen = make(map[string]Result)
+	}
+	if _, ok := v.seen[string(v.cursor.Key())]; ok {
+		return false
+	}
+	v.seen[string(v.cursor.Key())] = v.cursor.Value()
+	return true
+}
+
+// Has reports whether the document has the key.
+func (v *View) Has(key string) bool {
+	return v.Get(key).Exist()
+}
+
+// Len returns the number of distinct keys, which scans the rest of the document.
+func (v *View) Len() int {
+	for v.cursor.Next() {
+		v.remember()
+	}
+	return len(v.seen)
+}
+
+// Err returns the error of scanning a malformed document, after which keys are missing.
+func (v *View) Err() error {
+	return v.cursor.Err()
+}
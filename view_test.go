@@ -0,0 +1,32 @@
+package gbson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestView(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "a", Value: int32(1)}, {Key: "b", Value: "x"}, {Key: "a", Value: int32(2)}, {Key: "c", Value: true},
+	})
+	v := Get(doc).View()
+	require.Equal(t, "x", v.Get("b").String())
+	require.Len(t, v.seen, 2, "scanned up to b")
+	require.Equal(t, int32(1), v.Get("a").Int32())
+	require.True(t, v.Has("c"))
+	require.False(t, v.Has("d"))
+	require.Equal(t, 3, v.Len())
+	require.NoError(t, v.Err())
+
+	require.Equal(t, 3, Get(doc).View().Len())
+	require.Zero(t, Get(doc, "a").View().Len())
+
+	bad := append([]byte(nil), doc...)
+	bad[14] = 100 // the length of b
+	v = Get(bad).View()
+	require.Equal(t, int32(1), v.Get("a").Int32())
+	require.False(t, v.Has("c"))
+	require.ErrorIs(t, v.Err(), ErrInvalidLength)
+}
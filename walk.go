@@ -0,0 +1,71 @@
+package gbson
+
+// Handler receives the events of Walk. Returning an error from any method stops the walk with the error.
+type Handler interface {
+	// DocumentStart starts a document, either the walked one or an embedded one following a Key.
+	DocumentStart() error
+	// Key is the name of the following element of a document, elements of arrays have no Key events.
+	// The key refers to the walked bytes without copying.
+	Key(key []byte) error
+	// Value is a value other than documents and arrays.
+	Value(value Result) error
+	// DocumentEnd ends the last started document.
+	DocumentEnd() error
+	// ArrayStart starts an array, which is a value of a document following a Key or an item of an array.
+	ArrayStart() error
+	// ArrayEnd ends the last started array.
+	ArrayEnd() error
+}
+
+// Walk traverses the whole document in order and emits the events to the handler without allocations,
+// so converters like ToJSON could be built on it. For example, {"a": [1]} emits:
+//
+//	DocumentStart, Key("a"), ArrayStart, Value(1), ArrayEnd, DocumentEnd
+func Walk(doc []byte, h Handler) error {
+	return locateError(doc, walkContainer(Result{Type: BSONTypeObject, Raw: doc}, 1, h))
+}
+
+// walkContainer walks the document or array at the depth.
+func walkContainer(r Result, depth int, h Handler) error {
+	if err := checkDepth(depth); err != nil {
+		return err
+	}
+	elements, ok := containerElements(r.Raw)
+	if !ok {
+		return ErrInvalidLength
+	}
+	isDocument := r.Type == BSONTypeObject
+	var err error
+	if isDocument {
+		err = h.DocumentStart()
+	} else {
+		err = h.ArrayStart()
+	}
+	if err != nil {
+		return err
+	}
+	for len(elements) > 0 {
+		tp, name, value, n := consumeElement(elements)
+		if n < 0 {
+			return ErrInvalidLength
+		}
+		elements = elements[n:]
+		if isDocument {
+			if err = h.Key(name); err != nil {
+				return err
+			}
+		}
+		if tp == BSONTypeObject || tp == BSONTypeArray {
+			err = walkContainer(Result{Type: tp, Raw: value}, depth+1, h)
+		} else {
+			err = h.Value(Result{Type: tp, Raw: value})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if isDocument {
+		return h.DocumentEnd()
+	}
+	return h.ArrayEnd()
+}
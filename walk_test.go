@@ -0,0 +1,63 @@
+package gbson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type recordingHandler struct {
+	events []string
+	stopAt string
+}
+
+func (h *recordingHandler) record(event string) error {
+	h.events = append(h.events, event)
+	if event == h.stopAt {
+		return errors.New("stop")
+	}
+	return nil
+}
+
+func (h *recordingHandler) DocumentStart() error     { return h.record("{") }
+func (h *recordingHandler) Key(key []byte) error     { return h.record(string(key) + ":") }
+func (h *recordingHandler) Value(value Result) error { return h.record(value.Str()) }
+func (h *recordingHandler) DocumentEnd() error       { return h.record("}") }
+func (h *recordingHandler) ArrayStart() error        { return h.record("[") }
+func (h *recordingHandler) ArrayEnd() error          { return h.record("]") }
+
+type countingHandler struct{ events int }
+
+func (h *countingHandler) DocumentStart() error { h.events++; return nil }
+func (h *countingHandler) Key([]byte) error     { h.events++; return nil }
+func (h *countingHandler) Value(Result) error   { h.events++; return nil }
+func (h *countingHandler) DocumentEnd() error   { h.events++; return nil }
+func (h *countingHandler) ArrayStart() error    { h.events++; return nil }
+func (h *countingHandler) ArrayEnd() error      { h.events++; return nil }
+
+func TestWalk(t *testing.T) {
+	doc := mustMarshal(t, bson.D{
+		{Key: "a", Value: int32(1)},
+		{Key: "b", Value: bson.D{{Key: "c", Value: bson.A{"x", bson.D{}, bson.A{}}}}},
+		{Key: "d", Value: true},
+	})
+	h := &recordingHandler{}
+	require.NoError(t, Walk(doc, h))
+	require.Equal(t, "{ a: 1 b: { c: [ x { } [ ] ] } d: true }", strings.Join(h.events, " "))
+
+	h = &recordingHandler{stopAt: "x"}
+	require.EqualError(t, Walk(doc, h), "stop")
+	require.Equal(t, "{ a: 1 b: { c: [ x", strings.Join(h.events, " "))
+
+	require.ErrorIs(t, Walk(doc[:len(doc)-1], h), ErrInvalidLength)
+
+	counter := &countingHandler{}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = Walk(doc, counter)
+	})
+	require.Zero(t, allocs)
+	require.Equal(t, 17*101, counter.events)
+}
@@ -0,0 +1,325 @@
+package gbson
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// ToYAML renders the bson document as block style YAML for human review, e.g. of stored configurations.
+// Values without a YAML counterpart are tagged: ObjectIDs as "!objectId <hex>", datetimes as "!!timestamp",
+// generic binaries as "!!binary <base64>", UUIDs as "!uuid", and the other binary subtypes as "!binary/<subtype>".
+// The other BSON specific types are tagged likewise, e.g. "!decimal", "!regex" and "!minKey".
+func ToYAML(doc []byte) ([]byte, error) {
+	r := Result{Type: BSONTypeObject, Raw: doc}
+	if r.Length() == 0 {
+		if _, err := r.iterFields(func([]byte, Result) bool { return true }); err != nil {
+			return nil, locateError(doc, err)
+		}
+		return []byte("{}\n"), nil
+	}
+	var w yamlWriter
+	return r.locate(w.appendMapping(nil, r, 0, false))
+}
+
+// yamlWriter writes block style YAML directly from the raw bytes.
+type yamlWriter struct {
+	depth int // depth of the container being written
+}
+
+// appendMapping appends the elements of the document one per line at the indent.
+// If inline is true, the first line is already indented, like an item of a sequence.
+func (w yamlWriter) appendMapping(dst []byte, r Result, indent int, inline bool) ([]byte, error) {
+	w.depth++
+	if err := checkDepth(w.depth); err != nil {
+		return dst, err
+	}
+	var err error
+	_, iterErr := r.iterFields(func(key []byte, value Result) bool {
+		if !inline {
+			dst = appendIndent(dst, indent)
+		}
+		inline = false
+		dst = appendYAMLString(dst, key)
+		dst = append(dst, ':')
+		dst, err = w.appendValue(dst, value, indent)
+		return err == nil
+	})
+	if err != nil {
+		return dst, err
+	}
+	return dst, iterErr
+}
+
+// appendSequence is like appendMapping, for the items of an array.
+func (w yamlWriter) appendSequence(dst []byte, r Result, indent int, inline bool) ([]byte, error) {
+	w.depth++
+	if err := checkDepth(w.depth); err != nil {
+		return dst, err
+	}
+	var err error
+	_, iterErr := r.iterFields(func(_ []byte, value Result) bool {
+		if !inline {
+			dst = appendIndent(dst, indent)
+		}
+		inline = false
+		dst = append(dst, '-')
+		if value.IsContainer() && value.Length() > 0 {
+			dst = append(dst, ' ')
+			if value.Type == BSONTypeObject {
+				dst, err = w.appendMapping(dst, value, indent+2, true)
+			} else {
+				dst, err = w.appendSequence(dst, value, indent+2, true)
+			}
+		} else {
+			dst, err = w.appendValue(dst, value, indent)
+		}
+		return err == nil
+	})
+	if err != nil {
+		return dst, err
+	}
+	return dst, iterErr
+}
+
+// appendValue appends the value following a "key:" or a "-" of the line at the indent, ending the line.
+// Non-empty documents and arrays are appended on the following lines, indented one more level.
+func (w yamlWriter) appendValue(dst []byte, r Result, indent int) ([]byte, error) {
+	switch r.Type {
+	case BSONTypeObject, BSONTypeArray:
+		if err := checkDepth(w.depth + 1); err != nil {
+			return dst, err
+		}
+		if r.Length() == 0 {
+			if r.Type == BSONTypeObject {
+				return append(dst, " {}\n"...), nil
+			}
+			return append(dst, " []\n"...), nil
+		}
+		dst = append(dst, '\n')
+		if r.Type == BSONTypeObject {
+			return w.appendMapping(dst, r, indent+2, false)
+		}
+		return w.appendSequence(dst, r, indent+2, false)
+	case BSONTypeJavaScriptWithScope:
+		code, scope := r.JavaScriptWithScope()
+		if !scope.Exist() {
+			return dst, ErrInvalidLength
+		}
+		dst = appendIndent(append(dst, " !code\n"...), indent+2)
+		dst = appendYAMLString(append(dst, "code: "...), code)
+		dst = appendIndent(append(dst, '\n'), indent+2)
+		return w.appendValue(append(dst, "scope:"...), scope, indent+2)
+	}
+	dst = append(dst, ' ')
+	dst, err := w.appendScalar(dst, r)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, '\n'), nil
+}
+
+func (w yamlWriter) appendScalar(dst []byte, r Result) ([]byte, error) {
+	switch r.Type {
+	case BSONTypeDouble:
+		if len(r.Raw) < 8 {
+			return dst, ErrInvalidLength
+		}
+		switch f := r.Float64(); {
+		case math.IsInf(f, 1):
+			return append(dst, ".inf"...), nil
+		case math.IsInf(f, -1):
+			return append(dst, "-.inf"...), nil
+		case math.IsNaN(f):
+			return append(dst, ".nan"...), nil
+		default:
+			return appendExtJSONFloat(dst, f), nil
+		}
+	case BSONTypeString:
+		return w.appendString(dst, r)
+	case BSONTypeBinary:
+		if len(r.Raw) < 5 {
+			return dst, ErrInvalidLength
+		}
+		if uuid := r.UUIDString(); uuid != "" {
+			return append(append(dst, "!uuid "...), uuid...), nil
+		}
+		subtype, data := r.Binary()
+		if subtype == 0 {
+			dst = append(dst, "!!binary "...)
+		} else {
+			dst = append(dst, "!binary/"...)
+			dst = append(dst, hexDigits[subtype>>4], hexDigits[subtype&0xF], ' ')
+		}
+		if len(data) == 0 {
+			return append(dst, `""`...), nil
+		}
+		return appendBase64(dst, data), nil
+	case BSONTypeUndefined:
+		return append(dst, "!undefined"...), nil
+	case BSONTypeObjectID:
+		if len(r.Raw) < 12 {
+			return dst, ErrInvalidLength
+		}
+		return appendHex(append(dst, "!objectId "...), r.Raw[:12]), nil
+	case BSONTypeBoolean:
+		if len(r.Raw) < 1 {
+			return dst, ErrInvalidLength
+		}
+		return strconv.AppendBool(dst, r.Bool()), nil
+	case BSONTypeDateTime:
+		if len(r.Raw) < 8 {
+			return dst, ErrInvalidLength
+		}
+		t := r.TimeIn(time.UTC)
+		if t.Year() < 0 || t.Year() > 9999 {
+			return strconv.AppendInt(append(dst, "!date "...), int64(binary.LittleEndian.Uint64(r.Raw)), 10), nil
+		}
+		return t.AppendFormat(append(dst, "!!timestamp "...), rfc3339Milli), nil
+	case BSONTypeNull:
+		return append(dst, "null"...), nil
+	case BSONTypeRegex:
+		pattern, options := r.Regex()
+		return appendYAMLQuoted(append(dst, "!regex "...), "/"+pattern+"/"+sortString(options)), nil
+	case BSONTypeDBPointer:
+		ns, id, ok := r.DBPointer()
+		if !ok {
+			return dst, ErrInvalidLength
+		}
+		dst = appendYAMLQuoted(append(dst, "!dbPointer {ns: "...), ns)
+		dst = appendHex(append(dst, ", id: "...), id[:])
+		return append(dst, '}'), nil
+	case BSONTypeJavaScript:
+		return w.appendString(append(dst, "!code "...), r)
+	case BSONTypeSymbol:
+		return w.appendString(append(dst, "!symbol "...), r)
+	case BSONTypeInt32:
+		if len(r.Raw) < 4 {
+			return dst, ErrInvalidLength
+		}
+		return strconv.AppendInt(dst, int64(r.Int32()), 10), nil
+	case BSONTypeTimestamp:
+		if len(r.Raw) < 8 {
+			return dst, ErrInvalidLength
+		}
+		t, i := r.Timestamp()
+		dst = strconv.AppendUint(append(dst, "!bsonTimestamp {t: "...), uint64(t), 10)
+		dst = strconv.AppendUint(append(dst, ", i: "...), uint64(i), 10)
+		return append(dst, '}'), nil
+	case BSONTypeInt64:
+		if len(r.Raw) < 8 {
+			return dst, ErrInvalidLength
+		}
+		return strconv.AppendInt(dst, r.Int64(), 10), nil
+	case BSONTypeDecimal128:
+		if len(r.Raw) < 16 {
+			return dst, ErrInvalidLength
+		}
+		return append(append(dst, "!decimal "...), r.Decimal128().String()...), nil
+	case BSONTypeMinKey:
+		return append(dst, "!minKey"...), nil
+	case BSONTypeMaxKey:
+		return append(dst, "!maxKey"...), nil
+	}
+	return dst, errors.Wrapf(ErrUnsupportedType, "type %v", r.Type)
+}
+
+func (w yamlWriter) appendString(dst []byte, r Result) ([]byte, error) {
+	value, n := consumeString(r.Raw)
+	if n == 0 {
+		return dst, ErrInvalidLength
+	}
+	return appendYAMLString(dst, value), nil
+}
+
+func appendIndent(dst []byte, indent int) []byte {
+	for i := 0; i < indent; i++ {
+		dst = append(dst, ' ')
+	}
+	return dst
+}
+
+// appendYAMLString appends the string as a plain scalar if it couldn't be taken for anything else,
+// otherwise double quoted.
+func appendYAMLString[S string | []byte](dst []byte, s S) []byte {
+	if isYAMLPlain(s) {
+		return append(dst, s...)
+	}
+	return appendYAMLQuoted(dst, s)
+}
+
+// isYAMLPlain reports whether the string is safe to be written unquoted. It's conservative: the string must
+// start with a letter, "_", "$" or "/", be made of letters, digits, spaces and "_-./$", and not be a YAML
+// boolean or null like "yes" or "Null".
+func isYAMLPlain[S string | []byte](s S) bool {
+	if len(s) == 0 || s[len(s)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(s); {
+		c, size := rune(s[i]), 1
+		if c >= utf8.RuneSelf {
+			c, size = decodeRune(s[i:])
+			if !isYAMLPrintable(c) || c == utf8.RuneError || c == '\u0085' || c == '\u2028' || c == '\u2029' {
+				return false
+			}
+		} else if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$' || c == '/' ||
+			i > 0 && (c >= '0' && c <= '9' || c == '-' || c == '.' || c == ' ')) {
+			return false
+		}
+		i += size
+	}
+	switch strings.ToLower(string(s)) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null":
+		return false
+	}
+	return true
+}
+
+// isYAMLPrintable reports whether the rune could be written in a YAML stream without escaping.
+func isYAMLPrintable(c rune) bool {
+	return c >= 0x20 && c <= 0x7E || c == 0x85 || c >= 0xA0 && c <= 0xD7FF ||
+		c >= 0xE000 && c <= 0xFFFD && c != 0xFEFF || c >= 0x10000 && c <= utf8.MaxRune
+}
+
+// appendYAMLQuoted appends the double quoted and escaped string, invalid UTF-8 bytes are replaced by U+FFFD.
+func appendYAMLQuoted[S string | []byte](dst []byte, s S) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c, size := rune(s[i]), 1
+		if c >= utf8.RuneSelf {
+			c, size = decodeRune(s[i:])
+		}
+		if c != '"' && c != '\\' && isYAMLPrintable(c) && !(c == utf8.RuneError && size == 1) &&
+			c != '\u0085' && c != '\u2028' && c != '\u2029' {
+			i += size
+			continue
+		}
+		dst = append(dst, s[start:i]...)
+		switch {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', byte(c))
+		case c == '\n':
+			dst = append(dst, '\\', 'n')
+		case c == '\r':
+			dst = append(dst, '\\', 'r')
+		case c == '\t':
+			dst = append(dst, '\\', 't')
+		case c == utf8.RuneError && size == 1:
+			dst = append(dst, `\ufffd`...)
+		case c <= 0xFF:
+			dst = append(dst, '\\', 'x', hexDigits[c>>4], hexDigits[c&0xF])
+		default:
+			dst = append(dst, '\\', 'u', hexDigits[c>>12&0xF], hexDigits[c>>8&0xF], hexDigits[c>>4&0xF], hexDigits[c&0xF])
+		}
+		i += size
+		start = i
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
@@ -0,0 +1,90 @@
+package gbson
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/yaml.v3"
+)
+
+func TestToYAML(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("6ad24c360f2155c689639f61")
+	doc := mustMarshal(t, bson.D{
+		{Key: "_id", Value: id},
+		{Key: "name", Value: "service a"},
+		{Key: "port", Value: int32(8080)},
+		{Key: "ratio", Value: 0.5},
+		{Key: "enabled", Value: true},
+		{Key: "tags", Value: bson.A{"yes", "1.0", "x: y", bson.D{{Key: "k", Value: int64(1)}, {Key: "v", Value: nil}}, bson.A{int32(1), int32(2)}}},
+		{Key: "limits", Value: bson.D{{Key: "cpu", Value: bson.D{}}, {Key: "mem", Value: bson.A{}}}},
+		{Key: "updated", Value: time.Date(2022, 11, 10, 1, 2, 3, 4e6, time.UTC)},
+		{Key: "key", Value: primitive.Binary{Data: []byte{1, 2, 3}}},
+		{Key: "uuid", Value: primitive.Binary{Subtype: 4, Data: []byte("0123456789abcdef")}},
+		{Key: "multi\nline", Value: "a\tb\n"},
+	})
+	out, err := ToYAML(doc)
+	require.NoError(t, err)
+	require.Equal(t, `_id: !objectId 6ad24c360f2155c689639f61
+name: service a
+port: 8080
+ratio: 0.5
+enabled: true
+tags:
+  - "yes"
+  - "1.0"
+  - "x: y"
+  - k: 1
+    v: null
+  - - 1
+    - 2
+limits:
+  cpu: {}
+  mem: []
+updated: !!timestamp 2022-11-10T01:02:03.004Z
+key: !!binary AQID
+uuid: !uuid 30313233-3435-3637-3839-616263646566
+"multi\nline": "a\tb\n"
+`, string(out))
+
+	var m map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(out, &m))
+	require.Equal(t, "service a", m["name"])
+	require.Equal(t, 8080, m["port"])
+	require.Equal(t, []interface{}{"yes", "1.0", "x: y", map[string]interface{}{"k": 1, "v": nil}, []interface{}{1, 2}}, m["tags"])
+	require.Equal(t, time.Date(2022, 11, 10, 1, 2, 3, 4e6, time.UTC), m["updated"])
+	require.Equal(t, "a\tb\n", m["multi\nline"])
+
+	out, err = ToYAML(mustMarshal(t, bson.D{}))
+	require.NoError(t, err)
+	require.Equal(t, "{}\n", string(out))
+
+	_, err = ToYAML(doc[:len(doc)-3])
+	require.Error(t, err)
+}
+
+func TestToYAMLAllTypes(t *testing.T) {
+	out, err := ToYAML(getTestJSONDocument(t))
+	require.NoError(t, err)
+	var node yaml.Node
+	require.NoError(t, yaml.Unmarshal(out, &node))
+	fields := map[string]*yaml.Node{}
+	root := node.Content[0]
+	for i := 0; i < len(root.Content); i += 2 {
+		fields[root.Content[i].Value] = root.Content[i+1]
+	}
+	require.Len(t, fields, Get(getTestJSONDocument(t)).Length())
+	require.Equal(t, "!objectId", fields["_id"].Tag)
+	require.Equal(t, "!!float", fields["nan"].ShortTag())
+	require.Equal(t, "quote\" slash\\ tab\t ctrl\x01 <html> 中文   bad�", fields["string"].Value)
+	require.Equal(t, "!binary/80", fields["binary"].Tag)
+	require.Equal(t, "!!timestamp", fields["date"].Tag)
+	require.Equal(t, "!!null", fields["null"].ShortTag())
+	require.Equal(t, "/^a\"/im", fields["regex"].Value)
+	require.Equal(t, "!code", fields["scope"].Tag)
+	require.Equal(t, yaml.MappingNode, fields["scope"].Kind)
+	require.Equal(t, "!decimal", fields["decimal"].Tag)
+	require.Equal(t, "!maxKey", fields["max"].Tag)
+}